@@ -1,87 +1,612 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"k8watch/internal/api"
+	"k8watch/internal/archive"
+	"k8watch/internal/audit"
+	"k8watch/internal/notifier"
+	"k8watch/internal/plugin"
 	"k8watch/internal/storage"
+	"k8watch/internal/stream"
+	"k8watch/internal/version"
 	"k8watch/internal/watcher"
+
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	// Parse flags
 	kubeconfig := flag.String("kubeconfig", filepath.Join(os.Getenv("HOME"), ".kube", "config"), "Path to kubeconfig file")
+	skipTLSVerify := flag.Bool("skip-tls-verify", false, "Don't verify the API server certificate (insecure, only with --kubeconfig)")
+	caCert := flag.String("ca-cert", "", "CA bundle for verifying the API server certificate, e.g. of a cluster with a self-signed CA")
 	dbPath := flag.String("db", "./events.db", "Path to SQLite database file")
+	dbDriver := flag.String("db-driver", "", "SQLite driver: sqlite3 (cgo) or sqlite (pure Go, build with -tags purego); default: first compiled in")
 	addr := flag.String("addr", ":8080", "HTTP server address")
+	webDir := flag.String("web-dir", "", "Serve the web UI from this directory instead of the copy built into the binary (for UI development)")
+	portMetrics := flag.Int("port-metrics", 0, "Serve /metrics on this port instead of --addr, e.g. 9090 (0 serves it on --addr)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; serves the API over HTTPS (requires --tls-key)")
+	tlsKey := flag.String("tls-key", "", "TLS private key file (requires --tls-cert)")
+	tlsClientCA := flag.String("tls-client-ca", "", "CA bundle that client certificates must be signed by (mutual TLS)")
+	adminAllowedCIDRs := flag.String("admin-allowed-cidrs", "", "Comma-separated CIDR blocks allowed to call /api/admin/ endpoints, e.g. 10.0.0.0/8,192.168.1.0/24 (default: all)")
+	enablePprof := flag.Bool("enable-pprof", false, "Serve net/http/pprof profiles at /debug/pprof/ on --debug-addr")
+	debugAddr := flag.String("debug-addr", "", "Address for the pprof profiles, e.g. localhost:6060 (required by --enable-pprof)")
+	trustProxy := flag.Bool("trust-proxy", false, "Take client addresses from X-Forwarded-For; only enable behind a proxy that sets it")
+	apiTokensFile := flag.String("api-tokens", "", "YAML file of static API bearer tokens, optionally limited to namespaces")
+	oidcIssuer := flag.String("oidc-issuer", "", "OIDC issuer URL; when set, API requests need a bearer token from it")
+	oidcClientID := flag.String("oidc-client-id", "", "OIDC client ID that tokens must be issued for")
 	retentionDays := flag.Int("retention", 60, "Event retention period in days")
+	vacuumMode := flag.String("vacuum", storage.VacuumOff, "Vacuum the database after large cleanups: off, incremental or full")
+	vacuumThreshold := flag.Int64("vacuum-threshold", 10000, "Minimum number of events a cleanup must delete before it vacuums")
+	maxEvents := flag.Int("max-events", 0, "Maximum number of stored events; the oldest are evicted beyond it (0 means no cap)")
+	coalesceWindow := flag.Duration("coalesce-window", 0, "Merge an event that repeats the last identical change of a resource within this window into it, counting repeats (0 disables)")
+	dedupWindow := flag.Duration("dedup-window", 24*time.Hour, "Skip an event identical to one stored within this window, e.g. when a restarted watcher lists objects again (0 disables)")
+	retentionOverride := flag.String("retention-override", "", "Per-kind retention in days, e.g. Job=7,Secret=365,default=60")
+	watchKinds := flag.String("watch-kinds", "", "Comma-separated list of kinds to watch, e.g. Deployment,Secret,Ingress (default: all)")
+	auditLogPath := flag.String("audit-log-path", "", "Path to the Kubernetes audit log (JSON lines) used to attribute changes to users")
+	archiveBucket := flag.String("archive-s3-bucket", "", "S3 bucket to archive events to before they are deleted by retention cleanup")
+	archiveS3Endpoint := flag.String("archive-s3-endpoint", "", "Endpoint of an S3-compatible store such as MinIO (default: AWS S3)")
+	archiveDir := flag.String("archive-dir", "", "Local directory to archive events to before they are deleted by retention cleanup")
+	archivePrefix := flag.String("archive-s3-prefix", "kubewatcher", "Key prefix for archived events in the S3 bucket")
+	enableManagedFields := flag.Bool("enable-managed-fields", true, "Use metadata.managedFields to detect the author and source (helm, argocd, flux, kubectl) of changes")
+	ingressAnnotationPrefixes := flag.String("ingress-annotation-prefixes", "", "Comma-separated Ingress annotation prefixes (ending in /) or keys to track on top of the built-in ones, e.g. alb.ingress.kubernetes.io/")
+	appLabel := flag.String("app-label", "app.kubernetes.io/name", "Label whose value is stored as the app of each event, for /api/apps timelines (empty disables)")
+	ignoreRVOnly := flag.Bool("ignore-resource-version-only", false, "Skip updates that leave metadata.generation, spec and status unchanged, e.g. controllers touching annotations on every reconcile")
+	resyncPeriod := flag.Duration("resync-period", 5*time.Minute, "How often informers resync their cached resources (0 disables resyncs)")
+	quotaCheckInterval := flag.Duration("quota-check-interval", 5*time.Minute, "How often to check ResourceQuota usage (0 disables the checks)")
+	cronJobCheckInterval := flag.Duration("cronjob-check-interval", 5*time.Minute, "How often to check CronJobs for missed schedules (0 disables the checks)")
+	quotaAlertThreshold := flag.Float64("quota-alert-threshold", 0.9, "Used/hard ratio at which a ResourceQuota resource raises a THRESHOLD_EXCEEDED event")
+	pluginDir := flag.String("plugin-dir", "", "Directory of Go plugins (.so files) to run on every stored event")
+	severityRules := flag.String("severity-rules", "", "YAML or JSON file with rules that assign severities to events, checked before the built-in ones")
+	notifyMinSeverity := flag.String("notify-min-severity", storage.SeverityInfo, "Least severity sent to notifiers: info, notice, warn or critical")
+	maxDiffSize := flag.Int("max-diff-size", 10240, "Truncate stored diffs to this many bytes (0 disables)")
+	maxSpecSize := flag.Int("max-spec-size", 100*1024, "Skip snapshot objects larger than this many bytes (0 disables)")
+	secretHashKey := flag.String("secret-hash-key", os.Getenv("SECRET_HASH_KEY"), "Key for the HMAC digests of secret values stored as key_hashes; keep it stable to compare digests across restarts (default: random per run)")
+	storeSnapshots := flag.Bool("store-snapshots", false, "Store full sanitized before/after objects for MODIFIED events")
+	snapshotRetentionDays := flag.Int("snapshot-retention", 7, "Snapshot retention period in days")
 	slackWebhook := flag.String("slack-webhook", os.Getenv("SLACK_WEBHOOK_URL"), "Slack webhook URL for notifications")
+	externalURL := flag.String("external-url", os.Getenv("EXTERNAL_URL"), "URL k8watch is reachable at, e.g. https://k8watch.example.com; Slack messages link to events under it")
+	alertmanagerURL := flag.String("alertmanager-url", os.Getenv("ALERTMANAGER_URL"), "Alertmanager base URL for notifications (e.g. http://alertmanager:9093)")
+	telegramBotToken := flag.String("telegram-bot-token", os.Getenv("TELEGRAM_BOT_TOKEN"), "Telegram bot token for notifications")
+	telegramChatID := flag.String("telegram-chat-id", os.Getenv("TELEGRAM_CHAT_ID"), "Telegram chat ID to send notifications to")
+	notifierConfigPath := flag.String("notifier-config", "", "YAML file with notifier settings (slack_webhook_url, alertmanager_url, telegram_bot_token, telegram_chat_id), re-read on SIGHUP")
+	natsURL := flag.String("nats-url", os.Getenv("NATS_URL"), "NATS server URL for publishing events to JetStream")
+	natsStream := flag.String("nats-stream", "KUBEWATCHER", "JetStream stream name (created if missing)")
+	natsSubjectPrefix := flag.String("nats-subject-prefix", "kubewatcher.events", "Subject prefix; events go to {prefix}.{namespace}.{kind}.{action}")
+	enableLeaderElection := flag.Bool("enable-leader-election", false, "Only run the watchers on the replica holding the leader Lease")
+	leaderElectionName := flag.String("leader-election-name", "k8watch-leader", "Name of the leader election Lease")
+	leaderElectionNamespace := flag.String("leader-election-namespace", os.Getenv("POD_NAMESPACE"), "Namespace of the leader election Lease (default: $POD_NAMESPACE or default)")
+	leaderElectionID := flag.String("leader-election-id", "", "Identity of this replica in the election (default: hostname)")
+	leaseDuration := flag.Duration("leader-election-lease-duration", 15*time.Second, "Leader election lease duration")
+	migrateOnly := flag.Bool("migrate-only", false, "Apply pending database schema migrations and exit, e.g. in an init container")
+	dryRun := flag.Bool("dry-run", false, "Log detected events without storing them or sending notifications")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	showVersion := flag.Bool("version", false, "Print the version and exit")
 	flag.Parse()
 
-	log.Println("Starting K8Watch - Kubernetes Change Tracker")
-	log.Printf("Kubeconfig: %s", *kubeconfig)
-	log.Printf("Database: %s", *dbPath)
-	log.Printf("Server: %s", *addr)
-	log.Printf("Retention: %d days", *retentionDays)
+	if *showVersion {
+		fmt.Println(version.String())
+		return
+	}
+
+	logger, err := newLogger(*logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging flags: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	if *migrateOnly {
+		schemaVersion, err := storage.Migrate(*dbPath, *dbDriver, logger)
+		if err != nil {
+			fatal(logger, "Failed to migrate database", err)
+		}
+		logger.Info("Database migrated", slog.String("database", *dbPath), slog.Int("version", schemaVersion))
+		return
+	}
 
-	// Initialize storage
-	store, err := storage.NewStorage(*dbPath)
+	retentionOverrides, err := parseRetentionOverrides(*retentionOverride)
 	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+		fatal(logger, "Invalid --retention-override", err)
+	}
+	if days, ok := retentionOverrides["default"]; ok {
+		*retentionDays = days
+		delete(retentionOverrides, "default")
+	}
+
+	logger.Info("Starting K8Watch - Kubernetes Change Tracker",
+		slog.String("version", version.Version),
+		slog.String("commit", version.GitCommit),
+		slog.String("build_date", version.BuildDate),
+	)
+
+	// Root context for all storage and notifier calls, cancelled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger.Info("Configuration",
+		slog.String("kubeconfig", *kubeconfig),
+		slog.String("database", *dbPath),
+		slog.String("addr", *addr),
+		slog.Int("retention_days", *retentionDays),
+	)
+	if *skipTLSVerify {
+		if *caCert != "" {
+			fatal(logger, "Invalid TLS configuration", fmt.Errorf("--skip-tls-verify and --ca-cert are mutually exclusive"))
+		}
+		logger.Warn("TLS verification disabled", slog.String("kubeconfig", *kubeconfig))
+	}
+	if *storeSnapshots {
+		logger.Info("Snapshots enabled", slog.Int("retention_days", *snapshotRetentionDays))
+	}
+
+	// Newly stored events are streamed to /api/stream clients and invalidate
+	// the ETags of /api/events
+	hub := stream.NewHub(logger)
+	etags := api.NewETagCache()
+
+	// Initialize storage. A dry run stores nothing and sends no notifications.
+	var store storage.EventStore = storage.NewNoopStorage()
+	var schemaVersion int
+	if *dryRun {
+		logger.Warn("Dry run: events are logged only, nothing is stored or sent to notifiers")
+	} else {
+		db, err := storage.NewStorage(*dbPath, *dbDriver, logger)
+		if err != nil {
+			fatal(logger, "Failed to initialize storage", err)
+		}
+		db.SetRetentionOverrides(retentionOverrides)
+		db.SetCoalesceWindow(*coalesceWindow)
+		db.SetDedupWindow(*dedupWindow)
+		db.SetPublisher(storage.MultiPublisher(hub, etags))
+		if err := db.SetVacuum(ctx, *vacuumMode, *vacuumThreshold); err != nil {
+			fatal(logger, "Invalid --vacuum", err)
+		}
+		if *maxEvents > 0 {
+			if err := db.SetMaxEvents(ctx, *maxEvents); err != nil {
+				fatal(logger, "Failed to set --max-events", err)
+			}
+			logger.Info("Event count capped", slog.Int("max_events", *maxEvents))
+		}
+
+		// Archive expired events to S3 or a local directory instead of only deleting them
+		switch {
+		case *archiveBucket != "" && *archiveDir != "":
+			fatal(logger, "Invalid archive configuration", fmt.Errorf("--archive-s3-bucket and --archive-dir are mutually exclusive"))
+		case *archiveBucket != "":
+			archiver, err := archive.NewS3Archiver(ctx, *archiveBucket, *archivePrefix, *archiveS3Endpoint)
+			if err != nil {
+				fatal(logger, "Failed to initialize S3 archiver", err)
+			}
+			db.SetArchiver(archiver)
+			logger.Info("Archiving expired events to S3", slog.String("bucket", *archiveBucket), slog.String("prefix", *archivePrefix))
+		case *archiveDir != "":
+			archiver, err := archive.NewDirArchiver(*archiveDir)
+			if err != nil {
+				fatal(logger, "Failed to initialize archive directory", err)
+			}
+			db.SetArchiver(archiver)
+			logger.Info("Archiving expired events to a local directory", slog.String("dir", *archiveDir))
+		}
+		store = db
+		schemaVersion = db.SchemaVersion()
 	}
-	defer store.Close()
 
 	// Initial cleanup of old events
-	if deleted, err := store.CleanupOldEvents(*retentionDays); err != nil {
-		log.Printf("Warning: Failed to cleanup old events: %v", err)
-	} else if deleted > 0 {
-		log.Printf("Cleaned up %d events older than %d days", deleted, *retentionDays)
+	cleanup := func() {
+		if deleted, err := store.CleanupOldEvents(ctx, *retentionDays); err != nil {
+			logger.Warn("Failed to cleanup old events", slog.Any("error", err))
+		} else if total := storage.SumCounts(deleted); total > 0 {
+			logger.Info("Cleaned up expired events", slog.Int64("deleted", total), slog.Any("deleted_by_kind", deleted))
+		}
+		if deleted, err := store.CleanupOldSnapshots(ctx, *snapshotRetentionDays); err != nil {
+			logger.Warn("Failed to cleanup old snapshots", slog.Any("error", err))
+		} else if deleted > 0 {
+			logger.Info("Cleaned up expired snapshots", slog.Int64("deleted", deleted), slog.Int("retention_days", *snapshotRetentionDays))
+		}
 	}
+	cleanup()
 
 	// Start periodic cleanup (daily)
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour)
 		defer ticker.Stop()
-		for range ticker.C {
-			if deleted, err := store.CleanupOldEvents(*retentionDays); err != nil {
-				log.Printf("Warning: Periodic cleanup failed: %v", err)
-			} else if deleted > 0 {
-				log.Printf("Periodic cleanup: removed %d old events", deleted)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
 			}
+			cleanup()
 		}
 	}()
 
+	// Reloads run on SIGHUP, e.g. after a rotated webhook or certificate
+	var reloads []func()
+
+	// Initialize notifiers. Settings in --notifier-config override the flags.
+	var notifiers []notifier.Notifier
+	if !*dryRun {
+		natsPublisher, err := notifier.NewNATSPublisher(*natsURL, *natsStream, *natsSubjectPrefix)
+		if err != nil {
+			fatal(logger, "Failed to initialize NATS publisher", err)
+		}
+		defer natsPublisher.Close()
+
+		flagConfig := notifier.Config{
+			SlackWebhookURL:  *slackWebhook,
+			AlertmanagerURL:  *alertmanagerURL,
+			TelegramBotToken: *telegramBotToken,
+			TelegramChatID:   *telegramChatID,
+		}
+		notifyConfig := flagConfig
+		if *notifierConfigPath != "" {
+			if notifyConfig, err = notifier.LoadConfig(*notifierConfigPath, flagConfig); err != nil {
+				fatal(logger, "Invalid --notifier-config", err)
+			}
+		}
+
+		slack := notifier.NewSlackNotifier(notifyConfig.SlackWebhookURL)
+		slack.SetBaseURL(*externalURL)
+		notifiers = []notifier.Notifier{
+			slack,
+			notifier.NewAlertmanagerNotifier(notifyConfig.AlertmanagerURL),
+			notifier.NewTelegramNotifier(notifyConfig.TelegramBotToken, notifyConfig.TelegramChatID),
+			natsPublisher,
+		}
+
+		if *notifierConfigPath != "" {
+			// The watcher only keeps the notifiers enabled at startup, so
+			// only those can be reconfigured
+			var reconfigurable []notifier.Reconfigurer
+			for _, n := range notifiers {
+				if r, ok := n.(notifier.Reconfigurer); ok && n.IsEnabled() {
+					reconfigurable = append(reconfigurable, r)
+				}
+			}
+			reloads = append(reloads, func() {
+				cfg, err := notifier.LoadConfig(*notifierConfigPath, flagConfig)
+				if err != nil {
+					logger.Warn("Failed to reload notifier settings", slog.Any("error", err))
+					return
+				}
+				changed := cfg.Changed(notifyConfig)
+				if len(changed) == 0 {
+					logger.Info("Notifier settings unchanged", slog.String("path", *notifierConfigPath))
+					return
+				}
+				for _, r := range reconfigurable {
+					if err := r.Reconfigure(cfg); err != nil {
+						logger.Warn("Failed to reconfigure notifier", slog.String("notifier", r.(notifier.Notifier).Name()), slog.Any("error", err))
+						return
+					}
+				}
+				notifyConfig = cfg
+				logger.Info("Reloaded notifier settings", slog.String("path", *notifierConfigPath), slog.String("updated", strings.Join(changed, ",")))
+			})
+		}
+	}
+
+	// Tail the audit log for actor attribution
+	var auditLog *audit.Tailer
+	if *auditLogPath != "" {
+		auditLog = audit.NewTailer(*auditLogPath)
+		go func() {
+			if err := auditLog.Run(ctx); err != nil {
+				logger.Error("Audit log tailing stopped", slog.Any("error", err))
+			}
+		}()
+		logger.Info("Reading actors from audit log", slog.String("path", *auditLogPath))
+	}
+
+	var rules []watcher.SeverityRule
+	if *severityRules != "" {
+		if rules, err = watcher.LoadSeverityRules(*severityRules); err != nil {
+			fatal(logger, "Invalid --severity-rules", err)
+		}
+		logger.Info("Loaded severity rules", slog.String("path", *severityRules), slog.Int("rules", len(rules)))
+	}
+
 	// Initialize watcher
-	w, err := watcher.NewWatcher(*kubeconfig, store, *slackWebhook)
+	w, err := watcher.NewWatcher(ctx, *kubeconfig, store, notifiers, watcher.Options{
+		StoreSnapshots:            *storeSnapshots,
+		WatchKinds:                splitList(*watchKinds),
+		AuditLog:                  auditLog,
+		ManagedFields:             *enableManagedFields,
+		IngressAnnotationPrefixes: splitList(*ingressAnnotationPrefixes),
+		QuotaCheckInterval:        *quotaCheckInterval,
+		IgnoreResourceVersionOnly: *ignoreRVOnly,
+		ResyncPeriod:              *resyncPeriod,
+		QuotaAlertThreshold:       *quotaAlertThreshold,
+		CronJobCheckInterval:      *cronJobCheckInterval,
+		AppLabel:                  *appLabel,
+		SecretHashKey:             *secretHashKey,
+		DryRun:                    *dryRun,
+		SeverityRules:             rules,
+		NotifyMinSeverity:         *notifyMinSeverity,
+		MaxDiffSize:               *maxDiffSize,
+		MaxSpecSize:               *maxSpecSize,
+		CAFile:                    *caCert,
+		SkipTLSVerify:             *skipTLSVerify,
+	}, logger)
 	if err != nil {
-		log.Fatalf("Failed to initialize watcher: %v", err)
+		fatal(logger, "Failed to initialize watcher", err)
+	}
+	if *pluginDir != "" {
+		plugins, err := plugin.Load(*pluginDir)
+		if err != nil {
+			fatal(logger, "Failed to load plugins", err)
+		}
+		for _, p := range plugins {
+			w.RegisterPlugin(p)
+		}
 	}
 
-	// Start watching
-	if err := w.Start(); err != nil {
-		log.Fatalf("Failed to start watcher: %v", err)
+	// Start watching, either directly or whenever this replica is the leader
+	if *enableLeaderElection {
+		identity := *leaderElectionID
+		if identity == "" {
+			if identity, err = os.Hostname(); err != nil {
+				fatal(logger, "Failed to determine leader election identity", err)
+			}
+		}
+		namespace := *leaderElectionNamespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		logger.Info("Leader election enabled",
+			slog.String("lease", namespace+"/"+*leaderElectionName),
+			slog.String("identity", identity),
+		)
+
+		go func() {
+			err := w.RunWithLeaderElection(ctx, watcher.LeaderElectionConfig{
+				LeaseName:      *leaderElectionName,
+				LeaseNamespace: namespace,
+				Identity:       identity,
+				LeaseDuration:  *leaseDuration,
+			})
+			if err != nil {
+				fatal(logger, "Leader election failed", err)
+			}
+		}()
+	} else if err := w.Start(); err != nil {
+		fatal(logger, "Failed to start watcher", err)
 	}
-	defer w.Stop()
 
 	// Start API server
-	server := api.NewServer(store)
-	go func() {
+	server := api.NewServer(store, logger)
+	server.SetWatchedKinds(w.WatchedKinds())
+	server.SetWatcherHealth(w)
+	server.SetDryRun(*dryRun)
+	server.SetSchemaVersion(schemaVersion)
+	server.SetRetention(*retentionDays)
+	server.SetTrustProxy(*trustProxy)
+	if *webDir != "" {
+		server.SetWebDir(*webDir)
+		logger.Info("Serving web UI from disk", slog.String("path", *webDir))
+	}
+	server.SetEventStream(hub)
+	server.SetETagCache(etags)
+	if err := server.SetAdminAllowlist(splitList(*adminAllowedCIDRs)); err != nil {
+		fatal(logger, "Invalid --admin-allowed-cidrs", err)
+	}
+	if !*dryRun {
+		server.SetNotificationRetrier(w.Notifications())
+	}
+	if *apiTokensFile != "" {
+		tokens, err := api.LoadTokenAuth(*apiTokensFile)
+		if err != nil {
+			fatal(logger, "Invalid --api-tokens", err)
+		}
+		server.SetTokenAuth(tokens)
+		logger.Info("API token authentication enabled", slog.String("path", *apiTokensFile))
+	}
+	if *oidcIssuer != "" {
+		if *oidcClientID == "" {
+			fatal(logger, "Invalid OIDC configuration", fmt.Errorf("--oidc-client-id is required with --oidc-issuer"))
+		}
+		auth, err := api.NewOIDCAuth(ctx, *oidcIssuer, *oidcClientID)
+		if err != nil {
+			fatal(logger, "Failed to initialize OIDC authentication", err)
+		}
+		server.SetOIDCAuth(auth)
+		logger.Info("OIDC authentication enabled", slog.String("issuer", *oidcIssuer))
+	}
+	scheme := "http"
+	if *tlsCert != "" || *tlsKey != "" {
+		files, err := api.LoadTLSFiles(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			fatal(logger, "Invalid --tls-cert/--tls-key", err)
+		}
+		server.SetTLS(files)
+		scheme = "https"
+		logger.Info("TLS enabled", slog.String("cert", *tlsCert), slog.Bool("client_certs", *tlsClientCA != ""))
+
+		// Pick up renewed certificates on change or on SIGHUP
+		go func() {
+			if err := files.Watch(ctx); err != nil {
+				logger.Warn("Not watching TLS files for changes", slog.Any("error", err))
+			}
+		}()
+		reloads = append(reloads, func() {
+			if err := files.Reload(); err != nil {
+				logger.Warn("Failed to reload TLS certificates", slog.Any("error", err))
+				return
+			}
+			logger.Info("Reloaded TLS certificates")
+		})
+	} else if *tlsClientCA != "" {
+		fatal(logger, "Invalid TLS configuration", fmt.Errorf("--tls-client-ca requires --tls-cert and --tls-key"))
+	}
+	if *enablePprof {
+		// The profiles expose memory contents and have no authentication,
+		// so they never share the API listener
+		if *debugAddr == "" {
+			fatal(logger, "Invalid pprof configuration", fmt.Errorf("--enable-pprof requires --debug-addr"))
+		}
+		go func() {
+			logger.Info("pprof enabled", slog.String("addr", *debugAddr))
+			if err := http.ListenAndServe(*debugAddr, api.PprofHandler()); err != nil {
+				fatal(logger, "Failed to start debug server", err)
+			}
+		}()
+	}
+
+	if len(reloads) > 0 {
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			for range hupCh {
+				for _, reload := range reloads {
+					reload()
+				}
+			}
+		}()
+	}
+
+	// The API and, with --port-metrics, the metrics listener. If either fails
+	// the process shuts down.
+	servers, serversCtx := errgroup.WithContext(context.Background())
+	toShutdown := []shutdowner{server}
+	servers.Go(func() error {
 		if err := server.Start(*addr); err != nil {
-			log.Fatalf("Failed to start API server: %v", err)
+			return fmt.Errorf("API server: %w", err)
 		}
-	}()
+		return nil
+	})
+	if *portMetrics > 0 {
+		server.SetMetrics(false)
+		metricsServer := &http.Server{Addr: fmt.Sprintf(":%d", *portMetrics), Handler: api.MetricsHandler()}
+		toShutdown = append(toShutdown, metricsServer)
+		servers.Go(func() error {
+			logger.Info("Serving metrics", slog.String("addr", metricsServer.Addr))
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("metrics server: %w", err)
+			}
+			return nil
+		})
+	}
 
-	log.Printf("K8Watch is running! Access the UI at http://localhost%s", *addr)
+	logger.Info("K8Watch is running! Access the UI at " + scheme + "://localhost" + *addr)
 
-	// Wait for interrupt signal
+	// Wait for an interrupt signal or a failed listener
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	select {
+	case <-sigCh:
+		logger.Info("Shutting down gracefully...")
+	case <-serversCtx.Done():
+	}
 
-	log.Println("Shutting down gracefully...")
+	shutdown(logger, cancel, w, store, hub, toShutdown...)
+	if err := servers.Wait(); err != nil {
+		fatal(logger, "Failed to serve HTTP", err)
+	}
+}
+
+// shutdowner is an HTTP server that can be shut down gracefully
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// shutdownTimeout bounds the wait for in-flight API requests and queued writes
+const shutdownTimeout = 10 * time.Second
+
+// shutdown tears down in dependency order: no new events are produced once the
+// watchers stop, queued events are written before the API stops answering,
+// and the database is closed last
+func shutdown(logger *slog.Logger, cancel context.CancelFunc, w *watcher.Watcher, store storage.EventStore, hub *stream.Hub, servers ...shutdowner) {
+	cancel() // ends leader election, cleanup and audit log tailing
+	w.Stop()
+
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelTimeout()
+
+	if err := store.Flush(ctx); err != nil {
+		logger.Warn("Failed to flush queued events", slog.Any("error", err))
+	}
+
+	hub.Close() // disconnects stream clients, which Shutdown would wait for
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Warn("HTTP server did not shut down cleanly", slog.Any("error", err))
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		logger.Warn("Failed to close storage", slog.Any("error", err))
+	}
+	logger.Info("Shutdown complete")
+}
+
+// newLogger builds the process logger from the --log-level and --log-format flags
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q (expected text or json)", format)
+}
+
+// fatal logs an error and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+// splitList splits a comma-separated flag value; an empty value yields nil
+func splitList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// parseRetentionOverrides parses "Kind=days,..." pairs; the key "default"
+// replaces --retention for all kinds without an override
+func parseRetentionOverrides(value string) (map[string]int, error) {
+	overrides := make(map[string]int)
+	for _, pair := range splitList(value) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kind, daysStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected Kind=days, got %q", pair)
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid number of days for %s: %q", kind, daysStr)
+		}
+		kind = strings.TrimSpace(kind)
+		if strings.EqualFold(kind, "default") {
+			kind = "default"
+		}
+		overrides[kind] = days
+	}
+	return overrides, nil
 }
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// jwksTTL is how long the provider's signing keys are cached before they are
+// fetched again. Tokens signed with an unknown key refetch them right away.
+const jwksTTL = time.Hour
+
+// Identity is the authenticated caller of an API request
+type Identity struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	// Namespaces limits a static API token to namespaces matching these
+	// globs; empty allows all
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity OIDC or token authentication
+// attached to a request
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// OIDCAuth validates bearer tokens issued by an OIDC provider for a client ID
+type OIDCAuth struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCAuth discovers the issuer's configuration. Tokens must be signed
+// with RS256 or ES256 and have clientID as audience.
+func NewOIDCAuth(ctx context.Context, issuer, clientID string) (*OIDCAuth, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+	}
+
+	var discovery struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("failed to read OIDC discovery document: %w", err)
+	}
+
+	keySet := &cachedKeySet{ctx: ctx, url: discovery.JWKSURL}
+	verifier := oidc.NewVerifier(issuer, keySet, &oidc.Config{
+		ClientID:             clientID,
+		SupportedSigningAlgs: []string{oidc.RS256, oidc.ES256},
+	})
+	return &OIDCAuth{verifier: verifier}, nil
+}
+
+// OIDCAuthMiddleware rejects requests without a valid bearer token and attaches
+// the token's sub and email claims to the request context
+func (a *OIDCAuth) OIDCAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			unauthorized(w, "")
+			return
+		}
+
+		idToken, err := a.verifier.Verify(r.Context(), token)
+		if err != nil {
+			unauthorized(w, "invalid_token")
+			return
+		}
+		var claims struct {
+			Email string `json:"email"`
+		}
+		if err := idToken.Claims(&claims); err != nil {
+			unauthorized(w, "invalid_token")
+			return
+		}
+
+		identity := Identity{Subject: idToken.Subject, Email: claims.Email}
+		if rec, ok := w.(*statusRecorder); ok {
+			rec.identity = identity
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+	})
+}
+
+func unauthorized(w http.ResponseWriter, errorCode string) {
+	challenge := `Bearer realm="K8Watch"`
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`, error="%s"`, errorCode)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// cachedKeySet is a remote JWKS that is fetched again once it is older than jwksTTL
+type cachedKeySet struct {
+	ctx context.Context
+	url string
+
+	mu      sync.Mutex
+	keys    *oidc.RemoteKeySet
+	fetched time.Time
+}
+
+func (k *cachedKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	k.mu.Lock()
+	if k.keys == nil || time.Since(k.fetched) > jwksTTL {
+		k.keys = oidc.NewRemoteKeySet(k.ctx, k.url)
+		k.fetched = time.Now()
+	}
+	keys := k.keys
+	k.mu.Unlock()
+
+	return keys.VerifySignature(ctx, jwt)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SetDryRun marks every JSON response with "dry_run": true, so clients can
+// tell that the empty results come from a run that stores nothing
+func (s *Server) SetDryRun(dryRun bool) {
+	s.dryRun = dryRun
+}
+
+// dryRunWriter buffers a response so the dry_run field can be added to it
+type dryRunWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+
+	// passthrough is set once a streaming handler hijacks or flushes the
+	// response; it is then written unchanged
+	passthrough bool
+}
+
+func (w *dryRunWriter) WriteHeader(status int) {
+	if !w.passthrough {
+		w.status = status
+	}
+}
+
+func (w *dryRunWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// Hijack passes the connection through to WebSocket handlers, which write
+// their own response
+func (w *dryRunWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.passthrough = true
+	}
+	return conn, rw, err
+}
+
+// Flush sends what was written so far and passes the rest of the response
+// through, for server-sent events
+func (w *dryRunWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// markDryRun adds "dry_run": true to JSON object responses in dry-run mode.
+// Other responses, such as exports and errors, are passed through unchanged.
+func (s *Server) markDryRun(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.dryRun {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &dryRunWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.passthrough {
+			return
+		}
+
+		body := rec.body.Bytes()
+		if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			var response map[string]json.RawMessage
+			if err := json.Unmarshal(body, &response); err == nil {
+				response["dry_run"] = json.RawMessage("true")
+				if marked, err := json.Marshal(response); err == nil {
+					body = append(marked, '\n')
+				}
+			}
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8watch/internal/storage"
+)
+
+// etagCacheSize bounds the number of query strings whose ETag is remembered
+const etagCacheSize = 256
+
+// ETagCache remembers the ETag of the last /api/events response per query
+// string, so polls answered with 304 Not Modified skip the database. Every
+// stored event invalidates it; entries also expire after cacheTTL, which
+// covers changes that publish no event such as coalesced repeats or retention
+// cleanup. It implements storage.EventPublisher.
+type ETagCache struct {
+	generation atomic.Uint64 // bumped on every change
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *etagEntry, most recently used first
+}
+
+type etagEntry struct {
+	key        string
+	etag       string
+	generation uint64
+	created    time.Time
+}
+
+// NewETagCache creates an empty ETag cache
+func NewETagCache() *ETagCache {
+	return &ETagCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Publish invalidates all cached ETags once an event is stored
+func (c *ETagCache) Publish(event *storage.ChangeEvent) {
+	c.Invalidate()
+}
+
+// Invalidate forgets all cached ETags, e.g. after an event was edited
+func (c *ETagCache) Invalidate() {
+	c.generation.Add(1)
+}
+
+// Generation returns the current generation, to be read before building a
+// response that is then stored with Put
+func (c *ETagCache) Generation() uint64 {
+	return c.generation.Load()
+}
+
+// Get returns the cached ETag of key if it is still valid
+func (c *ETagCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*etagEntry)
+	if entry.generation != c.Generation() || time.Since(entry.created) >= cacheTTL {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.etag, true
+}
+
+// Put caches the ETag of key for a response built at generation
+func (c *ETagCache) Put(key, etag string, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &etagEntry{key: key, etag: etag, generation: generation, created: time.Now()}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > etagCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagEntry).key)
+	}
+}
+
+// SetETagCache replaces the server's ETag cache, e.g. with one that is also
+// the storage's event publisher
+func (s *Server) SetETagCache(cache *ETagCache) {
+	s.etags = cache
+}
+
+// invalidateETags drops the cached ETags after every request that may have
+// changed events, such as tagging, annotating or deleting them
+func (s *Server) invalidateETags(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			s.etags.Invalidate()
+		}
+	})
+}
+
+// writeJSONWithETag writes v as JSON with an ETag of the body, or just the
+// ETag and 304 Not Modified if the request's If-None-Match matches it. It
+// returns the ETag, or "" if v could not be encoded.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return ""
+	}
+	body = append(body, '\n') // as written by json.Encoder
+	etag := computeETag(body)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return etag
+	}
+	w.Write(body)
+	return etag
+}
+
+// computeETag returns a quoted FNV-64a hash of a response body
+func computeETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header matches etag. Weak
+// validators compare equal to strong ones, as RFC 9110 asks for GET.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
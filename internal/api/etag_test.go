@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"k8watch/internal/storage"
+)
+
+func TestETagNotModified(t *testing.T) {
+	server, _ := newTestServer(t, storage.NewMemoryStore(), seededEvents(20)...)
+
+	for _, path := range []string{"/api/events", "/api/stats", "/api/stats/namespace/team-1", "/api/timeline/team-1/Deployment/web-1"} {
+		first := httptest.NewRecorder()
+		server.router.ServeHTTP(first, httptest.NewRequest(http.MethodGet, path, nil))
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" {
+			t.Errorf("GET %s = %d with ETag %q, want 200 with an ETag", path, first.Code, etag)
+			continue
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		second := httptest.NewRecorder()
+		server.router.ServeHTTP(second, req)
+		if second.Code != http.StatusNotModified || second.Body.Len() != 0 {
+			t.Errorf("GET %s with If-None-Match = %d with %d bytes, want an empty 304", path, second.Code, second.Body.Len())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("If-None-Match", `"stale"`)
+		third := httptest.NewRecorder()
+		server.router.ServeHTTP(third, req)
+		if third.Code != http.StatusOK {
+			t.Errorf("GET %s with a stale If-None-Match = %d, want 200", path, third.Code)
+		}
+	}
+}
+
+// BenchmarkPollPayload polls stats and a timeline on a seeded SQLite database
+// the way the dashboard does, with and without If-None-Match, and reports the
+// bytes sent per poll
+func BenchmarkPollPayload(b *testing.B) {
+	store, err := storage.NewStorage(filepath.Join(b.TempDir(), "events.db"), "", slog.New(slog.DiscardHandler))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer store.Close()
+	for _, event := range seededEvents(5000) {
+		event.Name = "web" // one long timeline
+		if err := store.SaveEventSync(context.Background(), event); err != nil {
+			b.Fatal(err)
+		}
+	}
+	server := NewServer(store, slog.New(slog.DiscardHandler))
+
+	for name, path := range map[string]string{"stats": "/api/stats", "timeline": "/api/timeline/team-1/Deployment/web"} {
+		first := httptest.NewRecorder()
+		server.router.ServeHTTP(first, httptest.NewRequest(http.MethodGet, path, nil))
+		etag := first.Header().Get("ETag")
+
+		for _, conditional := range []bool{false, true} {
+			mode := "full"
+			if conditional {
+				mode = "if-none-match"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				var size int
+				for b.Loop() {
+					req := httptest.NewRequest(http.MethodGet, path, nil)
+					if conditional {
+						req.Header.Set("If-None-Match", etag)
+					}
+					rec := httptest.NewRecorder()
+					server.router.ServeHTTP(rec, req)
+					if rec.Code != http.StatusOK && rec.Code != http.StatusNotModified {
+						b.Fatalf("status = %d", rec.Code)
+					}
+					size = rec.Body.Len()
+				}
+				b.ReportMetric(float64(size), "bytes/response")
+			})
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+)
+
+// exportFormats maps the format parameter to the response content type
+var exportFormats = map[string]string{
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"csv":    "text/csv; charset=utf-8",
+}
+
+var csvHeader = []string{
+	"id", "timestamp", "namespace", "kind", "name", "action", "severity", "source",
+	"author", "actor", "image_before", "image_after", "uid", "resource_version", "tags", "repeat_count", "last_seen", "diff", "metadata",
+}
+
+// exportEvents streams all events matching the /api/events filters as JSON,
+// NDJSON or CSV. Events are written as they are read, never held in memory.
+func (s *Server) exportEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := eventFilter(query)
+	filter.OrderBy = query.Get("order_by")
+	filter.Order = query.Get("order")
+	if err := storage.ValidateSort(filter.OrderBy, filter.Order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = l
+	}
+	if !scopeFilter(w, r, &filter) {
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, "format must be json, ndjson or csv", http.StatusBadRequest)
+		return
+	}
+
+	var write func(*storage.ChangeEvent) error
+	var csvWriter *csv.Writer
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		first := true
+		write = func(event *storage.ChangeEvent) error {
+			if !first {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			return encoder.Encode(event)
+		}
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		write = func(event *storage.ChangeEvent) error {
+			return encoder.Encode(event)
+		}
+	case "csv":
+		csvWriter = csv.NewWriter(w)
+		write = func(event *storage.ChangeEvent) error {
+			csvWriter.Write(csvRecord(event))
+			// csv.Writer buffers everything until it is flushed
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	}
+
+	// The headers go out with the first event, so errors before it still get a status
+	started := false
+	start := func() {
+		started = true
+		filename := fmt.Sprintf("kubewatcher-events-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		switch format {
+		case "json":
+			w.Write([]byte("["))
+		case "csv":
+			csvWriter.Write(csvHeader)
+			csvWriter.Flush()
+		}
+	}
+
+	count := 0
+	err := s.storage.StreamEvents(r.Context(), filter, func(event *storage.ChangeEvent) error {
+		if !started {
+			start()
+		}
+		count++
+		return write(event)
+	})
+
+	switch {
+	case !started && errors.Is(err, storage.ErrFullTextUnavailable):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case !started && err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case err != nil:
+		// The status is already sent; the client gets a truncated document
+		s.logger.Warn("Export failed", slog.Int("events_written", count), slog.Any("error", err))
+		return
+	case !started:
+		// No events still make a valid, empty document
+		start()
+	}
+	if format == "json" {
+		w.Write([]byte("]\n"))
+	}
+}
+
+// csvRecord renders an event as a CSV row in csvHeader order
+func csvRecord(event *storage.ChangeEvent) []string {
+	lastSeen := ""
+	if event.LastSeen != nil {
+		lastSeen = event.LastSeen.Format(time.RFC3339Nano)
+	}
+	record := []string{
+		strconv.FormatInt(event.ID, 10),
+		event.Timestamp.Format(time.RFC3339Nano),
+		event.Namespace,
+		event.Kind,
+		event.Name,
+		event.Action,
+		event.Severity,
+		event.Source,
+		event.Author,
+		event.Actor,
+		event.ImageBefore,
+		event.ImageAfter,
+		event.UID,
+		event.ResourceVersion,
+		strings.Join(event.Tags, ";"),
+		strconv.FormatInt(event.RepeatCount, 10),
+		lastSeen,
+		event.Diff,
+		event.Metadata,
+	}
+	for i, value := range record {
+		record[i] = csvSafe(value)
+	}
+	return record
+}
+
+// csvSafe prefixes values a spreadsheet would evaluate as a formula with a quote
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler serves the Prometheus metrics at /metrics, e.g. on a
+// separate listener
+func MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// SetMetrics controls whether /metrics is served on the API listener. It is
+// on by default; turn it off when the metrics have a listener of their own.
+func (s *Server) SetMetrics(enabled bool) {
+	s.metricsOff = !enabled
+}
+
+// serveMetrics serves the metrics unless they were turned off
+func (s *Server) serveMetrics() http.Handler {
+	metrics := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.metricsOff {
+			http.NotFound(w, r)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
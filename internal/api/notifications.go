@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"k8watch/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// NotificationRetrier retries failed notification deliveries for
+// POST /api/notifications/{id}/retry
+type NotificationRetrier interface {
+	Retry(ctx context.Context, id int64) (*storage.NotificationDelivery, error)
+}
+
+// SetNotificationRetrier enables manual retries of failed notifications
+func (s *Server) SetNotificationRetrier(retrier NotificationRetrier) {
+	s.notifications = retrier
+}
+
+var notificationStatuses = []string{storage.NotificationPending, storage.NotificationDelivered, storage.NotificationFailed}
+
+// getNotifications returns recent notification deliveries, newest first
+func (s *Server) getNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	filter := storage.NotificationFilter{
+		Channel: query.Get("channel"),
+		Status:  query.Get("status"),
+	}
+	if filter.Status != "" && !slices.Contains(notificationStatuses, filter.Status) {
+		http.Error(w, fmt.Sprintf("invalid status %q (expected pending, delivered or failed)", filter.Status), http.StatusBadRequest)
+		return
+	}
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 {
+		filter.Limit = l
+	}
+
+	deliveries, err := s.storage.GetNotifications(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notifications": deliveries,
+		"count":         len(deliveries),
+	})
+}
+
+// retryNotification makes one more attempt at a failed delivery
+func (s *Server) retryNotification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid notification id", http.StatusBadRequest)
+		return
+	}
+	if s.notifications == nil {
+		http.Error(w, "notifications are disabled", http.StatusNotImplemented)
+		return
+	}
+
+	delivery, err := s.notifications.Retry(r.Context(), id)
+	switch {
+	case errors.Is(err, storage.ErrNotificationNotFound), errors.Is(err, storage.ErrEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrNotificationNotRetryable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(delivery)
+}
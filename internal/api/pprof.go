@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofHandler serves the net/http/pprof profiles under /debug/pprof/. It
+// has no authentication, so it is only served on the separate --debug-addr
+// listener.
+func PprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8watch/internal/storage"
+)
+
+func TestPprofHandler(t *testing.T) {
+	ts := httptest.NewServer(PprofHandler())
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/debug/pprof/":                  "Types of profiles available",
+		"/debug/pprof/heap?debug=1":      "heap profile",
+		"/debug/pprof/goroutine?debug=1": "goroutine profile",
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, resp.StatusCode)
+			continue
+		}
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET %s does not contain %q", path, want)
+		}
+	}
+}
+
+func TestPprofNotOnAPIServer(t *testing.T) {
+	server := NewServer(storage.NewMemoryStore(), slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/debug/pprof/heap?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && strings.Contains(string(body), "heap profile") {
+		t.Error("the API server serves pprof profiles")
+	}
+}
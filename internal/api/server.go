@@ -1,23 +1,78 @@
 package api
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"k8watch/internal/diff"
 	"k8watch/internal/storage"
+	"k8watch/internal/stream"
+	"k8watch/internal/version"
+	"k8watch/internal/watcher"
+	"k8watch/web"
 
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
-	storage    *storage.Storage
+	storage    storage.EventStore
+	logger     *slog.Logger
 	router     *mux.Router
-	statsCache *cacheEntry
+	http       *http.Server
+	statsCache map[statsCacheKey]*cacheEntry
+	nsCache    map[string]*cacheEntry // namespace stats, keyed by namespace
 	cacheMutex sync.RWMutex
+
+	watchedKinds  []string
+	health        WatcherHealth
+	auth          *OIDCAuth
+	tokens        *TokenAuth // static API tokens, see tokens.go
+	dryRun        bool
+	notifications NotificationRetrier
+
+	adminAllowlist mux.MiddlewareFunc // nil leaves /api/admin/ open
+	trustProxy     bool
+
+	stream        *stream.Hub // live events, see stream.go
+	schemaVersion int         // reported by /api/version
+	retentionDays int         // default days for POST /api/admin/cleanup
+	metricsOff    bool        // /metrics has its own listener, see metrics.go
+	assets        fs.FS       // dashboard files, see static.go
+	etags         *ETagCache  // conditional GETs of /api/events, see etag.go
+}
+
+// WatcherHealth reports watcher restarts for /api/health and the state of
+// each kind's watcher for /api/resources/watched
+type WatcherHealth interface {
+	RestartCounts() map[string]int32
+	RecentRestarts(window time.Duration) map[string]int
+	Statuses() map[string]watcher.WatcherStatus
+}
+
+// statsCacheKey identifies cached /api/stats responses by window and by the
+// namespace globs of the caller's token
+type statsCacheKey struct {
+	window time.Duration
+	scope  string
 }
 
 type cacheEntry struct {
@@ -25,65 +80,646 @@ type cacheEntry struct {
 	timestamp time.Time
 }
 
-const cacheTTL = 10 * time.Second
+const (
+	cacheTTL          = 10 * time.Second
+	namespaceCacheTTL = 30 * time.Second
+)
 
-// NewServer creates a new API server
-func NewServer(storage *storage.Storage) *Server {
+// NewServer creates a new API server. A nil logger uses slog.Default().
+func NewServer(storage storage.EventStore, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	s := &Server{
-		storage: storage,
-		router:  mux.NewRouter(),
+		storage:    storage,
+		logger:     logger,
+		router:     mux.NewRouter(),
+		statsCache: make(map[statsCacheKey]*cacheEntry),
+		nsCache:    make(map[string]*cacheEntry),
+		etags:      NewETagCache(),
+		assets:     web.Assets,
+
+		retentionDays: 60,
 	}
+	s.http = &http.Server{Handler: s.router}
 	s.setupRoutes()
 	return s
 }
 
+// SetWatchedKinds records which resource kinds the watcher is running for
+func (s *Server) SetWatchedKinds(kinds []string) {
+	s.watchedKinds = kinds
+}
+
+// SetSchemaVersion records the database schema version for /api/version
+func (s *Server) SetSchemaVersion(version int) {
+	s.schemaVersion = version
+}
+
+// SetRetention sets the retention in days that POST /api/admin/cleanup uses
+// when the request has no days parameter
+func (s *Server) SetRetention(days int) {
+	s.retentionDays = days
+}
+
+// SetOIDCAuth requires a valid OIDC bearer token on the API routes
+func (s *Server) SetOIDCAuth(auth *OIDCAuth) {
+	s.auth = auth
+}
+
+// publicPaths stay open when authentication is on: the health endpoint for
+// liveness and readiness probes and the watcher status for operators
+var publicPaths = []string{"/api/health", "/api/resources/watched"}
+
+// authenticate applies static token and OIDC authentication when they are
+// configured, except on publicPaths
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (s.auth == nil && s.tokens == nil) || slices.Contains(publicPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.tokens != nil {
+			bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token, ok := s.tokens.lookup(bearer); ok && bearer != "" {
+				identity := Identity{Subject: token.Name, Namespaces: token.Namespaces}
+				if rec, ok := w.(*statusRecorder); ok {
+					rec.identity = identity
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+				return
+			}
+			if s.auth == nil {
+				errorCode := "invalid_token"
+				if bearer == "" {
+					errorCode = ""
+				}
+				unauthorized(w, errorCode)
+				return
+			}
+		}
+		s.auth.OIDCAuthMiddleware(next).ServeHTTP(w, r)
+	})
+}
+
+// SetTrustProxy makes the server take client addresses from X-Forwarded-For.
+// Only enable it behind a proxy that sets the header.
+func (s *Server) SetTrustProxy(trust bool) {
+	s.trustProxy = trust
+}
+
+// SetAdminAllowlist restricts the /api/admin/ routes to clients in the given
+// CIDR blocks. An empty list leaves them open to every client.
+func (s *Server) SetAdminAllowlist(cidrs []string) error {
+	if len(cidrs) == 0 {
+		s.adminAllowlist = nil
+		return nil
+	}
+	if _, err := parseCIDRs(cidrs); err != nil {
+		return err
+	}
+	s.adminAllowlist = s.IPAllowlistMiddleware(cidrs)
+	return nil
+}
+
+// restrictAdmin applies the admin allowlist when it is configured
+func (s *Server) restrictAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminAllowlist == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s.adminAllowlist(next).ServeHTTP(w, r)
+	})
+}
+
+// gzipMinSize is the smallest response worth compressing
+const gzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// gzipResponses compresses responses of at least gzipMinSize bytes for
+// clients that accept gzip. Streams (server-sent events and WebSockets) are
+// passed through.
+func gzipResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the first gzipMinSize bytes of a response to
+// decide whether to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool         // the header was sent, compressed or not
+	gz      *gzip.Writer // set when compressing
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < gzipMinSize {
+			return len(b), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// start sends the header and the held back bytes, compressed when compress is
+// set and the response is not already encoded or a stream
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" && !strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends the response so far, uncompressed if it is still short, as
+// streaming handlers expect
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack passes the connection through to WebSocket handlers
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.decided = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close sends a response that stayed below gzipMinSize, or finishes the
+// compressed stream
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(io.Discard) // don't keep the connection alive in the pool
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+// IPAllowlistMiddleware rejects requests from clients outside allowedCIDRs
+// with 403. Entries may be CIDR blocks or single addresses; invalid ones are
+// logged and skipped, use SetAdminAllowlist to reject them instead.
+func (s *Server) IPAllowlistMiddleware(allowedCIDRs []string) mux.MiddlewareFunc {
+	var allowed []netip.Prefix
+	for _, cidr := range allowedCIDRs {
+		prefixes, err := parseCIDRs([]string{cidr})
+		if err != nil {
+			s.logger.Warn("Ignoring invalid allowlist entry", slog.Any("error", err))
+			continue
+		}
+		allowed = append(allowed, prefixes...)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := s.clientIP(r)
+			if ok && slices.ContainsFunc(allowed, func(p netip.Prefix) bool { return p.Contains(ip) }) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			s.logger.Warn("Rejected request from IP not in allowlist",
+				slog.String("path", r.URL.Path), slog.String("ip", ip.String()))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "forbidden: IP not in allowlist"})
+		})
+	}
+}
+
+// parseCIDRs parses CIDR blocks; a single address is treated as a block of one
+func parseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR block %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR block %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// clientIP returns the address of the client. With a trusted proxy it is the
+// last X-Forwarded-For entry, the one the proxy added; earlier entries can be
+// set by the client.
+func (s *Server) clientIP(r *http.Request) (netip.Addr, bool) {
+	host := r.RemoteAddr
+	if s.trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			host = strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// SetWatcherHealth sets the source of watcher restart counts for /api/health
+// and of the watcher states for /api/resources/watched
+func (s *Server) SetWatcherHealth(health WatcherHealth) {
+	s.health = health
+}
+
 // setupRoutes configures API routes
 func (s *Server) setupRoutes() {
 	// API routes (must come before static files)
 	api := s.router.PathPrefix("/api").Subrouter()
+	api.Use(s.logRequests)
+	api.Use(gzipResponses)
+	api.Use(s.authenticate)
+	api.Use(s.restrictScope)
+	api.Use(s.markDryRun)
+	api.Use(s.invalidateETags)
 	api.HandleFunc("/events", s.getEvents).Methods("GET")
+	api.HandleFunc("/stream", s.streamEvents).Methods("GET")
+	api.HandleFunc("/events/stream", s.streamEventsSSE).Methods("GET")
+	api.HandleFunc("/search", s.searchEvents).Methods("GET")
+	api.HandleFunc("/export", s.exportEvents).Methods("GET")
+	api.HandleFunc("/events/{id:[0-9]+}", s.getEvent).Methods("GET")
+	api.HandleFunc("/events/{id:[0-9]+}/snapshot", s.getSnapshot).Methods("GET")
+	api.HandleFunc("/events/{id:[0-9]+}/tags", s.setEventTags).Methods("POST")
+	api.HandleFunc("/events/{id:[0-9]+}/annotations", s.addAnnotation).Methods("POST")
+	api.HandleFunc("/events/{id:[0-9]+}/notes", s.getEventNotes).Methods("GET")
+	api.HandleFunc("/events/{id:[0-9]+}/notes", s.addEventNote).Methods("POST")
+	api.HandleFunc("/tags", s.getTags).Methods("GET")
 	api.HandleFunc("/timeline/{namespace}/{kind}/{name}", s.getTimeline).Methods("GET")
+	api.HandleFunc("/apps/{namespace}/{app}/timeline", s.getAppTimeline).Methods("GET")
+	api.HandleFunc("/compare", s.compareEvents).Methods("GET")
+	api.HandleFunc("/diff", s.diffEvents).Methods("GET")
 	api.HandleFunc("/stats", s.getStats).Methods("GET")
-	api.HandleFunc("/cleanup", s.cleanupOldEvents).Methods("POST")
+	api.HandleFunc("/stats/namespace/{namespace}", s.getNamespaceStats).Methods("GET")
+	api.HandleFunc("/stats/namespaces", s.getTopNamespaces).Methods("GET")
+	api.HandleFunc("/histogram", s.getHistogram).Methods("GET")
+	api.HandleFunc("/aggregate", s.getAggregate).Methods("GET")
+	api.HandleFunc("/db", s.getDBStats).Methods("GET")
+	api.HandleFunc("/config/kinds", s.getWatchedKinds).Methods("GET")
+	api.HandleFunc("/health", s.getHealth).Methods("GET")
+	api.HandleFunc("/resources", s.getResources).Methods("GET")
+	api.HandleFunc("/resources/watched", s.getWatchedResources).Methods("GET")
+	api.HandleFunc("/resources/{namespace}/{kind}/{name}/summary", s.getResourceSummary).Methods("GET")
+	api.HandleFunc("/version", s.getVersion).Methods("GET")
+	api.HandleFunc("/images", s.getImageRollouts).Methods("GET")
+	api.HandleFunc("/images/history", s.getImageHistoryByPrefix).Methods("GET")
+	api.HandleFunc("/images/{namespace}/{name}", s.getImageHistory).Methods("GET")
+	api.HandleFunc("/changesets", s.getChangesets).Methods("GET")
+	api.HandleFunc("/notifications", s.getNotifications).Methods("GET")
+	api.HandleFunc("/notifications/{id:[0-9]+}/retry", s.retryNotification).Methods("POST")
+
+	// Admin routes, restricted by the admin allowlist
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(s.restrictAdmin)
+	admin.HandleFunc("/cleanup", s.cleanupOldEvents).Methods("POST")
+	admin.HandleFunc("/events/{id:[0-9]+}", s.deleteEvent).Methods("DELETE")
+
+	s.router.Handle("/metrics", s.serveMetrics())
 
-	// Static files (catch-all, must be last)
-	s.router.PathPrefix("/").Handler(http.FileServer(http.Dir("./web")))
+	// Dashboard (catch-all, must be last)
+	s.router.PathPrefix("/").HandlerFunc(s.serveStatic)
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server and blocks until it fails or Shutdown is called
 func (s *Server) Start(addr string) error {
-	log.Printf("Starting API server on %s", addr)
-	return http.ListenAndServe(addr, s.router)
+	s.logger.Info("Starting API server", slog.String("addr", addr), slog.Bool("tls", s.http.TLSConfig != nil))
+	s.http.Addr = addr
+	var err error
+	if s.http.TLSConfig != nil {
+		err = s.http.ListenAndServeTLS("", "") // certificates come from SetTLS
+	} else {
+		err = s.http.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish or ctx to be done. Streams should be closed first, see SetEventStream.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
 }
 
-// getEvents returns filtered events
+// getEvents returns filtered events. Responses carry an ETag; a poll whose
+// If-None-Match still matches gets 304 Not Modified.
 func (s *Server) getEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	key := r.URL.RawQuery
+	if identity, ok := IdentityFromContext(r.Context()); ok && len(identity.Namespaces) > 0 {
+		key = identity.Subject + "?" + key // scoped tokens see different results
+	}
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if etag, ok := s.etags.Get(key); ok && ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	generation := s.etags.Generation()
+
 	query := r.URL.Query()
+	filter := eventFilter(query)
+	filter.Limit = 50 // default page size
+
+	if err := parsePagination(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := parseSinceID(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !scopeFilter(w, r, &filter) {
+		return
+	}
+
+	events, err := s.storage.GetEvents(r.Context(), filter)
+	if errors.Is(err, storage.ErrFullTextUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get total count for pagination
+	totalCount, err := s.storage.GetTotalCount(r.Context(), filter)
+	if err != nil {
+		s.logger.Warn("Failed to get total count", slog.Any("error", err))
+		totalCount = int64(len(events))
+	}
+
+	response := map[string]interface{}{
+		"events":      events,
+		"count":       len(events),
+		"total_count": totalCount,
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+		"max_id":      maxEventID(events, filter.SinceID),
+	}
+	addCursors(response, events, filter)
+
+	if etag := writeJSONWithETag(w, r, response); etag != "" {
+		s.etags.Put(key, etag, generation)
+	}
+}
+
+// parsePagination reads limit, offset, the ordering and the cursor parameters
+// into filter. cursor pages towards older events, after towards newer ones.
+func parsePagination(query url.Values, filter *storage.Filter) error {
+	if limit := query.Get("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
+			filter.Offset = o
+		}
+	}
+
+	filter.OrderBy = query.Get("order_by")
+	filter.Order = query.Get("order")
+	if err := storage.ValidateSort(filter.OrderBy, filter.Order); err != nil {
+		return err
+	}
+
+	var err error
+	if filter.Before, err = cursorParam(query, "cursor"); err != nil {
+		return err
+	}
+	if filter.After, err = cursorParam(query, "after"); err != nil {
+		return err
+	}
+	if (filter.Before != nil || filter.After != nil) && !filter.DefaultOrder() {
+		return fmt.Errorf("cursor pagination needs the default order (timestamp desc); use offset instead")
+	}
+	return nil
+}
+
+// parseSinceID reads since_id, which polls for the events stored after that
+// ID, oldest first. Responses report max_id to poll with next.
+func parseSinceID(query url.Values, filter *storage.Filter) error {
+	param := query.Get("since_id")
+	if param == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || id < 0 {
+		return fmt.Errorf("invalid since_id %q", param)
+	}
+	if filter.Before != nil || filter.After != nil {
+		return fmt.Errorf("since_id can't be combined with cursor or after")
+	}
+	if (filter.OrderBy != "" && filter.OrderBy != "id") || filter.Order == storage.SortDesc {
+		return fmt.Errorf("since_id returns events in ID order; leave out order_by and order")
+	}
+	filter.SinceID = id
+	filter.OrderBy, filter.Order = "id", storage.SortAsc
+	return nil
+}
+
+// maxEventID returns the highest ID among events, or floor if it is higher
+// or there are none, so a poll with since_id=max_id never goes backwards
+func maxEventID(events []storage.ChangeEvent, floor int64) int64 {
+	for i := range events {
+		floor = max(floor, events[i].ID)
+	}
+	return floor
+}
+
+// addCursors adds next_cursor, which fetches the following (older) page, and
+// prev_cursor, which fetches newer events, to a paged response. Pages in a
+// custom order get none.
+func addCursors(response map[string]interface{}, events []storage.ChangeEvent, filter storage.Filter) {
+	if len(events) == 0 || !filter.DefaultOrder() {
+		return
+	}
+	response["prev_cursor"] = storage.CursorFor(&events[0]).String()
+	if len(events) == filter.Limit {
+		response["next_cursor"] = storage.CursorFor(&events[len(events)-1]).String()
+	}
+}
+
+// eventFilter builds the filter shared by the event listing endpoints from
+// the query parameters, leaving out pagination
+func eventFilter(query url.Values) storage.Filter {
 	filter := storage.Filter{
-		Namespace: query.Get("namespace"),
-		Kind:      query.Get("kind"),
-		Name:      query.Get("name"),
-		Action:    query.Get("action"),
-		Limit:     50, // default page size
+		Namespaces:        listParam(query, "namespace"),
+		Kinds:             listParam(query, "kind"),
+		Name:              query.Get("name"),
+		Actions:           listParam(query, "action"),
+		Author:            query.Get("author"),
+		UID:               query.Get("uid"),
+		Source:            query.Get("source"),
+		Severity:          query.Get("severity"),
+		Tag:               query.Get("tag"),
+		App:               query.Get("app"),
+		Query:             query.Get("q"),
+		ExcludeNamespaces: listParam(query, "exclude_namespace"),
+		ExcludeKinds:      listParam(query, "exclude_kind"),
+		ExcludeActions:    listParam(query, "exclude_action"),
 	}
 
 	// Parse time filters
 	if startTime := query.Get("start_time"); startTime != "" {
 		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
-			filter.StartTime = t
+			filter.StartTime = t.UTC()
 		}
 	}
 	if endTime := query.Get("end_time"); endTime != "" {
 		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
-			filter.EndTime = t
+			filter.EndTime = t.UTC()
+		}
+	}
+	if acknowledged := query.Get("acknowledged"); acknowledged != "" {
+		if ack, err := strconv.ParseBool(acknowledged); err == nil {
+			filter.Acknowledged = &ack
+		}
+	}
+	return filter
+}
+
+// cursorParam parses an optional pagination cursor query parameter
+func cursorParam(query url.Values, key string) (*storage.Cursor, error) {
+	token := query.Get(key)
+	if token == "" {
+		return nil, nil
+	}
+	return storage.ParseCursor(token)
+}
+
+// listParam returns the values of a query parameter given either
+// comma-separated (kind=Deployment,StatefulSet) or repeated
+func listParam(query url.Values, key string) []string {
+	var values []string
+	for _, raw := range query[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
 		}
 	}
+	return values
+}
+
+// maxSearchResults caps the number of events returned by /api/search
+const maxSearchResults = 100
+
+// searchEvents handles free-text search across name, namespace, diff and metadata
+func (s *Server) searchEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	text := sanitizeSearchQuery(query.Get("q"))
+	if text == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
 
-	// Parse limit and offset (pagination)
+	filter := storage.Filter{
+		Namespaces: listParam(query, "namespace"),
+		Kinds:      listParam(query, "kind"),
+		Actions:    listParam(query, "action"),
+		Limit:      maxSearchResults,
+	}
 	if limit := query.Get("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 && l <= maxSearchResults {
 			filter.Limit = l
 		}
 	}
@@ -92,101 +728,1212 @@ func (s *Server) getEvents(w http.ResponseWriter, r *http.Request) {
 			filter.Offset = o
 		}
 	}
-
-	events, err := s.storage.GetEvents(filter)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !scopeFilter(w, r, &filter) {
 		return
 	}
 
-	// Get total count for pagination
-	totalCount, err := s.storage.GetTotalCount(filter)
+	events, err := s.storage.SearchEvents(r.Context(), text, filter)
 	if err != nil {
-		log.Printf("Warning: failed to get total count: %v", err)
-		totalCount = int64(len(events))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"events":      events,
-		"count":       len(events),
-		"total_count": totalCount,
-		"offset":      filter.Offset,
-		"limit":       filter.Limit,
+		"query":  text,
+		"events": events,
+		"count":  len(events),
+		"offset": filter.Offset,
+		"limit":  filter.Limit,
 	})
 }
 
-// getTimeline returns timeline for a specific resource
-func (s *Server) getTimeline(w http.ResponseWriter, r *http.Request) {
+// sanitizeSearchQuery trims the search text, drops control characters and SQL
+// comment/statement tokens, and caps its length. The text is always passed as a
+// bound parameter; this only keeps obviously hostile input out of the query.
+func sanitizeSearchQuery(q string) string {
+	q = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, q)
+	q = strings.NewReplacer("--", "", ";", "", "/*", "", "*/", "").Replace(q)
+	q = strings.TrimSpace(q)
+	if runes := []rune(q); len(runes) > 200 {
+		q = string(runes[:200])
+	}
+	return q
+}
+
+// getEvent returns a single event with its annotations
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	vars := mux.Vars(r)
-	namespace := vars["namespace"]
-	kind := vars["kind"]
-	name := vars["name"]
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
 
-	timeline, err := s.storage.GetTimeline(namespace, kind, name)
+	event, err := s.storage.GetEventByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if event == nil {
+		http.Error(w, storage.ErrEventNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if !allowNamespace(w, r, event.Namespace) {
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"timeline": timeline,
-		"count":    len(timeline),
-	})
+	json.NewEncoder(w).Encode(event)
 }
 
-// getStats returns dashboard statistics
-func (s *Server) getStats(w http.ResponseWriter, r *http.Request) {
+// deleteEvent removes a single event, e.g. one recorded by mistake
+func (s *Server) deleteEvent(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Check cache
-	s.cacheMutex.RLock()
-	if s.statsCache != nil && time.Since(s.statsCache.timestamp) < cacheTTL {
-		json.NewEncoder(w).Encode(s.statsCache.data)
-		s.cacheMutex.RUnlock()
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
 		return
 	}
-	s.cacheMutex.RUnlock()
 
-	// Fetch fresh data
-	stats, err := s.storage.GetStats()
+	err = s.storage.DeleteEvent(r.Context(), id)
+	if errors.Is(err, storage.ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Update cache
-	s.cacheMutex.Lock()
-	s.statsCache = &cacheEntry{
-		data:      stats,
-		timestamp: time.Now(),
-	}
-	s.cacheMutex.Unlock()
-
-	json.NewEncoder(w).Encode(stats)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"message": "Event deleted",
+	})
 }
 
-// cleanupOldEvents manually triggers cleanup of old events
-func (s *Server) cleanupOldEvents(w http.ResponseWriter, r *http.Request) {
+// getSnapshot returns the stored before/after objects of an event and their full diff
+func (s *Server) getSnapshot(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	retentionDays := 60 // default
-	if days := r.URL.Query().Get("days"); days != "" {
-		if d, err := strconv.Atoi(days); err == nil && d > 0 {
-			retentionDays = d
-		}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
 	}
 
-	deleted, err := s.storage.CleanupOldEvents(retentionDays)
+	snapshot, err := s.storage.GetSnapshot(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if snapshot == nil {
+		http.Error(w, "no snapshot stored for this event", http.StatusNotFound)
+		return
+	}
+
+	var before, after interface{}
+	if snapshot.Before != "" {
+		json.Unmarshal([]byte(snapshot.Before), &before)
+	}
+	if snapshot.After != "" {
+		json.Unmarshal([]byte(snapshot.After), &after)
+	}
+
+	fullDiff, err := diff.ComputeDiff(before, after)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"event_id":   snapshot.EventID,
+		"created_at": snapshot.CreatedAt,
+		"before":     before,
+		"after":      after,
+		"diff":       fullDiff,
+	})
+}
+
+// compareEvents returns a structured diff between the resource state recorded
+// by two events of the same resource
+func (s *Server) compareEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	id1, err1 := strconv.ParseInt(query.Get("event1"), 10, 64)
+	id2, err2 := strconv.ParseInt(query.Get("event2"), 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "event1 and event2 must be event IDs", http.StatusBadRequest)
+		return
+	}
+
+	event1, state1, ok := s.loadEventState(w, r, id1)
+	if !ok {
+		return
+	}
+	event2, state2, ok := s.loadEventState(w, r, id2)
+	if !ok {
+		return
+	}
+
+	if event1.Namespace != event2.Namespace || event1.Kind != event2.Kind || event1.Name != event2.Name {
+		http.Error(w, fmt.Sprintf("events refer to different resources: %s %s/%s and %s %s/%s",
+			event1.Kind, event1.Namespace, event1.Name, event2.Kind, event2.Namespace, event2.Name), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := diff.ComputeStructuredDiff(state1, state2)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"event1": event1,
+		"event2": event2,
+		"diff":   changes,
+	})
+}
+
+// loadEventState fetches an event and the resource state it recorded (the
+// snapshot after the change, or before it for deletions). It writes an error
+// response and returns ok=false if either is missing.
+func (s *Server) loadEventState(w http.ResponseWriter, r *http.Request, id int64) (*storage.ChangeEvent, interface{}, bool) {
+	event, err := s.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if event == nil {
+		http.Error(w, fmt.Sprintf("event %d not found", id), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	state, found, err := s.snapshotState(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("no snapshot stored for event %d (requires --store-snapshots)", id), http.StatusNotFound)
+		return nil, nil, false
+	}
+	return event, state, true
+}
+
+// snapshotState returns the resource state recorded in an event's snapshot:
+// the object after the change, or before it for deletions. found is false
+// when the event has no snapshot.
+func (s *Server) snapshotState(ctx context.Context, id int64) (state interface{}, found bool, err error) {
+	snapshot, err := s.storage.GetSnapshot(ctx, id)
+	if err != nil || snapshot == nil {
+		return nil, false, err
+	}
+
+	doc := snapshot.After
+	if doc == "" {
+		doc = snapshot.Before
+	}
+	if err := json.Unmarshal([]byte(doc), &state); err != nil {
+		return nil, false, fmt.Errorf("invalid snapshot for event %d: %w", id, err)
+	}
+	return state, true, nil
+}
+
+// maxDiffEvents caps the events concatenated by /api/diff
+const maxDiffEvents = 1000
+
+// errDiffEventsFull stops reading events once maxDiffEvents are collected
+var errDiffEventsFull = errors.New("diff event limit reached")
+
+// diffEvents describes what changed in a resource between two of its events.
+// With snapshots stored for both, it diffs the recorded states; otherwise it
+// concatenates the diffs of the events after from up to and including to,
+// oldest first.
+func (s *Server) diffEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	fromID, err1 := strconv.ParseInt(query.Get("from"), 10, 64)
+	toID, err2 := strconv.ParseInt(query.Get("to"), 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "from and to must be event IDs", http.StatusBadRequest)
+		return
+	}
+
+	from, ok := s.loadScopedEvent(w, r, fromID)
+	if !ok {
+		return
+	}
+	to, ok := s.loadScopedEvent(w, r, toID)
+	if !ok {
+		return
+	}
+	if from.Namespace != to.Namespace || from.Kind != to.Kind || from.Name != to.Name {
+		http.Error(w, fmt.Sprintf("events refer to different resources: %s %s/%s and %s %s/%s",
+			from.Kind, from.Namespace, from.Name, to.Kind, to.Namespace, to.Name), http.StatusUnprocessableEntity)
+		return
+	}
+	if eventAfter(from, to) {
+		from, to = to, from // always describe the change from the older event
+	}
+
+	response := map[string]interface{}{
+		"from": from,
+		"to":   to,
+	}
+
+	fromState, fromFound, err := s.snapshotState(r.Context(), from.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	toState, toFound, err := s.snapshotState(r.Context(), to.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fromFound && toFound {
+		fullDiff, err := diff.ComputeDiff(fromState, toState)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response["source"] = "snapshots"
+		response["diff"] = fullDiff
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Name filters match substrings, so other resources are skipped here
+	filter := storage.Filter{
+		Namespace: from.Namespace,
+		Kind:      from.Kind,
+		Name:      from.Name,
+		StartTime: from.Timestamp,
+		EndTime:   to.Timestamp,
+		OrderBy:   "timestamp",
+		Order:     storage.SortAsc,
+	}
+	events := []storage.ChangeEvent{}
+	err = s.storage.StreamEvents(r.Context(), filter, func(event *storage.ChangeEvent) error {
+		if event.Name != from.Name || !eventAfter(event, from) || eventAfter(event, to) {
+			return nil
+		}
+		if len(events) == maxDiffEvents {
+			return errDiffEventsFull
+		}
+		events = append(events, *event)
+		return nil
+	})
+	truncated := errors.Is(err, errDiffEventsFull)
+	if err != nil && !truncated {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	diffs := make([]string, len(events))
+	for i, event := range events {
+		diffs[i] = fmt.Sprintf("#%d %s %s\n%s", event.ID, event.Timestamp.Format(time.RFC3339), event.Action, event.Diff)
+	}
+	response["source"] = "events"
+	response["diff"] = strings.Join(diffs, "\n\n")
+	response["events"] = events
+	response["truncated"] = truncated
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadScopedEvent fetches an event the caller's token may see. It writes an
+// error response and returns ok=false otherwise.
+func (s *Server) loadScopedEvent(w http.ResponseWriter, r *http.Request, id int64) (*storage.ChangeEvent, bool) {
+	event, err := s.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if event == nil {
+		http.Error(w, fmt.Sprintf("event %d not found", id), http.StatusNotFound)
+		return nil, false
+	}
+	if !allowNamespace(w, r, event.Namespace) {
+		return nil, false
+	}
+	return event, true
+}
+
+// eventAfter reports whether a was stored after b, by time and then ID
+func eventAfter(a, b *storage.ChangeEvent) bool {
+	if a.Timestamp.Equal(b.Timestamp) {
+		return a.ID > b.ID
+	}
+	return a.Timestamp.After(b.Timestamp)
+}
+
+// defaultImageLimit caps image history results when no limit is given
+const defaultImageLimit = 100
+
+// imageLimit parses the limit query parameter for the image endpoints
+func imageLimit(query url.Values) int {
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil && l > 0 {
+		return l
+	}
+	return defaultImageLimit
+}
+
+// getImageHistory returns the images a deployment has run, newest first
+func (s *Server) getImageHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	history, err := s.storage.GetImageHistory(r.Context(), vars["namespace"], vars["name"], imageLimit(r.URL.Query()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": vars["namespace"],
+		"name":      vars["name"],
+		"history":   history,
+		"count":     len(history),
+	})
+}
+
+// getImageHistoryByPrefix lists the image changes of an image repository,
+// newest first, to see which tags went live where and when
+func (s *Server) getImageHistoryByPrefix(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		http.Error(w, "prefix parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	history, err := s.storage.GetImageHistoryByPrefix(r.Context(), prefix, imageLimit(r.URL.Query()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"prefix":  prefix,
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// defaultChangesetLimit caps the changesets returned when no limit is given
+const defaultChangesetLimit = 50
+
+// getChangesets lists the newest changesets, each a Deployment change with the
+// ReplicaSet events of its rollout
+func (s *Server) getChangesets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := defaultChangesetLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	changesets, err := s.storage.GetChangesets(r.Context(), r.URL.Query().Get("namespace"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"changesets": changesets,
+		"count":      len(changesets),
+	})
+}
+
+// getImageRollouts lists the deployment events that rolled out an exact image
+func (s *Server) getImageRollouts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		http.Error(w, "image parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	events, err := s.storage.GetImageRollouts(r.Context(), image, imageLimit(r.URL.Query()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"image":  image,
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// maxHistogramBuckets bounds the size of a histogram response
+const maxHistogramBuckets = 2000
+
+// histogramBuckets maps the accepted bucket parameter values to sizes
+var histogramBuckets = map[string]time.Duration{
+	"1h":   storage.BucketHour,
+	"hour": storage.BucketHour,
+	"1d":   storage.BucketDay,
+	"24h":  storage.BucketDay,
+	"day":  storage.BucketDay,
+}
+
+// getHistogram returns event counts per time bucket for activity charts.
+// Without start_time it covers the last 24 hours (hourly) or 30 days (daily).
+func (s *Server) getHistogram(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	bucketParam := query.Get("bucket")
+	if bucketParam == "" {
+		bucketParam = "1h"
+	}
+	bucket, ok := histogramBuckets[bucketParam]
+	if !ok {
+		http.Error(w, "bucket must be 1h or 1d", http.StatusBadRequest)
+		return
+	}
+	groupBy := query.Get("group_by")
+	if groupBy != "" && !slices.Contains(storage.HistogramGroupBy, groupBy) {
+		http.Error(w, "group_by must be one of: "+strings.Join(storage.HistogramGroupBy, ", "), http.StatusBadRequest)
+		return
+	}
+
+	filter := eventFilter(query)
+	if filter.EndTime.IsZero() {
+		filter.EndTime = time.Now()
+	}
+	if filter.StartTime.IsZero() {
+		if bucket == storage.BucketDay {
+			filter.StartTime = filter.EndTime.AddDate(0, 0, -30)
+		} else {
+			filter.StartTime = filter.EndTime.Add(-24 * time.Hour)
+		}
+	}
+	if filter.EndTime.Before(filter.StartTime) {
+		http.Error(w, "end_time must not be before start_time", http.StatusBadRequest)
+		return
+	}
+	if filter.EndTime.Sub(filter.StartTime)/bucket >= maxHistogramBuckets {
+		http.Error(w, fmt.Sprintf("time range too large: at most %d buckets", maxHistogramBuckets), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := s.storage.GetEventHistogram(r.Context(), filter, bucket, groupBy)
+	if errors.Is(err, storage.ErrFullTextUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bucket":     bucketParam,
+		"group_by":   groupBy,
+		"start_time": filter.StartTime,
+		"end_time":   filter.EndTime,
+		"buckets":    buckets,
+	})
+}
+
+// defaultTimelineLimit is the timeline page size when no limit is given
+const defaultTimelineLimit = 100
+
+// getTimeline returns timeline for a specific resource, newest first and paged
+func (s *Server) getTimeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	kind := vars["kind"]
+	name := vars["name"]
+	if !allowNamespace(w, r, namespace) {
+		return
+	}
+
+	query := r.URL.Query()
+	parsed := eventFilter(query)
+	filter := storage.Filter{
+		Actions:   parsed.Actions,
+		StartTime: parsed.StartTime,
+		EndTime:   parsed.EndTime,
+		Limit:     defaultTimelineLimit,
+	}
+	if err := parsePagination(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timeline, err := s.storage.GetTimeline(r.Context(), namespace, kind, name, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	countFilter := filter
+	countFilter.Namespace, countFilter.Kind, countFilter.Name = namespace, kind, name
+	totalCount, err := s.storage.GetTotalCount(r.Context(), countFilter)
+	if err != nil {
+		s.logger.Warn("Failed to get timeline count", slog.Any("error", err))
+		totalCount = int64(len(timeline))
+	}
+
+	response := map[string]interface{}{
+		"timeline":    timeline,
+		"count":       len(timeline),
+		"total_count": totalCount,
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+	}
+	addCursors(response, timeline, filter)
+	// group_by=uid splits the timeline into incarnations of the resource
+	if query.Get("group_by") == "uid" {
+		response["incarnations"] = storage.SegmentByUID(timeline)
+	}
+
+	writeJSONWithETag(w, r, response)
+}
+
+// getAppTimeline returns the events of every resource carrying an
+// application label in a namespace, across kinds, newest first
+func (s *Server) getAppTimeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	if !allowNamespace(w, r, vars["namespace"]) {
+		return
+	}
+
+	query := r.URL.Query()
+	parsed := eventFilter(query)
+	filter := storage.Filter{
+		Namespace: vars["namespace"],
+		App:       vars["app"],
+		Kinds:     parsed.Kinds,
+		Actions:   parsed.Actions,
+		StartTime: parsed.StartTime,
+		EndTime:   parsed.EndTime,
+		Limit:     defaultTimelineLimit,
+	}
+	if err := parsePagination(query, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := s.storage.GetEvents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := s.storage.GetTotalCount(r.Context(), filter)
+	if err != nil {
+		s.logger.Warn("Failed to get app timeline count", slog.Any("error", err))
+		totalCount = int64(len(events))
+	}
+
+	response := map[string]interface{}{
+		"namespace":   filter.Namespace,
+		"app":         filter.App,
+		"timeline":    events,
+		"count":       len(events),
+		"total_count": totalCount,
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+	}
+	addCursors(response, events, filter)
+
+	writeJSONWithETag(w, r, response)
+}
+
+// getResourceSummary returns the latest known state of one resource
+func (s *Server) getResourceSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	if !allowNamespace(w, r, vars["namespace"]) {
+		return
+	}
+
+	summary, err := s.storage.GetResourceSummary(r.Context(), vars["namespace"], vars["kind"], vars["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if summary == nil {
+		http.Error(w, "no events for this resource", http.StatusNotFound)
+		return
+	}
+	writeJSONWithETag(w, r, summary)
+}
+
+// Page sizes of /api/resources
+const (
+	defaultResourceLimit = 100
+	maxResourceLimit     = 1000
+)
+
+// getResources lists every resource that has events, with when it was first
+// and last seen and whether it was deleted
+func (s *Server) getResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	filter := storage.ResourceFilter{
+		Namespace: query.Get("namespace"),
+		Kind:      query.Get("kind"),
+		Limit:     defaultResourceLimit,
+	}
+	if param := query.Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = min(l, maxResourceLimit)
+	}
+	if param := query.Get("offset"); param != "" {
+		o, err := strconv.Atoi(param)
+		if err != nil || o < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		filter.Offset = o
+	}
+	if param := query.Get("deleted"); param != "" {
+		deleted, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid deleted", http.StatusBadRequest)
+			return
+		}
+		filter.Deleted = &deleted
+	}
+	if filter.Namespace != "" && !allowNamespace(w, r, filter.Namespace) {
+		return
+	}
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		filter.NamespacePatterns = identity.Namespaces
+	}
+
+	resources, err := s.storage.GetResources(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	total, err := s.storage.CountResources(r.Context(), filter)
+	if err != nil {
+		s.logger.Warn("Failed to count resources", slog.Any("error", err))
+		total = int64(len(resources))
+	}
+
+	writeJSONWithETag(w, r, map[string]interface{}{
+		"resources":   resources,
+		"count":       len(resources),
+		"total_count": total,
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+	})
+}
+
+// getStats returns dashboard statistics
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	window := storage.DefaultStatsWindow
+	if param := r.URL.Query().Get("window"); param != "" {
+		var err error
+		if window, err = parseWindow(param); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := storage.StatsFilter{Window: window, NamespacePatterns: scopePatterns(r)}
+	key := statsCacheKey{window: window, scope: strings.Join(filter.NamespacePatterns, ",")}
+
+	// Check cache
+	s.cacheMutex.RLock()
+	if entry := s.statsCache[key]; entry != nil && time.Since(entry.timestamp) < cacheTTL {
+		s.cacheMutex.RUnlock()
+		writeJSONWithETag(w, r, entry.data)
+		return
+	}
+	s.cacheMutex.RUnlock()
+
+	// Fetch fresh data
+	stats, err := s.storage.GetStats(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Update cache, dropping expired windows so it does not grow without bound
+	s.cacheMutex.Lock()
+	for key, entry := range s.statsCache {
+		if time.Since(entry.timestamp) >= cacheTTL {
+			delete(s.statsCache, key)
+		}
+	}
+	s.statsCache[key] = &cacheEntry{
+		data:      stats,
+		timestamp: time.Now(),
+	}
+	s.cacheMutex.Unlock()
+
+	writeJSONWithETag(w, r, stats)
+}
+
+// getAggregate counts the events of a time window per namespace, kind or name
+func (s *Server) getAggregate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "namespace"
+	}
+	if !slices.Contains(storage.AggregateGroupBy, groupBy) {
+		http.Error(w, "group_by must be one of: "+strings.Join(storage.AggregateGroupBy, ", "), http.StatusBadRequest)
+		return
+	}
+	window := storage.DefaultStatsWindow
+	if param := query.Get("window"); param != "" {
+		var err error
+		if window, err = parseWindow(param); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := storage.StatsFilter{Window: window, NamespacePatterns: scopePatterns(r)}
+	counts, err := s.storage.GetAggregate(r.Context(), groupBy, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group_by": groupBy,
+		"window":   storage.FormatWindow(window),
+		"counts":   counts,
+	})
+}
+
+// maxTopNamespaces bounds the limit parameter of /api/stats/namespaces
+const maxTopNamespaces = 100
+
+// getTopNamespaces ranks the namespaces by their changes in a time window
+func (s *Server) getTopNamespaces(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	window := storage.DefaultStatsWindow
+	if param := query.Get("window"); param != "" {
+		var err error
+		if window, err = parseWindow(param); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 10
+	if param := query.Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(l, maxTopNamespaces)
+	}
+
+	namespaces, err := s.storage.GetTopChangedNamespaces(r.Context(), window, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONWithETag(w, r, map[string]interface{}{
+		"window":     storage.FormatWindow(window),
+		"limit":      limit,
+		"namespaces": namespaces,
+	})
+}
+
+// getNamespaceStats returns the statistics of a single namespace
+func (s *Server) getNamespaceStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	namespace := mux.Vars(r)["namespace"]
+	if !allowNamespace(w, r, namespace) {
+		return
+	}
+
+	s.cacheMutex.RLock()
+	if entry := s.nsCache[namespace]; entry != nil && time.Since(entry.timestamp) < namespaceCacheTTL {
+		s.cacheMutex.RUnlock()
+		writeJSONWithETag(w, r, entry.data)
+		return
+	}
+	s.cacheMutex.RUnlock()
+
+	stats, err := s.storage.GetNamespaceStats(r.Context(), namespace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.cacheMutex.Lock()
+	for key, entry := range s.nsCache {
+		if time.Since(entry.timestamp) >= namespaceCacheTTL {
+			delete(s.nsCache, key)
+		}
+	}
+	s.nsCache[namespace] = &cacheEntry{
+		data:      stats,
+		timestamp: time.Now(),
+	}
+	s.cacheMutex.Unlock()
+
+	writeJSONWithETag(w, r, stats)
+}
+
+// maxStatsWindow bounds the window parameter of /api/stats
+const maxStatsWindow = 365 * 24 * time.Hour
+
+// parseWindow parses a stats window such as 1h, 24h, 7d or 30d
+func parseWindow(param string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(param, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", param)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(param); err != nil {
+			return 0, fmt.Errorf("invalid window %q", param)
+		}
+	}
+	if window < time.Minute || window > maxStatsWindow {
+		return 0, fmt.Errorf("window must be between 1m and 365d")
+	}
+	return window, nil
+}
+
+// cleanupOldEvents manually triggers cleanup of old events
+func (s *Server) cleanupOldEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	retentionDays := s.retentionDays
+	if days := r.URL.Query().Get("days"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil && d > 0 {
+			retentionDays = d
+		}
+	}
+
+	deleted, err := s.storage.CleanupOldEvents(r.Context(), retentionDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted":         storage.SumCounts(deleted),
+		"deleted_by_kind": deleted,
+		"retention_days":  retentionDays,
+		"message":         "Cleanup completed successfully",
+	})
+}
+
+// setEventTags replaces the tags of an event
+func (s *Server) setEventTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := storage.NormalizeTags(body.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.storage.SetEventTags(r.Context(), id, tags)
+	if errors.Is(err, storage.ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":   id,
+		"tags": tags,
+	})
+}
+
+// addAnnotation attaches a note or an acknowledgement to an event. With OIDC
+// enabled the author is the authenticated caller.
+func (s *Server) addAnnotation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Author       string `json:"author"`
+		Note         string `json:"note"`
+		Acknowledged bool   `json:"acknowledged"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	annotation := &storage.Annotation{
+		EventID:      id,
+		Author:       body.Author,
+		Note:         body.Note,
+		Acknowledged: body.Acknowledged,
+	}
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		annotation.Author = identity.Subject
+		if identity.Email != "" {
+			annotation.Author = identity.Email
+		}
+	}
+	if err := storage.ValidateAnnotation(annotation); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.storage.AddAnnotation(r.Context(), annotation)
+	if errors.Is(err, storage.ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(annotation)
+}
+
+// addEventNote appends an operator note to an event and returns all of its
+// notes. With OIDC enabled the author is the authenticated caller.
+func (s *Server) addEventNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Note   string `json:"note"`
+		Author string `json:"author"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		body.Author = identity.Subject
+		if identity.Email != "" {
+			body.Author = identity.Email
+		}
+	}
+	if _, _, err := storage.ValidateNote(body.Note, body.Author); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.storage.AddEventNote(r.Context(), id, body.Note, body.Author)
+	if errors.Is(err, storage.ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	event, err := s.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if event == nil {
+		http.Error(w, storage.ErrEventNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(eventNotes(event))
+}
+
+// getEventNotes returns the operator notes of an event, oldest first
+func (s *Server) getEventNotes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid event id", http.StatusBadRequest)
+		return
+	}
+
+	event, err := s.storage.GetEventByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if event == nil {
+		http.Error(w, storage.ErrEventNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(eventNotes(event))
+}
+
+// eventNotes is the response body of the notes endpoints
+func eventNotes(event *storage.ChangeEvent) map[string]interface{} {
+	notes := event.Notes
+	if notes == nil {
+		notes = []storage.EventNote{}
+	}
+	return map[string]interface{}{
+		"event_id": event.ID,
+		"notes":    notes,
+	}
+}
+
+// getTags returns all tags in use with their event counts
+func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tags, err := s.storage.GetTags(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+// getDBStats returns the database size and rows per kind
+func (s *Server) getDBStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	stats, err := s.storage.DBStats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// getWatchedKinds returns the resource kinds currently being watched
+func (s *Server) getWatchedKinds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	kinds := s.watchedKinds
+	if kinds == nil {
+		kinds = []string{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kinds": kinds,
+	})
+}
+
+const (
+	// A watcher restarted more than maxRecentRestarts times within
+	// restartWindow makes /api/health report unhealthy
+	maxRecentRestarts = 10
+	restartWindow     = time.Hour
+)
+
+// getHealth reports whether the watchers are running normally
+func (s *Server) getHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := "ok"
+	restarts := map[string]int32{}
+	failing := []string{}
+	if s.health != nil {
+		restarts = s.health.RestartCounts()
+		for kind, count := range s.health.RecentRestarts(restartWindow) {
+			if count > maxRecentRestarts {
+				failing = append(failing, kind)
+			}
+		}
+	}
+	if len(failing) > 0 {
+		sort.Strings(failing)
+		status = "unhealthy"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           status,
+		"version":          version.Version,
+		"commit":           version.GitCommit,
+		"watcher_restarts": restarts,
+		"failing_watchers": failing,
+	})
+}
+
+// getWatchedResources reports the state of the watcher of every enabled kind
+func (s *Server) getWatchedResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	statuses := map[string]watcher.WatcherStatus{}
+	if s.health != nil {
+		statuses = s.health.Statuses()
+	}
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// getVersion reports the running build and database schema version
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"deleted":        deleted,
-		"retention_days": retentionDays,
-		"message":        "Cleanup completed successfully",
+		"version":        version.Version,
+		"commit":         version.GitCommit,
+		"build_date":     version.BuildDate,
+		"go_version":     runtime.Version(),
+		"schema_version": s.schemaVersion,
 	})
 }
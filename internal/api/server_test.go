@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"k8watch/internal/storage"
+)
+
+// newTestServer serves the API over store, seeded with events
+func newTestServer(t *testing.T, store storage.EventStore, events ...*storage.ChangeEvent) (*Server, *httptest.Server) {
+	t.Helper()
+	for _, event := range events {
+		if err := store.SaveEventSync(context.Background(), event); err != nil {
+			t.Fatalf("SaveEventSync: %v", err)
+		}
+	}
+	server := NewServer(store, slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+// getJSON fetches path and decodes a 200 response into v. It returns the
+// status code.
+func getJSON(t *testing.T, ts *httptest.Server, path string, v interface{}) int {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGetEventsSort(t *testing.T) {
+	now := time.Now().UTC()
+	_, ts := newTestServer(t, storage.NewMemoryStore(),
+		&storage.ChangeEvent{Timestamp: now.Add(-3 * time.Minute), Namespace: "default", Kind: "Deployment", Name: "web", Action: "MODIFIED"},
+		&storage.ChangeEvent{Timestamp: now.Add(-2 * time.Minute), Namespace: "default", Kind: "ConfigMap", Name: "api", Action: "MODIFIED"},
+		&storage.ChangeEvent{Timestamp: now.Add(-1 * time.Minute), Namespace: "default", Kind: "Service", Name: "cache", Action: "ADDED"},
+	)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		{name: "default order", query: "", wantStatus: http.StatusOK, wantNames: []string{"cache", "api", "web"}},
+		{name: "timestamp ascending", query: "order=asc", wantStatus: http.StatusOK, wantNames: []string{"web", "api", "cache"}},
+		{name: "name ascending", query: "order_by=name&order=asc", wantStatus: http.StatusOK, wantNames: []string{"api", "cache", "web"}},
+		{name: "kind descending", query: "order_by=kind&order=desc", wantStatus: http.StatusOK, wantNames: []string{"cache", "web", "api"}},
+		{name: "unknown field", query: "order_by=severity", wantStatus: http.StatusBadRequest},
+		{name: "injected field", query: "order_by=name%3B%20DROP%20TABLE%20change_events", wantStatus: http.StatusBadRequest},
+		{name: "unknown direction", query: "order=sideways", wantStatus: http.StatusBadRequest},
+		{name: "upper case direction", query: "order=DESC", wantStatus: http.StatusBadRequest},
+		{name: "cursor with a custom order", query: "order_by=name&cursor=abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response struct {
+				Events []storage.ChangeEvent `json:"events"`
+			}
+			status := getJSON(t, ts, "/api/events?"+tt.query, &response)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if tt.wantNames == nil {
+				return
+			}
+			names := make([]string, len(response.Events))
+			for i, event := range response.Events {
+				names[i] = event.Name
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+// seededEvents returns count Deployment events a second apart
+func seededEvents(count int) []*storage.ChangeEvent {
+	base := time.Now().UTC().Add(-time.Duration(count) * time.Second)
+	events := make([]*storage.ChangeEvent, count)
+	for i := range events {
+		events[i] = &storage.ChangeEvent{
+			Timestamp:   base.Add(time.Duration(i) * time.Second),
+			Namespace:   fmt.Sprintf("team-%d", i%10),
+			Kind:        "Deployment",
+			Name:        fmt.Sprintf("web-%d", i),
+			Action:      "MODIFIED",
+			Diff:        fmt.Sprintf("Image updated: registry.example.com/web:1.%d → registry.example.com/web:1.%d", i, i+1),
+			Metadata:    `{"replicas":3}`,
+			ImageBefore: fmt.Sprintf("registry.example.com/web:1.%d", i),
+			ImageAfter:  fmt.Sprintf("registry.example.com/web:1.%d", i+1),
+		}
+	}
+	return events
+}
+
+func TestGzipResponses(t *testing.T) {
+	server, _ := newTestServer(t, storage.NewMemoryStore(), seededEvents(100)...)
+
+	plain := httptest.NewRecorder()
+	server.router.ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/api/events?limit=100", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?limit=100", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	compressed := httptest.NewRecorder()
+	server.router.ServeHTTP(compressed, req)
+
+	if got := compressed.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if plain.Header().Get("Content-Encoding") != "" {
+		t.Error("response compressed without Accept-Encoding")
+	}
+	zr, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, plain.Body.Bytes()) {
+		t.Error("decompressed body differs from the uncompressed response")
+	}
+}
+
+// BenchmarkEventsGzip serves a page of 1000 events with and without gzip and
+// reports the bytes sent per response
+func BenchmarkEventsGzip(b *testing.B) {
+	store := storage.NewMemoryStore()
+	for _, event := range seededEvents(1000) {
+		if err := store.SaveEventSync(context.Background(), event); err != nil {
+			b.Fatal(err)
+		}
+	}
+	server := NewServer(store, slog.New(slog.DiscardHandler))
+
+	for _, encoding := range []string{"identity", "gzip"} {
+		b.Run(encoding, func(b *testing.B) {
+			var size int
+			for b.Loop() {
+				req := httptest.NewRequest(http.MethodGet, "/api/events?limit=1000", nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				rec := httptest.NewRecorder()
+				server.router.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					b.Fatalf("status = %d", rec.Code)
+				}
+				size = rec.Body.Len()
+			}
+			b.ReportMetric(float64(size), "bytes/response")
+		})
+	}
+}
+
+// slowStore holds GetEvents until release is closed
+type slowStore struct {
+	*storage.MemoryStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) GetEvents(ctx context.Context, filter storage.Filter) ([]storage.ChangeEvent, error) {
+	close(s.started)
+	<-s.release
+	return s.MemoryStore.GetEvents(ctx, filter)
+}
+
+func TestShutdownWaitsForRequests(t *testing.T) {
+	store := &slowStore{MemoryStore: storage.NewMemoryStore(), started: make(chan struct{}), release: make(chan struct{})}
+	server := NewServer(store, slog.New(slog.DiscardHandler))
+
+	// Pick a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	served := make(chan error, 1)
+	go func() { served <- server.Start(addr) }()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+	}
+
+	type result struct {
+		status int
+		err    error
+	}
+	responded := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/api/events")
+		if err != nil {
+			responded <- result{err: err}
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		responded <- result{status: resp.StatusCode}
+	}()
+	<-store.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(ctx) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v while a request was in flight", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(store.release)
+
+	if r := <-responded; r.err != nil || r.status != http.StatusOK {
+		t.Errorf("in-flight request = %d, %v; want 200", r.status, r.err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Start after Shutdown = %v, want nil", err)
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("server still accepts connections after Shutdown")
+	}
+}
+
+func TestGetEventAndTimeline(t *testing.T) {
+	now := time.Now().UTC()
+	events := []*storage.ChangeEvent{
+		{Timestamp: now.Add(-2 * time.Minute), Namespace: "shop", Kind: "Deployment", Name: "web", Action: "ADDED"},
+		{Timestamp: now.Add(-time.Minute), Namespace: "shop", Kind: "Deployment", Name: "web", Action: "MODIFIED", Diff: "Scaled up: 1 → 3 replicas"},
+		{Timestamp: now, Namespace: "shop", Kind: "Service", Name: "web", Action: "ADDED"},
+	}
+	_, ts := newTestServer(t, storage.NewMemoryStore(), events...)
+
+	var event storage.ChangeEvent
+	if status := getJSON(t, ts, fmt.Sprintf("/api/events/%d", events[1].ID), &event); status != http.StatusOK {
+		t.Fatalf("GET /api/events/{id} = %d", status)
+	}
+	if event.ID != events[1].ID || event.Diff != "Scaled up: 1 → 3 replicas" {
+		t.Errorf("GET /api/events/{id} = %+v", event)
+	}
+	if status := getJSON(t, ts, "/api/events/999", nil); status != http.StatusNotFound {
+		t.Errorf("GET of a missing event = %d, want 404", status)
+	}
+
+	var timeline struct {
+		Timeline []storage.ChangeEvent `json:"timeline"`
+		Count    int                   `json:"count"`
+	}
+	if status := getJSON(t, ts, "/api/timeline/shop/Deployment/web", &timeline); status != http.StatusOK {
+		t.Fatalf("GET /api/timeline = %d", status)
+	}
+	if timeline.Count != 2 || timeline.Timeline[0].ID != events[1].ID || timeline.Timeline[1].ID != events[0].ID {
+		t.Errorf("timeline = %+v, want the two Deployment events newest first", timeline.Timeline)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8watch/internal/storage"
+	"k8watch/internal/stream"
+)
+
+const (
+	// sseKeepAlive is how often an idle event stream sends a comment, so
+	// proxies do not close it
+	sseKeepAlive = 15 * time.Second
+	// maxReplayEvents caps the events replayed after Last-Event-ID per
+	// connection; the stream then ends and the client resumes from the last one
+	maxReplayEvents = 1000
+)
+
+// errReplayTruncated ends a stream whose replay reached maxReplayEvents
+var errReplayTruncated = errors.New("replay truncated")
+
+// streamEventsSSE sends every newly stored event as a server-sent event with
+// the event ID as its id. The namespace, kind and action parameters filter the
+// events. A client resuming with Last-Event-ID first gets the matching events
+// stored after that ID.
+func (s *Server) streamEventsSSE(w http.ResponseWriter, r *http.Request) {
+	if s.stream == nil {
+		http.Error(w, "event stream is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var lastID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	filter := stream.FilterFromQuery(r.URL.Query())
+	// Subscribe before replaying, so no event falls between the two
+	sub, err := s.stream.Subscribe(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would buffer the stream
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		s.logger.Warn("Event stream cannot be flushed", slog.Any("error", err))
+		return
+	}
+
+	if lastID > 0 {
+		replay := filter.EventFilter(lastID)
+		replay.Order = storage.SortAsc
+		replay.Limit = maxReplayEvents
+		replayed := 0
+		err := s.storage.StreamEvents(r.Context(), replay, func(event *storage.ChangeEvent) error {
+			replayed++
+			lastID = max(lastID, event.ID)
+			return writeSSE(w, event)
+		})
+		if err == nil && replayed == maxReplayEvents {
+			err = errReplayTruncated
+		}
+		if err != nil {
+			s.logger.Debug("Event stream replay ended", slog.Int64("last_event_id", lastID), slog.Any("reason", err))
+			return
+		}
+		rc.Flush()
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				s.logger.Debug("Event stream ended", slog.String("remote", r.RemoteAddr), slog.Any("reason", sub.Err()))
+				return
+			}
+			if event.ID <= lastID {
+				continue // already replayed
+			}
+			if err := writeSSE(w, &event); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes event as a server-sent event with its ID as the event id
+func writeSSE(w io.Writer, event *storage.ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, data)
+	return err
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// staticMaxAge is how long browsers may reuse the dashboard's scripts and
+// styles without asking. Their names don't change between releases, so this
+// stays short; index.html is always revalidated.
+const staticMaxAge = 5 * time.Minute
+
+// SetWebDir serves the dashboard from dir instead of the embedded copy, so UI
+// changes show up without a rebuild
+func (s *Server) SetWebDir(dir string) {
+	s.assets = os.DirFS(dir)
+}
+
+// serveStatic serves the dashboard. Unknown paths get index.html so the UI
+// can route them, except API paths and missing files with an extension,
+// which get 404.
+func (s *Server) serveStatic(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "api" || strings.HasPrefix(name, "api/") {
+		http.NotFound(w, r)
+		return
+	}
+	if name == "" {
+		name = "index.html"
+	}
+
+	if info, err := fs.Stat(s.assets, name); err != nil || info.IsDir() {
+		if path.Ext(name) != "" {
+			http.NotFound(w, r)
+			return
+		}
+		name = "index.html" // SPA fallback
+	}
+	data, err := fs.ReadFile(s.assets, name)
+	if err != nil {
+		http.Error(w, "failed to read "+name, http.StatusInternalServerError)
+		return
+	}
+
+	if name == "index.html" {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(staticMaxAge.Seconds())))
+	}
+	// Embedded files have no modification time, so revalidation uses an ETag
+	w.Header().Set("ETag", computeETag(data))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8watch/internal/stream"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamWriteTimeout disconnects stream clients that stop reading
+const streamWriteTimeout = 10 * time.Second
+
+// SetEventStream serves the events published to hub on /api/stream
+func (s *Server) SetEventStream(hub *stream.Hub) {
+	s.stream = hub
+}
+
+// streamEvents pushes every newly stored event to a WebSocket client as a JSON
+// message. The namespace, kind and action parameters filter the events.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if s.stream == nil {
+		http.Error(w, "event stream is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := s.stream.Subscribe(stream.FilterFromQuery(r.URL.Query()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has replied with an error status
+	}
+	defer conn.Close()
+	s.sendEvents(conn, sub)
+}
+
+// streamUpgrader accepts /api/stream handshakes from pages of the same origin
+var streamUpgrader = websocket.Upgrader{CheckOrigin: checkStreamOrigin}
+
+// sendEvents writes the events of sub to conn until the client goes away or
+// the subscription ends, on shutdown or because the client fell behind. The
+// end of a subscription is sent as a close frame.
+func (s *Server) sendEvents(conn *websocket.Conn, sub *stream.Subscription) {
+	// Clients send nothing; reading handles their close frames and notices
+	// when they go away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				s.logger.Debug("Event stream ended", slog.String("remote", conn.RemoteAddr().String()), slog.Any("reason", sub.Err()))
+				message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, sub.Err().Error())
+				conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				s.logger.Debug("Failed to send stream event", slog.String("remote", conn.RemoteAddr().String()), slog.Any("error", err))
+				return
+			}
+		}
+	}
+}
+
+// checkStreamOrigin rejects handshakes from pages of other origins, so other
+// sites cannot read the stream with a visitor's credentials. Clients that send
+// no Origin header are accepted.
+func checkStreamOrigin(r *http.Request) bool {
+	header := r.Header.Get("Origin")
+	if header == "" {
+		return true
+	}
+	origin, err := url.Parse(header)
+	return err == nil && strings.EqualFold(origin.Host, r.Host)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8watch/internal/storage"
+	"k8watch/internal/stream"
+)
+
+func newStreamServer(t *testing.T) (*stream.Hub, *httptest.Server) {
+	t.Helper()
+	hub := stream.NewHub(slog.New(slog.DiscardHandler))
+	server := NewServer(storage.NewMemoryStore(), slog.New(slog.DiscardHandler))
+	server.SetEventStream(hub)
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(func() {
+		hub.Close()
+		ts.Close()
+	})
+	return hub, ts
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	hub, ts := newStreamServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := stream.Dial(ctx, ts.URL, stream.Filter{Kinds: []string{"Deployment"}}, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+	if n := hub.Subscribers(); n != 1 {
+		t.Fatalf("Subscribers() = %d, want 1", n)
+	}
+
+	hub.Publish(&storage.ChangeEvent{ID: 1, Namespace: "default", Kind: "ConfigMap", Name: "settings", Action: "MODIFIED"})
+	hub.Publish(&storage.ChangeEvent{ID: 2, Namespace: "default", Kind: "Deployment", Name: "web", Action: "MODIFIED", Diff: "Scaled up: 1 → 3 replicas"})
+
+	event, err := client.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if event.ID != 2 || event.Kind != "Deployment" || event.Name != "web" || event.Diff != "Scaled up: 1 → 3 replicas" {
+		t.Errorf("Next() = %+v, want the Deployment event", event)
+	}
+
+	hub.Close()
+	if _, err := client.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() after the hub closed = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamRejectsOtherOrigins(t *testing.T) {
+	_, ts := newStreamServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	header := http.Header{"Origin": {"https://evil.example"}}
+	if client, err := stream.Dial(ctx, ts.URL, stream.Filter{}, header); err == nil {
+		client.Close()
+		t.Fatal("Dial with a foreign Origin succeeded")
+	}
+
+	header.Set("Origin", ts.URL)
+	client, err := stream.Dial(ctx, ts.URL, stream.Filter{}, header)
+	if err != nil {
+		t.Fatalf("Dial with the server's Origin: %v", err)
+	}
+	client.Close()
+}
+
+func TestStreamRequiresUpgrade(t *testing.T) {
+	_, ts := newStreamServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET without Upgrade = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TLSFiles serves the API over TLS from certificate files that can be
+// reloaded while running, e.g. after a cert-manager renewal. With a client CA
+// every client must present a certificate signed by it (mutual TLS).
+type TLSFiles struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu     sync.RWMutex
+	config *tls.Config
+}
+
+// LoadTLSFiles loads the key pair and, if clientCAFile is set, the client CA
+// bundle. Both cert and key are required.
+func LoadTLSFiles(certFile, keyFile, clientCAFile string) (*TLSFiles, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	}
+	t := &TLSFiles{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Reload re-reads the files. On error the previous certificates stay in use.
+func (t *TLSFiles) Reload() error {
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if t.clientCAFile != "" {
+		pem, err := os.ReadFile(t.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA %s", t.clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	t.mu.Lock()
+	t.config = config
+	t.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the files when they change until ctx is cancelled. The
+// directories are watched rather than the files, since mounted Secrets are
+// updated by swapping a symlink.
+func (t *TLSFiles) Watch(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	dirs := map[string]bool{}
+	for _, file := range []string{t.certFile, t.keyFile, t.clientCAFile} {
+		if file == "" || dirs[filepath.Dir(file)] {
+			continue
+		}
+		dirs[filepath.Dir(file)] = true
+		if err := fsw.Add(filepath.Dir(file)); err != nil {
+			return fmt.Errorf("failed to watch TLS directory: %w", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			// A renewal writes cert and key separately; until both are
+			// written the pair doesn't match and the old one stays in use
+			if err := t.Reload(); err != nil {
+				slog.Debug("TLS files not reloaded", slog.Any("error", err))
+				continue
+			}
+			slog.Info("Reloaded TLS certificates", slog.String("file", event.Name))
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("TLS file watcher error", slog.Any("error", err))
+		}
+	}
+}
+
+// getConfigForClient hands each handshake the most recently loaded config
+func (t *TLSFiles) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.config, nil
+}
+
+// SetTLS serves the API over TLS from the given files
+func (s *Server) SetTLS(files *TLSFiles) {
+	s.http.TLSConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: files.getConfigForClient,
+	}
+}
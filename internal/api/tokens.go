@@ -0,0 +1,161 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+
+	"k8watch/internal/storage"
+
+	"github.com/gorilla/mux"
+	"sigs.k8s.io/yaml"
+)
+
+// APIToken is a static bearer token. A token with namespaces only sees the
+// events of namespaces matching one of them.
+type APIToken struct {
+	Name       string   `json:"name"`
+	Token      string   `json:"token"`
+	Namespaces []string `json:"namespaces,omitempty"` // globs such as team-a-*; empty allows all
+}
+
+// TokenAuth authenticates requests with static bearer tokens
+type TokenAuth struct {
+	tokens []APIToken
+}
+
+// LoadTokenAuth reads a YAML (or JSON) list of API tokens
+func LoadTokenAuth(file string) (*TokenAuth, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API tokens: %w", err)
+	}
+	var tokens []APIToken
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse API tokens: %w", err)
+	}
+	for i, t := range tokens {
+		switch {
+		case t.Name == "":
+			return nil, fmt.Errorf("API token %d: name is required", i+1)
+		case t.Token == "":
+			return nil, fmt.Errorf("API token %s: token is required", t.Name)
+		}
+		for _, pattern := range t.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("API token %s: invalid namespace pattern %q", t.Name, pattern)
+			}
+		}
+	}
+	return &TokenAuth{tokens: tokens}, nil
+}
+
+// lookup returns the token matching a bearer token. Every token is compared in
+// constant time.
+func (a *TokenAuth) lookup(bearer string) (APIToken, bool) {
+	var found APIToken
+	var ok bool
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(bearer)) == 1 && !ok {
+			found, ok = t, true
+		}
+	}
+	return found, ok
+}
+
+// SetTokenAuth accepts static bearer tokens on the API routes. With OIDC also
+// enabled, tokens that are not in the list are checked by OIDC.
+func (s *Server) SetTokenAuth(auth *TokenAuth) {
+	s.tokens = auth
+}
+
+// scopedRoutes are the routes that restrict their results to the namespaces
+// of a scoped token; other routes reject scoped tokens
+var scopedRoutes = []string{
+	"/api/events",
+	"/api/events/{id:[0-9]+}",
+	"/api/search",
+	"/api/export",
+	"/api/timeline/{namespace}/{kind}/{name}",
+	"/api/diff",
+	"/api/apps/{namespace}/{app}/timeline",
+	"/api/resources",
+	"/api/resources/{namespace}/{kind}/{name}/summary",
+	"/api/stats",
+	"/api/stats/namespace/{namespace}",
+	"/api/aggregate",
+	"/api/config/kinds",
+	"/api/health",
+	"/api/resources/watched",
+	"/api/version",
+}
+
+// restrictScope rejects namespace-scoped tokens on routes that cannot limit
+// their results to the token's namespaces
+func (s *Server) restrictScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok || len(identity.Namespaces) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil && slices.Contains(scopedRoutes, template) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		forbidden(w, "forbidden: not available to namespace-scoped tokens")
+	})
+}
+
+// scopeFilter limits filter to the namespaces of the caller's token. It writes
+// 403 and returns false if the filter asks for a namespace outside them.
+func scopeFilter(w http.ResponseWriter, r *http.Request, filter *storage.Filter) bool {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok || len(identity.Namespaces) == 0 {
+		return true
+	}
+	requested := filter.Namespaces
+	if filter.Namespace != "" {
+		requested = append(slices.Clone(requested), filter.Namespace)
+	}
+	for _, namespace := range requested {
+		if !storage.MatchNamespace(identity.Namespaces, namespace) {
+			forbidden(w, fmt.Sprintf("forbidden: namespace %q is outside this token's scope", namespace))
+			return false
+		}
+	}
+	filter.NamespacePatterns = identity.Namespaces
+	return true
+}
+
+// scopePatterns returns the namespace globs of the caller's token, or nil if
+// it is not scoped
+func scopePatterns(r *http.Request) []string {
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		return identity.Namespaces
+	}
+	return nil
+}
+
+// allowNamespace writes 403 and returns false if the caller's token may not
+// see namespace
+func allowNamespace(w http.ResponseWriter, r *http.Request, namespace string) bool {
+	identity, ok := IdentityFromContext(r.Context())
+	if !ok || len(identity.Namespaces) == 0 || storage.MatchNamespace(identity.Namespaces, namespace) {
+		return true
+	}
+	forbidden(w, fmt.Sprintf("forbidden: namespace %q is outside this token's scope", namespace))
+	return false
+}
+
+func forbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
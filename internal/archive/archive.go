@@ -0,0 +1,30 @@
+package archive
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// archiveTimeFormat is used in archive names; it sorts chronologically
+const archiveTimeFormat = "20060102T150405Z"
+
+// archiveName names an archive after the range of event times it holds.
+// Archiving the same range again, after a failed delete, replaces it.
+func archiveName(start, end time.Time) string {
+	return start.UTC().Format(archiveTimeFormat) + "-" + end.UTC().Format(archiveTimeFormat) + "-events.ndjson.gz"
+}
+
+// gzipReader returns a reader of the gzip-compressed contents of r
+func gzipReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		if _, err := io.Copy(gz, r); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(gz.Close())
+	}()
+	return pr
+}
@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirArchiver writes expired events to a local directory as gzip-compressed NDJSON
+type DirArchiver struct {
+	dir string
+}
+
+// NewDirArchiver creates an archiver for dir, creating it if needed
+func NewDirArchiver(dir string) (*DirArchiver, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &DirArchiver{dir: dir}, nil
+}
+
+// Archive compresses the NDJSON events into {dir}/{start}-{end}-events.ndjson.gz.
+// The file is written under a temporary name and renamed once complete, so
+// a failed archive leaves no partial file behind.
+func (a *DirArchiver) Archive(ctx context.Context, r io.Reader, start, end time.Time) error {
+	name := filepath.Join(a.dir, archiveName(start, end))
+
+	tmp, err := os.CreateTemp(a.dir, ".events-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzipReader(r)
+	defer gz.Close()
+	if _, err := io.Copy(tmp, gz); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to rename archive to %s: %w", name, err)
+	}
+
+	slog.Info("Archived expired events", slog.String("file", name))
+	return nil
+}
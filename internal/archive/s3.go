@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Archiver uploads expired events to S3 as gzip-compressed NDJSON
+type S3Archiver struct {
+	bucket   string
+	prefix   string
+	uploader *manager.Uploader
+}
+
+// NewS3Archiver creates an archiver for bucket/prefix. Credentials and region
+// come from the default AWS chain (environment, shared config, instance profile).
+// A non-empty endpoint selects an S3-compatible store such as MinIO, addressed
+// with path-style URLs.
+func NewS3Archiver(ctx context.Context, bucket, prefix, endpoint string) (*S3Archiver, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Archiver{
+		bucket: bucket,
+		prefix: prefix,
+		// The uploader switches to multipart upload for large archives
+		uploader: manager.NewUploader(client),
+	}, nil
+}
+
+// Archive compresses the NDJSON events and uploads them to
+// s3://{bucket}/{prefix}/{start}-{end}-events.ndjson.gz
+func (a *S3Archiver) Archive(ctx context.Context, r io.Reader, start, end time.Time) error {
+	key := path.Join(a.prefix, archiveName(start, end))
+
+	body := gzipReader(r)
+	defer body.Close()
+
+	_, err := a.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s: %w", a.bucket, key, err)
+	}
+
+	slog.Info("Archived expired events", slog.String("bucket", a.bucket), slog.String("key", key))
+	return nil
+}
@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// bufferSize is the number of recent audit entries kept in memory
+	bufferSize = 10000
+	// matchWindow is how far an audit entry may be from a change event to be attributed to it
+	matchWindow = 5 * time.Second
+)
+
+// Entry is the part of a Kubernetes audit event needed to attribute a change
+type Entry struct {
+	Timestamp time.Time
+	Verb      string
+	Resource  string
+	Namespace string
+	Name      string
+	Username  string
+}
+
+// auditEvent mirrors the fields we read from an audit.k8s.io/v1 Event
+type auditEvent struct {
+	Verb string `json:"verb"`
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef *struct {
+		Resource  string `json:"resource"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"objectRef"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time `json:"stageTimestamp"`
+}
+
+// mutatingVerbs are the only verbs that can produce a change event
+var mutatingVerbs = map[string]bool{
+	"create": true,
+	"update": true,
+	"patch":  true,
+	"delete": true,
+}
+
+// Tailer follows a JSON-lines audit log and keeps the most recent mutating
+// entries in a ring buffer for actor lookups
+type Tailer struct {
+	path string
+
+	mu      sync.RWMutex
+	entries []Entry
+	next    int
+	full    bool
+
+	file    *os.File
+	partial []byte
+}
+
+// NewTailer creates a tailer for the audit log at path
+func NewTailer(path string) *Tailer {
+	return &Tailer{
+		path:    filepath.Clean(path),
+		entries: make([]Entry, bufferSize),
+	}
+}
+
+// Run tails the audit log until ctx is cancelled. Only entries written after
+// startup are read. Rotated or recreated files are picked up automatically.
+func (t *Tailer) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	// Watch the directory so the file can be recreated by log rotation
+	if err := fsw.Add(filepath.Dir(t.path)); err != nil {
+		return fmt.Errorf("failed to watch audit log directory: %w", err)
+	}
+
+	if err := t.open(true); err != nil {
+		slog.Warn("Audit log not readable yet", slog.String("path", t.path), slog.Any("error", err))
+	}
+	defer t.closeFile()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != t.path {
+				continue
+			}
+			switch {
+			case event.Has(fsnotify.Create):
+				t.closeFile()
+				if err := t.open(false); err != nil {
+					slog.Warn("Failed to open audit log", slog.String("path", t.path), slog.Any("error", err))
+				}
+				t.readNew()
+			case event.Has(fsnotify.Write):
+				if t.file == nil {
+					if err := t.open(false); err != nil {
+						slog.Warn("Failed to open audit log", slog.String("path", t.path), slog.Any("error", err))
+						continue
+					}
+				}
+				t.readNew()
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				t.closeFile()
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("Audit log watcher error", slog.Any("error", err))
+		}
+	}
+}
+
+// Lookup returns the username of the most recent mutating audit entry for the
+// object within matchWindow of at, or "" if there is none
+func (t *Tailer) Lookup(resource, namespace, name string, at time.Time) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	count := t.next
+	if t.full {
+		count = len(t.entries)
+	}
+	for i := 1; i <= count; i++ {
+		entry := &t.entries[(t.next-i+len(t.entries))%len(t.entries)]
+		if entry.Resource != resource || entry.Namespace != namespace || entry.Name != name {
+			continue
+		}
+		if delta := at.Sub(entry.Timestamp); delta <= matchWindow && delta >= -matchWindow {
+			return entry.Username
+		}
+	}
+	return ""
+}
+
+// open opens the audit log, optionally positioned at its end
+func (t *Tailer) open(seekEnd bool) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	if seekEnd {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	t.file = file
+	t.partial = nil
+	return nil
+}
+
+func (t *Tailer) closeFile() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+	t.partial = nil
+}
+
+// readNew reads everything appended since the last read and records complete lines
+func (t *Tailer) readNew() {
+	if t.file == nil {
+		return
+	}
+
+	// Start over if the file was truncated in place (copytruncate rotation)
+	if info, err := t.file.Stat(); err == nil {
+		if offset, err := t.file.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset {
+			t.file.Seek(0, io.SeekStart)
+			t.partial = nil
+		}
+	}
+
+	data, err := io.ReadAll(t.file)
+	if err != nil {
+		slog.Warn("Failed to read audit log", slog.String("path", t.path), slog.Any("error", err))
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	data = append(t.partial, data...)
+	lines := bytes.Split(data, []byte("\n"))
+	// The last element is an incomplete line (or empty after a trailing newline)
+	t.partial = append([]byte(nil), lines[len(lines)-1]...)
+	for _, line := range lines[:len(lines)-1] {
+		t.addLine(line)
+	}
+}
+
+// addLine parses one audit event and keeps it if it describes a mutation
+func (t *Tailer) addLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var ev auditEvent
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return
+	}
+	if ev.ObjectRef == nil || !mutatingVerbs[ev.Verb] || ev.User.Username == "" {
+		return
+	}
+
+	timestamp := ev.StageTimestamp
+	if timestamp.IsZero() {
+		timestamp = ev.RequestReceivedTimestamp
+	}
+
+	t.mu.Lock()
+	t.entries[t.next] = Entry{
+		Timestamp: timestamp,
+		Verb:      ev.Verb,
+		Resource:  ev.ObjectRef.Resource,
+		Namespace: ev.ObjectRef.Namespace,
+		Name:      ev.ObjectRef.Name,
+		Username:  ev.User.Username,
+	}
+	t.next = (t.next + 1) % len(t.entries)
+	if t.next == 0 {
+		t.full = true
+	}
+	t.mu.Unlock()
+}
@@ -3,6 +3,9 @@ package diff
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
@@ -26,6 +29,90 @@ func ComputeDiff(oldObj, newObj interface{}) (string, error) {
 	return dmp.DiffPrettyText(diffs), nil
 }
 
+// FieldChange describes a single field that differs between two objects
+type FieldChange struct {
+	Path string      `json:"path"` // dotted path, e.g. spec.template.spec.containers[0].image
+	Type string      `json:"type"` // added, removed, changed
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ComputeStructuredDiff compares two objects field by field and returns the
+// changed leaf fields sorted by path
+func ComputeStructuredDiff(oldObj, newObj interface{}) ([]FieldChange, error) {
+	oldFields, err := flatten(oldObj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten old object: %w", err)
+	}
+	newFields, err := flatten(newObj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten new object: %w", err)
+	}
+
+	changes := []FieldChange{}
+	for path, oldVal := range oldFields {
+		newVal, ok := newFields[path]
+		if !ok {
+			changes = append(changes, FieldChange{Path: path, Type: "removed", Old: oldVal})
+		} else if !reflect.DeepEqual(oldVal, newVal) {
+			changes = append(changes, FieldChange{Path: path, Type: "changed", Old: oldVal, New: newVal})
+		}
+	}
+	for path, newVal := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, Type: "added", New: newVal})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flatten converts an object into a map of leaf paths to values
+func flatten(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	flattenInto(fields, "", generic)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			fields[prefix] = v
+		}
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenInto(fields, path, child)
+		}
+	case []interface{}:
+		if len(v) == 0 && prefix != "" {
+			fields[prefix] = v
+		}
+		for i, child := range v {
+			flattenInto(fields, prefix+"["+strconv.Itoa(i)+"]", child)
+		}
+	case nil:
+		if prefix != "" {
+			fields[prefix] = nil
+		}
+	default:
+		fields[prefix] = v
+	}
+}
+
 // ExtractImage extracts container image from a deployment spec
 func ExtractImage(obj map[string]interface{}) string {
 	// Navigate through the spec to find container image
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8watch/internal/storage"
+)
+
+// alertDuration is how long an alert stays active. Changes are point-in-time
+// events, so alerts resolve on their own instead of waiting for a resolve call.
+const alertDuration = 10 * time.Minute
+
+type AlertmanagerNotifier struct {
+	mu      sync.RWMutex // guards baseURL and enabled, see Reconfigure
+	baseURL string
+	enabled bool
+	client  *http.Client
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// NewAlertmanagerNotifier creates a new Alertmanager notifier.
+// baseURL is the Alertmanager root, e.g. http://alertmanager:9093
+func NewAlertmanagerNotifier(baseURL string) *AlertmanagerNotifier {
+	return &AlertmanagerNotifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		enabled: baseURL != "",
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Name returns the notifier name
+func (a *AlertmanagerNotifier) Name() string {
+	return "Alertmanager"
+}
+
+// IsEnabled returns whether Alertmanager notifications are enabled
+func (a *AlertmanagerNotifier) IsEnabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.enabled
+}
+
+// Reconfigure switches to cfg.AlertmanagerURL
+func (a *AlertmanagerNotifier) Reconfigure(cfg Config) error {
+	if err := checkURL("Alertmanager URL", cfg.AlertmanagerURL); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.baseURL = strings.TrimRight(cfg.AlertmanagerURL, "/")
+	a.enabled = cfg.AlertmanagerURL != ""
+	return nil
+}
+
+// NotifyChange posts a resource change to Alertmanager as a short-lived alert
+func (a *AlertmanagerNotifier) NotifyChange(ctx context.Context, event *storage.ChangeEvent) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if !a.enabled {
+		return nil
+	}
+
+	if !shouldNotify(event.Action) {
+		return nil
+	}
+
+	now := time.Now()
+	alert := alertmanagerAlert{
+		Labels: map[string]string{
+			"alertname": "KubeResourceChanged",
+			"kind":      event.Kind,
+			"namespace": event.Namespace,
+			"resource":  event.Name,
+			"action":    event.Action,
+			"severity":  event.Severity,
+		},
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s %s/%s %s", event.Kind, event.Namespace, event.Name, strings.ToLower(event.Action)),
+			"description": event.Diff,
+		},
+		StartsAt: now,
+		EndsAt:   now.Add(alertDuration),
+	}
+
+	payload, err := json.Marshal([]alertmanagerAlert{alert})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alertmanager alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/api/v2/alerts", bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build alertmanager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alertmanager alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alertmanager returned non-200 status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// TestConnection checks Alertmanager's health endpoint. It deliberately does
+// not fire a test alert, since that would page whoever is on call.
+func (a *AlertmanagerNotifier) TestConnection() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if !a.enabled {
+		return fmt.Errorf("alertmanager notifier is not enabled")
+	}
+
+	resp, err := a.client.Get(a.baseURL + "/-/healthy")
+	if err != nil {
+		return fmt.Errorf("failed to reach alertmanager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alertmanager returned non-200 status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
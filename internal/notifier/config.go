@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds the notifier settings that can change while running, e.g. a
+// rotated Slack webhook
+type Config struct {
+	SlackWebhookURL  string `json:"slack_webhook_url,omitempty"`
+	AlertmanagerURL  string `json:"alertmanager_url,omitempty"`
+	TelegramBotToken string `json:"telegram_bot_token,omitempty"`
+	TelegramChatID   string `json:"telegram_chat_id,omitempty"`
+}
+
+// Reconfigurer is a notifier whose settings can be replaced without a restart
+type Reconfigurer interface {
+	// Reconfigure applies the notifier's fields of cfg. An empty value
+	// disables the notifier; on error the old settings are kept.
+	Reconfigure(cfg Config) error
+}
+
+// LoadConfig reads a YAML or JSON Config from path. Fields the file leaves
+// empty keep their value in base, e.g. the one from a flag.
+func LoadConfig(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read notifier config: %w", err)
+	}
+	var file Config
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return Config{}, fmt.Errorf("failed to parse notifier config: %w", err)
+	}
+
+	cfg := base
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&cfg.SlackWebhookURL, file.SlackWebhookURL},
+		{&cfg.AlertmanagerURL, file.AlertmanagerURL},
+		{&cfg.TelegramBotToken, file.TelegramBotToken},
+		{&cfg.TelegramChatID, file.TelegramChatID},
+	} {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid notifier config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the settings so a reload is rejected as a whole rather
+// than applied to some notifiers only
+func (c Config) Validate() error {
+	if err := checkURL("Slack webhook URL", c.SlackWebhookURL); err != nil {
+		return err
+	}
+	if err := checkURL("Alertmanager URL", c.AlertmanagerURL); err != nil {
+		return err
+	}
+	if (c.TelegramBotToken == "") != (c.TelegramChatID == "") {
+		return fmt.Errorf("telegram bot token and chat ID must be set together")
+	}
+	return nil
+}
+
+// Changed returns the names of the fields that differ from old. Only names
+// are returned since the values are secrets.
+func (c Config) Changed(old Config) []string {
+	var changed []string
+	if c.SlackWebhookURL != old.SlackWebhookURL {
+		changed = append(changed, "slack_webhook_url")
+	}
+	if c.AlertmanagerURL != old.AlertmanagerURL {
+		changed = append(changed, "alertmanager_url")
+	}
+	if c.TelegramBotToken != old.TelegramBotToken {
+		changed = append(changed, "telegram_bot_token")
+	}
+	if c.TelegramChatID != old.TelegramChatID {
+		changed = append(changed, "telegram_chat_id")
+	}
+	return changed
+}
+
+// checkURL rejects values that are set but are not http(s) URLs
+func checkURL(name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s: expected an http(s) URL", name)
+	}
+	return nil
+}
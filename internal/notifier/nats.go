@@ -0,0 +1,159 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	natsStreamMaxAge   = 24 * time.Hour
+	natsPublishTimeout = 10 * time.Second
+)
+
+// NATSPublisher publishes change events to a NATS JetStream stream so
+// downstream services can subscribe to cluster changes
+type NATSPublisher struct {
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	stream        string
+	subjectPrefix string
+	enabled       bool
+}
+
+// NewNATSPublisher connects to NATS and makes sure the JetStream stream exists.
+// An empty url returns a disabled publisher.
+func NewNATSPublisher(url, stream, subjectPrefix string) (*NATSPublisher, error) {
+	p := &NATSPublisher{
+		stream:        stream,
+		subjectPrefix: strings.TrimSuffix(subjectPrefix, "."),
+		enabled:       url != "",
+	}
+	if !p.enabled {
+		return p, nil
+	}
+
+	conn, err := nats.Connect(url, nats.Name("k8watch"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	p.conn = conn
+	p.js = js
+	if err := p.ensureStream(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ensureStream creates the stream if it doesn't exist yet
+func (p *NATSPublisher) ensureStream() error {
+	_, err := p.js.StreamInfo(p.stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("failed to look up stream %s: %w", p.stream, err)
+	}
+
+	_, err = p.js.AddStream(&nats.StreamConfig{
+		Name:      p.stream,
+		Subjects:  []string{p.subjectPrefix + ".>"},
+		Retention: nats.WorkQueuePolicy,
+		MaxAge:    natsStreamMaxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", p.stream, err)
+	}
+	return nil
+}
+
+// Name returns the notifier name
+func (p *NATSPublisher) Name() string {
+	return "NATS"
+}
+
+// IsEnabled returns whether NATS publishing is enabled
+func (p *NATSPublisher) IsEnabled() bool {
+	return p.enabled
+}
+
+// subject returns {prefix}.{namespace}.{kind}.{action}, lowercased
+func (p *NATSPublisher) subject(event *storage.ChangeEvent) string {
+	return strings.ToLower(fmt.Sprintf("%s.%s.%s.%s", p.subjectPrefix, event.Namespace, event.Kind, event.Action))
+}
+
+// NotifyChange publishes the event and waits for the JetStream ack
+func (p *NATSPublisher) NotifyChange(ctx context.Context, event *storage.ChangeEvent) error {
+	if !p.enabled {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	future, err := p.js.PublishAsync(p.subject(event), payload)
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	select {
+	case <-future.Ok():
+		return nil
+	case err := <-future.Err():
+		return fmt.Errorf("nats did not acknowledge event: %w", err)
+	case <-time.After(natsPublishTimeout):
+		return fmt.Errorf("timed out waiting for nats ack")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TestConnection verifies the connection is up and the stream is reachable
+func (p *NATSPublisher) TestConnection() error {
+	if !p.enabled {
+		return fmt.Errorf("nats publisher is not enabled")
+	}
+	if !p.conn.IsConnected() {
+		return fmt.Errorf("not connected to nats (status: %s)", p.conn.Status())
+	}
+	if _, err := p.js.StreamInfo(p.stream); err != nil {
+		return fmt.Errorf("failed to look up stream %s: %w", p.stream, err)
+	}
+	return nil
+}
+
+// Close waits for outstanding publishes to complete and drains the connection
+func (p *NATSPublisher) Close() error {
+	if !p.enabled {
+		return nil
+	}
+
+	var err error
+	select {
+	case <-p.js.PublishAsyncComplete():
+	case <-time.After(natsPublishTimeout):
+		err = fmt.Errorf("timed out waiting for pending nats publishes")
+	}
+	if drainErr := p.conn.Drain(); drainErr != nil && err == nil {
+		err = drainErr
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"context"
+
+	"k8watch/internal/storage"
+)
+
+// Notifier delivers change events to an external system
+type Notifier interface {
+	// Name returns a short human-readable name used in logs
+	Name() string
+	// IsEnabled returns whether the notifier is configured
+	IsEnabled() bool
+	// NotifyChange sends a notification about a resource change
+	NotifyChange(ctx context.Context, event *storage.ChangeEvent) error
+	// TestConnection verifies the notifier can reach its backend
+	TestConnection() error
+}
+
+// shouldNotify reports whether an action is worth a notification. Creations
+// are left out to keep the noise down.
+func shouldNotify(action string) bool {
+	switch action {
+	case "MODIFIED", "DELETED", storage.ActionThresholdExceeded, storage.ActionMissedSchedule:
+		return true
+	default:
+		return false
+	}
+}
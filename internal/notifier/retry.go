@@ -0,0 +1,166 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"k8watch/internal/storage"
+)
+
+const (
+	// maxDeliveryAttempts is how often a notification is tried before it is
+	// marked failed
+	maxDeliveryAttempts = 3
+	// retryBackoff is the wait before the first retry; it doubles after each one
+	retryBackoff = 5 * time.Second
+)
+
+// RetryQueue sends events to notifiers, retrying failed deliveries with
+// backoff. Every delivery is recorded in the store's notification log, so
+// failures can be listed and retried through the API.
+type RetryQueue struct {
+	store     storage.EventStore
+	notifiers map[string]Notifier // by Name
+	logger    *slog.Logger
+}
+
+// NewRetryQueue creates a queue delivering to the given notifiers. A nil
+// logger uses slog.Default().
+func NewRetryQueue(store storage.EventStore, notifiers []Notifier, logger *slog.Logger) *RetryQueue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	q := &RetryQueue{
+		store:     store,
+		notifiers: make(map[string]Notifier, len(notifiers)),
+		logger:    logger,
+	}
+	for _, n := range notifiers {
+		q.notifiers[n.Name()] = n
+	}
+	return q
+}
+
+// Tracks reports whether deliveries of the event are recorded in the
+// notification log. Actions notifiers leave out, such as creations, are
+// still published to NATS but not tracked.
+func (q *RetryQueue) Tracks(event *storage.ChangeEvent) bool {
+	return len(q.notifiers) > 0 && shouldNotify(event.Action)
+}
+
+// Notify delivers the event to every notifier in the background
+func (q *RetryQueue) Notify(ctx context.Context, event *storage.ChangeEvent) {
+	for _, n := range q.notifiers {
+		go q.deliver(ctx, n, event)
+	}
+}
+
+// deliver sends the event to one notifier, retrying up to
+// maxDeliveryAttempts times
+func (q *RetryQueue) deliver(ctx context.Context, n Notifier, event *storage.ChangeEvent) {
+	if !q.Tracks(event) {
+		if err := n.NotifyChange(ctx, event); err != nil {
+			q.logger.Warn("Failed to send notification", append(deliveryAttrs(n, event), slog.Any("error", err))...)
+		}
+		return
+	}
+
+	delivery := &storage.NotificationDelivery{
+		EventID: event.ID,
+		Channel: n.Name(),
+		Status:  storage.NotificationPending,
+	}
+	if err := q.store.RecordNotification(ctx, delivery); err != nil {
+		q.logger.Warn("Failed to record notification", slog.String("notifier", n.Name()), slog.Any("error", err))
+	}
+
+	backoff := retryBackoff
+	for {
+		if q.attempt(ctx, n, event, delivery) {
+			return
+		}
+		if delivery.Attempts >= maxDeliveryAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	q.logger.Warn("Notification failed", append(deliveryAttrs(n, event),
+		slog.Int64("notification_id", delivery.ID),
+		slog.Int("attempts", delivery.Attempts),
+		slog.String("error", delivery.LastError),
+	)...)
+}
+
+// deliveryAttrs identifies a notifier and event in structured logs
+func deliveryAttrs(n Notifier, event *storage.ChangeEvent) []any {
+	return []any{
+		slog.String("notifier", n.Name()),
+		slog.String("kind", event.Kind),
+		slog.String("namespace", event.Namespace),
+		slog.String("name", event.Name),
+		slog.Int64("event_id", event.ID),
+	}
+}
+
+// attempt sends the event once and records the outcome. The delivery stays
+// pending after a failure until it runs out of attempts.
+func (q *RetryQueue) attempt(ctx context.Context, n Notifier, event *storage.ChangeEvent, delivery *storage.NotificationDelivery) bool {
+	err := n.NotifyChange(ctx, event)
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = storage.NotificationDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxDeliveryAttempts:
+		delivery.Status = storage.NotificationFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = storage.NotificationPending
+		delivery.LastError = err.Error()
+	}
+	if delivery.ID != 0 {
+		if err := q.store.UpdateNotification(ctx, delivery); err != nil {
+			q.logger.Warn("Failed to update notification", slog.Int64("notification_id", delivery.ID), slog.Any("error", err))
+		}
+	}
+	return err == nil
+}
+
+// Retry makes one more attempt at a failed delivery and returns its updated record
+func (q *RetryQueue) Retry(ctx context.Context, id int64) (*storage.NotificationDelivery, error) {
+	delivery, err := q.store.GetNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, storage.ErrNotificationNotFound
+	}
+	if delivery.Status != storage.NotificationFailed {
+		return nil, fmt.Errorf("%w: status is %s", storage.ErrNotificationNotRetryable, delivery.Status)
+	}
+	n, ok := q.notifiers[delivery.Channel]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not configured", storage.ErrNotificationNotRetryable, delivery.Channel)
+	}
+	event, err := q.store.GetEventByID(ctx, delivery.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, storage.ErrEventNotFound
+	}
+
+	// Failed deliveries have used up their attempts, so this one ends
+	// delivered or failed again
+	q.attempt(ctx, n, event, delivery)
+	return delivery, nil
+}
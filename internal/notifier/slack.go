@@ -2,16 +2,22 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"k8watch/internal/storage"
+	"k8watch/internal/version"
 )
 
 type SlackNotifier struct {
+	mu         sync.RWMutex // guards webhookURL and enabled, see Reconfigure
 	webhookURL string
+	baseURL    string // links event titles to {baseURL}/api/events/{id} when set
 	enabled    bool
 	client     *http.Client
 }
@@ -33,10 +39,11 @@ type slackTextObj struct {
 }
 
 type slackAttachment struct {
-	Color  string       `json:"color,omitempty"`
-	Title  string       `json:"title,omitempty"`
-	Text   string       `json:"text,omitempty"`
-	Fields []slackField `json:"fields,omitempty"`
+	Color     string       `json:"color,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []slackField `json:"fields,omitempty"`
 }
 
 type slackField struct {
@@ -56,23 +63,49 @@ func NewSlackNotifier(webhookURL string) *SlackNotifier {
 	}
 }
 
+// SetBaseURL sets the external URL of k8watch, e.g. https://k8watch.example.com.
+// Message titles then link to the event.
+func (s *SlackNotifier) SetBaseURL(baseURL string) {
+	s.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// Name returns the notifier name
+func (s *SlackNotifier) Name() string {
+	return "Slack"
+}
+
 // IsEnabled returns whether Slack notifications are enabled
 func (s *SlackNotifier) IsEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.enabled
 }
 
+// Reconfigure switches to cfg.SlackWebhookURL, e.g. after the webhook was rotated
+func (s *SlackNotifier) Reconfigure(cfg Config) error {
+	if err := checkURL("Slack webhook URL", cfg.SlackWebhookURL); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhookURL = cfg.SlackWebhookURL
+	s.enabled = cfg.SlackWebhookURL != ""
+	return nil
+}
+
 // NotifyChange sends a notification about a resource change
-func (s *SlackNotifier) NotifyChange(event *storage.ChangeEvent) error {
+func (s *SlackNotifier) NotifyChange(ctx context.Context, event *storage.ChangeEvent) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if !s.enabled {
 		return nil
 	}
 
-	// Only notify on critical changes (MODIFIED and DELETED)
-	if event.Action != "MODIFIED" && event.Action != "DELETED" {
+	if !shouldNotify(event.Action) {
 		return nil
 	}
 
-	color := s.getColorForAction(event.Action)
+	color := s.getColorForSeverity(event.Severity)
 	emoji := s.getEmojiForKind(event.Kind)
 
 	msg := slackMessage{
@@ -91,11 +124,37 @@ func (s *SlackNotifier) NotifyChange(event *storage.ChangeEvent) error {
 						Value: event.Action,
 						Short: true,
 					},
+					{
+						Title: "Severity",
+						Value: event.Severity,
+						Short: true,
+					},
 				},
 			},
 		},
 	}
 
+	// Link to the stored event; it has no ID when it was queued for writing
+	if s.baseURL != "" && event.ID != 0 {
+		msg.Attachments[0].TitleLink = fmt.Sprintf("%s/api/events/%d", s.baseURL, event.ID)
+	}
+
+	// Add who made the change, if known
+	if event.Author != "" {
+		msg.Attachments[0].Fields = append(msg.Attachments[0].Fields, slackField{
+			Title: "Author",
+			Value: fmt.Sprintf("`%s`", event.Author),
+			Short: true,
+		})
+	}
+	if event.Actor != "" {
+		msg.Attachments[0].Fields = append(msg.Attachments[0].Fields, slackField{
+			Title: "Actor",
+			Value: fmt.Sprintf("`%s`", event.Actor),
+			Short: true,
+		})
+	}
+
 	// Add change details
 	if event.Diff != "" {
 		// Truncate diff if too long
@@ -115,17 +174,23 @@ func (s *SlackNotifier) NotifyChange(event *storage.ChangeEvent) error {
 		})
 	}
 
-	return s.sendMessage(msg)
+	return s.sendMessage(ctx, msg)
 }
 
 // sendMessage sends a message to Slack
-func (s *SlackNotifier) sendMessage(msg slackMessage) error {
+func (s *SlackNotifier) sendMessage(ctx context.Context, msg slackMessage) error {
 	payload, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal slack message: %w", err)
 	}
 
-	resp, err := s.client.Post(s.webhookURL, "application/json", bytes.NewBuffer(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send slack message: %w", err)
 	}
@@ -138,15 +203,15 @@ func (s *SlackNotifier) sendMessage(msg slackMessage) error {
 	return nil
 }
 
-// getColorForAction returns Slack color for action
-func (s *SlackNotifier) getColorForAction(action string) string {
-	switch action {
-	case "ADDED":
-		return "good" // green
-	case "DELETED":
+// getColorForSeverity returns Slack color for severity; events without one are info
+func (s *SlackNotifier) getColorForSeverity(severity string) string {
+	switch severity {
+	case storage.SeverityCritical:
 		return "danger" // red
-	case "MODIFIED":
+	case storage.SeverityWarn:
 		return "warning" // yellow
+	case storage.SeverityNotice:
+		return "#439FE0" // blue
 	default:
 		return "#808080" // gray
 	}
@@ -180,6 +245,8 @@ func (s *SlackNotifier) getEmojiForKind(kind string) string {
 
 // TestConnection sends a test message to verify Slack webhook
 func (s *SlackNotifier) TestConnection() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if !s.enabled {
 		return fmt.Errorf("slack notifier is not enabled")
 	}
@@ -189,10 +256,10 @@ func (s *SlackNotifier) TestConnection() error {
 		Attachments: []slackAttachment{
 			{
 				Color: "good",
-				Text:  "You will receive notifications for critical Kubernetes resource changes.",
+				Text:  "You will receive notifications for critical Kubernetes resource changes.\n" + version.String(),
 			},
 		},
 	}
 
-	return s.sendMessage(msg)
+	return s.sendMessage(context.Background(), msg)
 }
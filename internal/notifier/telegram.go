@@ -0,0 +1,229 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"k8watch/internal/storage"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+	// telegramRateLimit keeps below Telegram's limit of 30 messages per second per bot
+	telegramRateLimit = time.Second / 30
+	// telegramMaxDiff leaves room in the 4096 character message limit for the header
+	telegramMaxDiff = 3000
+)
+
+type TelegramNotifier struct {
+	mu      sync.RWMutex // guards token, chatID, enabled and limiter, see Reconfigure
+	token   string
+	chatID  string
+	enabled bool
+	client  *http.Client
+	limiter *time.Ticker
+}
+
+type telegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// NewTelegramNotifier creates a new Telegram bot notifier. It is enabled when
+// both the bot token and the chat ID are set.
+func NewTelegramNotifier(token, chatID string) *TelegramNotifier {
+	t := &TelegramNotifier{
+		token:   token,
+		chatID:  chatID,
+		enabled: token != "" && chatID != "",
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	if t.enabled {
+		t.limiter = time.NewTicker(telegramRateLimit)
+	}
+	return t
+}
+
+// Name returns the notifier name
+func (t *TelegramNotifier) Name() string {
+	return "Telegram"
+}
+
+// IsEnabled returns whether Telegram notifications are enabled
+func (t *TelegramNotifier) IsEnabled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.enabled
+}
+
+// Reconfigure switches to cfg.TelegramBotToken and cfg.TelegramChatID. They
+// must be set or cleared together.
+func (t *TelegramNotifier) Reconfigure(cfg Config) error {
+	if (cfg.TelegramBotToken == "") != (cfg.TelegramChatID == "") {
+		return fmt.Errorf("telegram bot token and chat ID must be set together")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = cfg.TelegramBotToken
+	t.chatID = cfg.TelegramChatID
+	t.enabled = cfg.TelegramBotToken != ""
+	if t.enabled && t.limiter == nil {
+		t.limiter = time.NewTicker(telegramRateLimit)
+	}
+	return nil
+}
+
+// NotifyChange sends a notification about a resource change
+func (t *TelegramNotifier) NotifyChange(ctx context.Context, event *storage.ChangeEvent) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.enabled {
+		return nil
+	}
+
+	if !shouldNotify(event.Action) {
+		return nil
+	}
+
+	return t.sendMessage(ctx, formatTelegramMessage(event))
+}
+
+// formatTelegramMessage renders an event as a MarkdownV2 message
+func formatTelegramMessage(event *storage.ChangeEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s*\n",
+		telegramEmojiForAction(event.Action),
+		escapeMarkdownV2(fmt.Sprintf("%s %s %s", event.Kind, event.Name, strings.ToLower(event.Action))))
+	fmt.Fprintf(&b, "Namespace: `%s`\n", escapeMarkdownV2Code(event.Namespace))
+	if event.Severity != "" {
+		fmt.Fprintf(&b, "Severity: `%s`\n", escapeMarkdownV2Code(event.Severity))
+	}
+	if event.Actor != "" {
+		fmt.Fprintf(&b, "Actor: `%s`\n", escapeMarkdownV2Code(event.Actor))
+	} else if event.Author != "" {
+		fmt.Fprintf(&b, "Author: `%s`\n", escapeMarkdownV2Code(event.Author))
+	}
+
+	if event.Diff != "" {
+		diff := event.Diff
+		if len(diff) > telegramMaxDiff {
+			diff = truncateUTF8(diff, telegramMaxDiff) + "\n...(truncated)"
+		}
+		fmt.Fprintf(&b, "```\n%s\n```", escapeMarkdownV2Code(diff))
+	}
+
+	return b.String()
+}
+
+// sendMessage posts a message to the configured chat, waiting for the rate limiter first
+func (t *TelegramNotifier) sendMessage(ctx context.Context, text string) error {
+	select {
+	case <-t.limiter.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	payload, err := json.Marshal(telegramMessage{
+		ChatID:    t.chatID,
+		Text:      text,
+		ParseMode: "MarkdownV2",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram message: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, t.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The error includes the URL, which contains the bot token
+		return fmt.Errorf("failed to send telegram message: %w", redactToken(err, t.token))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("telegram returned non-200 status code: %d %s", resp.StatusCode, result.Description)
+	}
+
+	return nil
+}
+
+// TestConnection sends a startup message to the configured chat
+func (t *TelegramNotifier) TestConnection() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.enabled {
+		return fmt.Errorf("telegram notifier is not enabled")
+	}
+
+	return t.sendMessage(context.Background(), escapeMarkdownV2("🎉 K8Watch notifications are now active!"))
+}
+
+// telegramEmojiForAction returns a short prefix for the action
+func telegramEmojiForAction(action string) string {
+	switch action {
+	case "ADDED":
+		return "🟢"
+	case "MODIFIED":
+		return "🟡"
+	case "DELETED":
+		return "🔴"
+	case storage.ActionThresholdExceeded, storage.ActionMissedSchedule:
+		return "⚠️"
+	default:
+		return "⚪"
+	}
+}
+
+// escapeMarkdownV2 escapes the characters MarkdownV2 reserves outside code
+func escapeMarkdownV2(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeMarkdownV2Code escapes the characters MarkdownV2 reserves inside code and pre blocks
+func escapeMarkdownV2Code(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(s)
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// redactToken removes the bot token from err's message
+func redactToken(err error, token string) error {
+	return errors.New(strings.ReplaceAll(err.Error(), token, "<token>"))
+}
@@ -0,0 +1,98 @@
+// Package plugin lets custom code run on every stored change event, e.g. to
+// update a CMDB or trigger a pipeline, without forking k8watch.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	goplugin "plugin"
+	"sort"
+
+	"k8watch/internal/notifier"
+	"k8watch/internal/storage"
+)
+
+// Plugin processes stored change events. OnEvent runs in its own goroutine
+// with a timeout and must not modify the event.
+type Plugin interface {
+	OnEvent(ctx context.Context, event *storage.ChangeEvent) error
+	Name() string
+}
+
+// NewSymbol is the symbol a plugin .so exports to be loaded by Load:
+//
+//	func New() plugin.Plugin
+const NewSymbol = "New"
+
+// Load opens every .so file in dir, in name order, and creates a Plugin from
+// each one's New function
+func Load(dir string) ([]Plugin, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+		}
+	}
+	sort.Strings(paths)
+
+	plugins := make([]Plugin, 0, len(paths))
+	for _, path := range paths {
+		p, err := open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plugin %s: %w", filepath.Base(path), err)
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// open loads a single plugin .so
+func open(path string) (Plugin, error) {
+	so, err := goplugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := so.Lookup(NewSymbol)
+	if err != nil {
+		return nil, err
+	}
+	newPlugin, ok := sym.(func() Plugin)
+	if !ok {
+		return nil, fmt.Errorf("%s has type %T, expected func() plugin.Plugin", NewSymbol, sym)
+	}
+	p := newPlugin()
+	if p == nil {
+		return nil, errors.New(NewSymbol + " returned nil")
+	}
+	return p, nil
+}
+
+// notifierPlugin runs a notifier as a plugin
+type notifierPlugin struct {
+	notifier notifier.Notifier
+}
+
+// FromNotifier adapts a notifier such as Slack to the Plugin interface. The
+// built-in notifiers are delivered through the notification retry queue
+// instead, which logs and retries deliveries; only register a notifier as a
+// plugin if it is not passed to the watcher as well.
+func FromNotifier(n notifier.Notifier) Plugin {
+	return notifierPlugin{notifier: n}
+}
+
+func (p notifierPlugin) Name() string {
+	return p.notifier.Name()
+}
+
+func (p notifierPlugin) OnEvent(ctx context.Context, event *storage.ChangeEvent) error {
+	if !p.notifier.IsEnabled() {
+		return nil
+	}
+	return p.notifier.NotifyChange(ctx, event)
+}
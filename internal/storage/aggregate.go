@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AggregateGroupBy lists the columns GetAggregate can group by
+var AggregateGroupBy = []string{"namespace", "kind", "name"}
+
+// statsTopNamespaces is the number of namespaces in Stats.TopNamespaces
+const statsTopNamespaces = 10
+
+// AggregateCount is the number of events with one value of the grouped column
+type AggregateCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// GetAggregate counts the events matching filter in its window
+// (DefaultStatsWindow if 0) per value of groupBy, most changed first
+func (s *Storage) GetAggregate(ctx context.Context, groupBy string, filter StatsFilter) ([]AggregateCount, error) {
+	if !containsValue(AggregateGroupBy, groupBy) {
+		return nil, fmt.Errorf("unsupported aggregate group_by %q", groupBy)
+	}
+	window := filter.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+
+	since := time.Now().UTC().Add(-window)
+	scope, scopeArgs := namespaceGlobs(filter.NamespacePatterns)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+groupBy+`, COUNT(*) FROM change_events
+		WHERE timestamp >= ?`+scope+`
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`, append([]interface{}{since}, scopeArgs...)...) // groupBy is validated against AggregateGroupBy
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregate: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []AggregateCount{}
+	for rows.Next() {
+		var c AggregateCount
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregate: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read aggregate: %w", err)
+	}
+	return counts, nil
+}
+
+// GetTopChangedNamespaces returns the limit namespaces with the most events in
+// the last window (DefaultStatsWindow if 0), most changed first
+func (s *Storage) GetTopChangedNamespaces(ctx context.Context, window time.Duration, limit int) ([]NamespaceChangeCount, error) {
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+	return s.topChangedNamespaces(ctx, time.Now().UTC().Add(-window), nil, limit)
+}
+
+// topChangedNamespaces ranks the namespaces matching patterns (all if empty)
+// by their events since since
+func (s *Storage) topChangedNamespaces(ctx context.Context, since time.Time, patterns []string, limit int) ([]NamespaceChangeCount, error) {
+	scope, scopeArgs := namespaceGlobs(patterns)
+	args := slices.Concat([]interface{}{since}, scopeArgs, []interface{}{limit})
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT namespace, COUNT(*) AS count FROM change_events
+		WHERE timestamp >= ?`+scope+`
+		GROUP BY namespace
+		ORDER BY count DESC, namespace
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top namespaces: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []NamespaceChangeCount{}
+	for rows.Next() {
+		var c NamespaceChangeCount
+		if err := rows.Scan(&c.Namespace, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top namespaces: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top namespaces: %w", err)
+	}
+	return counts, nil
+}
+
+// namespaceGlobs returns an " AND (...)" condition matching the namespace
+// globs patterns, or an empty condition when there are none
+func namespaceGlobs(patterns []string) (string, []interface{}) {
+	if len(patterns) == 0 {
+		return "", nil
+	}
+	args := make([]interface{}, len(patterns))
+	for i, pattern := range patterns {
+		args[i] = pattern
+	}
+	return " AND (" + strings.TrimSuffix(strings.Repeat("namespace GLOB ? OR ", len(patterns)), " OR ") + ")", args
+}
+
+// topNamespaces ranks per-namespace counts like GetTopChangedNamespaces
+func topNamespaces(totals map[string]int64, limit int) []NamespaceChangeCount {
+	counts := make([]NamespaceChangeCount, 0, len(totals))
+	for namespace, count := range totals {
+		counts = append(counts, NamespaceChangeCount{Namespace: namespace, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Namespace < counts[j].Namespace
+	})
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
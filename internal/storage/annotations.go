@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	maxAnnotationNote   = 2000
+	maxAnnotationAuthor = 256
+)
+
+// Annotation is a note attached to an event during review. Acknowledged
+// marks the change as expected.
+type Annotation struct {
+	ID           int64     `json:"id"`
+	EventID      int64     `json:"event_id"`
+	Author       string    `json:"author,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	Acknowledged bool      `json:"acknowledged"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ValidateAnnotation trims the annotation's note and author and checks that
+// it carries a note or an acknowledgement
+func ValidateAnnotation(annotation *Annotation) error {
+	annotation.Note = strings.TrimSpace(annotation.Note)
+	annotation.Author = strings.TrimSpace(annotation.Author)
+	switch {
+	case annotation.Note == "" && !annotation.Acknowledged:
+		return fmt.Errorf("an annotation needs a note or acknowledged=true")
+	case len(annotation.Note) > maxAnnotationNote:
+		return fmt.Errorf("note is longer than %d characters", maxAnnotationNote)
+	case len(annotation.Author) > maxAnnotationAuthor:
+		return fmt.Errorf("author is longer than %d characters", maxAnnotationAuthor)
+	}
+	return nil
+}
+
+// AddAnnotation attaches an annotation to its event and sets its ID. It returns
+// ErrEventNotFound if the event does not exist.
+func (s *Storage) AddAnnotation(ctx context.Context, annotation *Annotation) error {
+	if err := ValidateAnnotation(annotation); err != nil {
+		return err
+	}
+	if annotation.CreatedAt.IsZero() {
+		annotation.CreatedAt = time.Now()
+	}
+	annotation.CreatedAt = annotation.CreatedAt.UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO event_annotations (event_id, author, note, acknowledged, created_at)
+		SELECT ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM change_events WHERE id = ?)`,
+		annotation.EventID, annotation.Author, annotation.Note, annotation.Acknowledged, annotation.CreatedAt,
+		annotation.EventID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add annotation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEventNotFound
+	}
+	id, _ := result.LastInsertId()
+
+	if err := s.reindexAnnotations(ctx, tx, annotation.EventID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	annotation.ID = id
+	return nil
+}
+
+// GetAnnotations returns the annotations of the given events, oldest first,
+// keyed by event ID
+func (s *Storage) GetAnnotations(ctx context.Context, eventIDs []int64) (map[int64][]Annotation, error) {
+	annotations := make(map[int64][]Annotation)
+	if len(eventIDs) == 0 {
+		return annotations, nil
+	}
+
+	args := make([]interface{}, len(eventIDs))
+	for i, id := range eventIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, event_id, author, note, acknowledged, created_at FROM event_annotations
+		WHERE event_id IN (?`+strings.Repeat(", ?", len(eventIDs)-1)+`)
+		ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Annotation
+		if err := rows.Scan(&a.ID, &a.EventID, &a.Author, &a.Note, &a.Acknowledged, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		a.CreatedAt = a.CreatedAt.UTC()
+		annotations[a.EventID] = append(annotations[a.EventID], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// attachAnnotations loads the annotations of events into them
+func attachAnnotations(ctx context.Context, store EventStore, events []ChangeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+	}
+	annotations, err := store.GetAnnotations(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].Annotations = annotations[events[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Archiver keeps events that CleanupOldEvents is about to delete. r yields
+// the events as NDJSON; start and end are the oldest and newest event times.
+// If Archive fails nothing is deleted.
+type Archiver interface {
+	Archive(ctx context.Context, r io.Reader, start, end time.Time) error
+}
+
+// SetArchiver makes CleanupOldEvents archive events before deleting them
+func (s *Storage) SetArchiver(archiver Archiver) {
+	s.archiver = archiver
+}
+
+// ExportRange streams the events from start (inclusive) to end (exclusive) to
+// w as NDJSON, oldest first. A zero start or end leaves that side open.
+func (s *Storage) ExportRange(ctx context.Context, start, end time.Time, w io.Writer) error {
+	where := "1 = 1"
+	var args []interface{}
+	if !start.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, start.UTC())
+	}
+	if !end.IsZero() {
+		where += " AND timestamp < ?"
+		args = append(args, end.UTC())
+	}
+	return s.exportWhere(ctx, w, where, args...)
+}
+
+// exportWhere streams the events matching a WHERE condition to w as NDJSON
+func (s *Storage) exportWhere(ctx context.Context, w io.Writer, where string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM change_events WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan events: %w", err)
+		}
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return nil
+}
+
+// archiveExpired hands the events past their kind's cutoff to the archiver,
+// if there are any
+func (s *Storage) archiveExpired(ctx context.Context, cutoffs map[string]time.Time) error {
+	if len(cutoffs) == 0 {
+		return nil
+	}
+
+	conditions := make([]string, 0, len(cutoffs))
+	args := make([]interface{}, 0, 2*len(cutoffs))
+	for kind, cutoff := range cutoffs {
+		conditions = append(conditions, "(kind = ? AND timestamp < ?)")
+		args = append(args, kind, cutoff)
+	}
+	return s.archiveWhere(ctx, strings.Join(conditions, " OR "), args...)
+}
+
+// archiveWhere hands the events matching a WHERE condition to the archiver,
+// if there are any
+func (s *Storage) archiveWhere(ctx context.Context, where string, args ...interface{}) error {
+	// strftime also normalizes rows stored with a local offset to UTC
+	var count int64
+	var first, last sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*),
+		strftime('%Y-%m-%dT%H:%M:%SZ', MIN(timestamp)), strftime('%Y-%m-%dT%H:%M:%SZ', MAX(timestamp))
+		FROM change_events WHERE `+where, args...).Scan(&count, &first, &last)
+	if err != nil {
+		return fmt.Errorf("failed to count events to archive: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+	start, err := time.Parse(time.RFC3339, first.String)
+	if err != nil {
+		return fmt.Errorf("failed to parse oldest event time %q: %w", first.String, err)
+	}
+	end, err := time.Parse(time.RFC3339, last.String)
+	if err != nil {
+		return fmt.Errorf("failed to parse newest event time %q: %w", last.String, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.exportWhere(ctx, pw, where, args...))
+	}()
+	defer pr.Close()
+
+	if err := s.archiver.Archive(ctx, pr, start, end); err != nil {
+		return fmt.Errorf("failed to archive %d events: %w", count, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Changeset groups the events that share a changeset ID: a Deployment change
+// and the ReplicaSet events of the rollout it started
+type Changeset struct {
+	ID        string        `json:"changeset_id"`
+	Namespace string        `json:"namespace"`
+	StartedAt time.Time     `json:"started_at"`
+	Events    []ChangeEvent `json:"events"`
+}
+
+// GetChangesets returns the newest changesets, optionally only those of
+// namespace. Events within a changeset are ordered oldest first. A limit of 0
+// returns all of them.
+func (s *Storage) GetChangesets(ctx context.Context, namespace string, limit int) ([]Changeset, error) {
+	query := `
+		SELECT changeset_id
+		FROM change_events
+		WHERE changeset_id IS NOT NULL AND changeset_id != ''`
+	args := []interface{}{}
+	if namespace != "" {
+		query += " AND namespace = ?"
+		args = append(args, namespace)
+	}
+	query += " GROUP BY changeset_id ORDER BY MIN(timestamp) DESC, changeset_id"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changesets: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan changesets: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changesets: %w", err)
+	}
+	if len(ids) == 0 {
+		return []Changeset{}, nil
+	}
+
+	args = make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT `+eventColumns+`
+		FROM change_events
+		WHERE changeset_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)
+		ORDER BY timestamp, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changeset events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []ChangeEvent{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan changeset events: %w", err)
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changeset events: %w", err)
+	}
+	return groupChangesets(ids, events), nil
+}
+
+// groupChangesets groups events, ordered oldest first, into the changesets
+// with the given IDs, keeping the order of ids
+func groupChangesets(ids []string, events []ChangeEvent) []Changeset {
+	index := make(map[string]int, len(ids))
+	changesets := make([]Changeset, len(ids))
+	for i, id := range ids {
+		index[id] = i
+		changesets[i] = Changeset{ID: id, Events: []ChangeEvent{}}
+	}
+	for _, event := range events {
+		i, ok := index[event.ChangesetID]
+		if !ok {
+			continue
+		}
+		cs := &changesets[i]
+		if len(cs.Events) == 0 {
+			cs.Namespace = event.Namespace
+			cs.StartedAt = event.Timestamp
+		}
+		cs.Events = append(cs.Events, event)
+	}
+	return changesets
+}
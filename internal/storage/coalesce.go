@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type queryExecer interface {
+	execer
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SetCoalesceWindow merges repeats of an event into the stored one instead of
+// inserting a new row. An event repeats the latest stored event of the same
+// resource incarnation (UID) when action and diff are identical and that
+// event was last seen within window; its repeat_count and last_seen are
+// bumped instead. 0 disables coalescing.
+func (s *Storage) SetCoalesceWindow(window time.Duration) {
+	s.coalesceWindow.Store(int64(window))
+}
+
+// coalesce merges the event into a matching stored event and returns that
+// event's ID, or 0 if there is none and the event must be inserted
+func (s *Storage) coalesce(ctx context.Context, db queryExecer, event *ChangeEvent) (int64, error) {
+	window := time.Duration(s.coalesceWindow.Load())
+	if window <= 0 {
+		return 0, nil
+	}
+
+	diff, diffCompressed, _ := storedDiff(event.Diff)
+	var id int64
+	err := db.QueryRowContext(ctx, `
+		SELECT id FROM change_events
+		WHERE namespace = ? AND kind = ? AND name = ? AND COALESCE(uid, '') = ?
+			AND action = ? AND diff = ? AND diff_compressed IS ? AND COALESCE(last_seen, timestamp) >= ?
+		ORDER BY timestamp DESC, id DESC LIMIT 1`,
+		event.Namespace, event.Kind, event.Name, event.UID,
+		event.Action, diff, diffCompressed, event.Timestamp.Add(-window),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up repeated event: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		`UPDATE change_events SET repeat_count = repeat_count + 1, last_seen = ? WHERE id = ?`,
+		event.Timestamp, id,
+	); err != nil {
+		return 0, fmt.Errorf("failed to update repeated event: %w", err)
+	}
+	return id, nil
+}
+
+// repeats reports whether b repeats a under the coalescing rules
+func repeats(a, b *ChangeEvent) bool {
+	return a.Namespace == b.Namespace && a.Kind == b.Kind && a.Name == b.Name &&
+		a.UID == b.UID && a.Action == b.Action && a.Diff == b.Diff
+}
+
+// lastActivity is when the event or its last coalesced repeat happened
+func (e *ChangeEvent) lastActivity() time.Time {
+	if e.LastSeen != nil {
+		return *e.LastSeen
+	}
+	return e.Timestamp
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const (
+	// compressDiffThreshold is the diff size in bytes above which diffs are
+	// stored gzip-compressed
+	compressDiffThreshold = 4 << 10
+	// compressBatchSize is the number of stored diffs compressed per transaction
+	// by compressStoredDiffs
+	compressBatchSize = 100
+)
+
+// Large diffs are stored gzip-compressed in diff_compressed, with an empty
+// diff column and their uncompressed size in diff_size. scanEvent restores
+// them, so readers always see the plain diff.
+
+// storedDiff returns the diff, diff_compressed and diff_size column values for a diff
+func storedDiff(diff string) (string, []byte, interface{}) {
+	if len(diff) <= compressDiffThreshold {
+		return diff, nil, nil
+	}
+	return "", compressDiff(diff), len(diff)
+}
+
+func compressDiff(diff string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(diff)) // writes to a bytes.Buffer do not fail
+	zw.Close()
+	return buf.Bytes()
+}
+
+func decompressDiff(compressed []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress diff: %w", err)
+	}
+	diff, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress diff: %w", err)
+	}
+	return string(diff), nil
+}
+
+// compressStoredDiffs compresses the large diffs of events stored before
+// compression existed. It runs in the background at startup, one batch per
+// transaction, until none are left or ctx is cancelled.
+func (s *Storage) compressStoredDiffs(ctx context.Context) {
+	defer close(s.backgroundDone)
+
+	var rows, saved int64
+	for ctx.Err() == nil {
+		n, batchSaved, err := s.compressDiffBatch(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to compress stored diffs", slog.Any("error", err))
+			}
+			break
+		}
+		rows += n
+		saved += batchSaved
+		if n < compressBatchSize {
+			break
+		}
+	}
+	if rows > 0 {
+		s.logger.Info("Compressed stored diffs", slog.Int64("events", rows), slog.Int64("bytes_saved", saved))
+	}
+}
+
+// compressDiffBatch compresses up to compressBatchSize stored diffs and returns
+// how many it compressed and the bytes saved
+func (s *Storage) compressDiffBatch(ctx context.Context) (int64, int64, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, diff FROM change_events
+		WHERE diff_compressed IS NULL AND length(diff) > ?
+		ORDER BY id LIMIT ?`, compressDiffThreshold, compressBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query stored diffs: %w", err)
+	}
+	type storedRow struct {
+		id   int64
+		diff string
+	}
+	var batch []storedRow
+	for rows.Next() {
+		var row storedRow
+		if err := rows.Scan(&row.id, &row.diff); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan stored diff: %w", err)
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read stored diffs: %w", err)
+	}
+
+	var saved int64
+	for _, row := range batch {
+		compressed := compressDiff(row.diff)
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE change_events SET diff = '', diff_compressed = ?, diff_size = ? WHERE id = ?`,
+			compressed, len(row.diff), row.id,
+		); err != nil {
+			return 0, 0, fmt.Errorf("failed to store compressed diff: %w", err)
+		}
+		saved += int64(len(row.diff) - len(compressed))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit compressed diffs: %w", err)
+	}
+	return int64(len(batch)), saved, nil
+}
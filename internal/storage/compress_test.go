@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// largeDiff returns a diff over compressDiffThreshold with token deep inside
+func largeDiff(token string) string {
+	line := "Data key settings.yaml changed\n"
+	filler := strings.Repeat(line, compressDiffThreshold/len(line)+1)
+	return filler + token + "\n" + filler
+}
+
+func TestSearchCompressedDiffs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	now := time.Now().UTC()
+
+	saveEvents(t, s,
+		&ChangeEvent{Timestamp: now.Add(-3 * time.Hour), Namespace: "shop", Kind: "ConfigMap", Name: "old", Action: "MODIFIED", Diff: "small"},
+		&ChangeEvent{Timestamp: now.Add(-2 * time.Hour), Namespace: "shop", Kind: "ConfigMap", Name: "large", Action: "MODIFIED", Diff: largeDiff("feature-flag-7f3a")},
+		&ChangeEvent{Timestamp: now.Add(-time.Hour), Namespace: "shop", Kind: "ConfigMap", Name: "plain", Action: "MODIFIED", Diff: "feature-flag-7f3a enabled"},
+		&ChangeEvent{Timestamp: now, Namespace: "shop", Kind: "ConfigMap", Name: "feature-flag-7f3a", Action: "ADDED"},
+	)
+
+	// A diff stored before compression existed, compressed by the background job
+	if _, err := s.db.ExecContext(ctx, `UPDATE change_events SET diff = ? WHERE name = 'old'`, largeDiff("feature-flag-7f3a")); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err := s.compressDiffBatch(ctx); err != nil || n != 1 {
+		t.Fatalf("compressDiffBatch = %d, %v; want 1 compressed diff", n, err)
+	}
+	var compressed int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM change_events WHERE diff_compressed IS NOT NULL AND diff = ''`).Scan(&compressed); err != nil {
+		t.Fatal(err)
+	}
+	if compressed != 2 {
+		t.Fatalf("%d diffs stored compressed, want 2", compressed)
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		filter Filter
+		want   []string
+	}{
+		{name: "ranked with plain events", text: "FEATURE-flag-7f3a", filter: Filter{Limit: 10}, want: []string{"feature-flag-7f3a", "plain", "large", "old"}},
+		{name: "limit", text: "feature-flag-7f3a", filter: Filter{Limit: 2}, want: []string{"feature-flag-7f3a", "plain"}},
+		{name: "offset", text: "feature-flag-7f3a", filter: Filter{Limit: 2, Offset: 2}, want: []string{"large", "old"}},
+		{name: "offset past the end", text: "feature-flag-7f3a", filter: Filter{Limit: 2, Offset: 4}},
+		{name: "filtered", text: "feature-flag-7f3a", filter: Filter{Name: "old", Limit: 10}, want: []string{"old"}},
+		{name: "no match", text: "missing-token", filter: Filter{Limit: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := s.SearchEvents(ctx, tt.text, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventNames(events); !slices.Equal(got, tt.want) {
+				t.Errorf("SearchEvents(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			for _, event := range events {
+				if (event.Name == "large" || event.Name == "old") && !strings.Contains(event.Diff, "feature-flag-7f3a") {
+					t.Errorf("event %s returned without its decompressed diff", event.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is a position in the event list, which is ordered by timestamp and ID
+// (newest first). Unlike offsets, cursors stay valid while new events arrive.
+type Cursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// CursorFor returns the cursor positioned at event
+func CursorFor(event *ChangeEvent) Cursor {
+	return Cursor{Timestamp: event.Timestamp, ID: event.ID}
+}
+
+// String encodes the cursor as an opaque URL-safe token
+func (c Cursor) String() string {
+	raw := c.Timestamp.Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token produced by Cursor.String
+func ParseCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	c := &Cursor{}
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return nil, fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+	c.Timestamp = c.Timestamp.UTC()
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid cursor id: %w", err)
+	}
+	return c, nil
+}
+
+// isOlder reports whether event is older than the cursor position
+func (c *Cursor) isOlder(event *ChangeEvent) bool {
+	if event.Timestamp.Equal(c.Timestamp) {
+		return event.ID < c.ID
+	}
+	return event.Timestamp.Before(c.Timestamp)
+}
+
+// isNewer reports whether event is newer than the cursor position
+func (c *Cursor) isNewer(event *ChangeEvent) bool {
+	if event.Timestamp.Equal(c.Timestamp) {
+		return event.ID > c.ID
+	}
+	return event.Timestamp.After(c.Timestamp)
+}
+
+// cursorCondition returns the SQL condition for events older (op "<") or
+// newer (op ">") than c. The stored timestamp of the cursor's event is used
+// when it still exists, so the comparison matches the ORDER BY exactly.
+func cursorCondition(c *Cursor, op string) (string, []interface{}) {
+	return ` AND (timestamp, id) ` + op + ` (COALESCE((SELECT timestamp FROM change_events WHERE id = ?), ?), ?)`,
+		[]interface{}{c.ID, c.Timestamp.UTC(), c.ID}
+}
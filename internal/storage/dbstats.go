@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const (
+	VacuumOff         = "off"
+	VacuumIncremental = "incremental"
+	VacuumFull        = "full"
+)
+
+// VacuumModes are the accepted values for SetVacuum
+var VacuumModes = []string{VacuumOff, VacuumIncremental, VacuumFull}
+
+// incrementalVacuumPages is how many free pages one incremental vacuum step
+// releases. Writes wait between steps only, not for the whole vacuum.
+const incrementalVacuumPages = 1000
+
+// autoVacuumModes maps PRAGMA auto_vacuum values to their names
+var autoVacuumModes = map[int]string{0: "none", 1: "full", 2: "incremental"}
+
+// DBStats describes the size of the database
+type DBStats struct {
+	Path          string           `json:"path,omitempty"`
+	FileSizeBytes int64            `json:"file_size_bytes"`
+	WALSizeBytes  int64            `json:"wal_size_bytes"`
+	PageSize      int64            `json:"page_size"`
+	PageCount     int64            `json:"page_count"`
+	FreelistPages int64            `json:"freelist_pages"` // pages freed by deletes but still part of the file
+	AutoVacuum    string           `json:"auto_vacuum,omitempty"`
+	TotalRows     int64            `json:"total_rows"`
+	RowsByKind    map[string]int64 `json:"rows_by_kind"`
+
+	CompressedDiffs int64 `json:"compressed_diffs"` // events whose diff is stored gzip-compressed
+	DiffBytesSaved  int64 `json:"diff_bytes_saved"` // bytes saved by compressing them
+}
+
+// DBStats returns the database file size, page usage and event rows per kind
+func (s *Storage) DBStats(ctx context.Context) (*DBStats, error) {
+	stats := &DBStats{RowsByKind: make(map[string]int64)}
+
+	for _, pragma := range []struct {
+		name  string
+		value *int64
+	}{
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"freelist_count", &stats.FreelistPages},
+	} {
+		if err := s.db.QueryRowContext(ctx, "PRAGMA "+pragma.name).Scan(pragma.value); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", pragma.name, err)
+		}
+	}
+	var autoVacuum int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return nil, fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	stats.AutoVacuum = autoVacuumModes[autoVacuum]
+
+	var seq int
+	var name string
+	if err := s.db.QueryRowContext(ctx, "PRAGMA database_list").Scan(&seq, &name, &stats.Path); err != nil {
+		return nil, fmt.Errorf("failed to read database path: %w", err)
+	}
+	// In-memory databases have no file
+	if stats.Path != "" {
+		if info, err := os.Stat(stats.Path); err == nil {
+			stats.FileSizeBytes = info.Size()
+		}
+		if info, err := os.Stat(stats.Path + "-wal"); err == nil {
+			stats.WALSizeBytes = info.Size()
+		}
+	}
+
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(diff_size - length(diff_compressed)), 0)
+		FROM change_events WHERE diff_compressed IS NOT NULL`,
+	).Scan(&stats.CompressedDiffs, &stats.DiffBytesSaved); err != nil {
+		return nil, fmt.Errorf("failed to sum compressed diffs: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT kind, COUNT(*) FROM change_events GROUP BY kind")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events per kind: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var count int64
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan events per kind: %w", err)
+		}
+		stats.RowsByKind[kind] = count
+		stats.TotalRows += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events per kind: %w", err)
+	}
+	return stats, nil
+}
+
+// SetVacuum makes cleanups that delete at least threshold events vacuum the
+// database afterwards, so the file shrinks. Incremental vacuum needs
+// auto_vacuum=INCREMENTAL; switching an existing database to it rewrites the
+// file once with a full VACUUM.
+func (s *Storage) SetVacuum(ctx context.Context, mode string, threshold int64) error {
+	switch mode {
+	case VacuumOff, VacuumFull:
+	case VacuumIncremental:
+		if err := s.enableIncrementalVacuum(ctx); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown vacuum mode %q", mode)
+	}
+
+	s.vacuumMu.Lock()
+	defer s.vacuumMu.Unlock()
+	s.vacuumMode = mode
+	s.vacuumThreshold = threshold
+	return nil
+}
+
+// enableIncrementalVacuum switches the database to auto_vacuum=INCREMENTAL.
+// The pragma only applies to the connection it runs on and takes effect with
+// the VACUUM, so both run on one connection while holding the writer lock.
+func (s *Storage) enableIncrementalVacuum(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	var autoVacuum int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if autoVacuumModes[autoVacuum] == VacuumIncremental {
+		return nil
+	}
+
+	s.logger.Info("Enabling incremental auto-vacuum, rewriting the database once")
+	if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to enable incremental auto-vacuum: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if autoVacuumModes[autoVacuum] != VacuumIncremental {
+		return fmt.Errorf("auto_vacuum is %s after enabling incremental auto-vacuum", autoVacuumModes[autoVacuum])
+	}
+	return nil
+}
+
+// vacuumAfter vacuums the database if a cleanup deleted enough events
+func (s *Storage) vacuumAfter(ctx context.Context, deleted int64) {
+	s.vacuumMu.Lock()
+	mode, threshold := s.vacuumMode, s.vacuumThreshold
+	s.vacuumMu.Unlock()
+
+	if mode == "" || mode == VacuumOff || deleted < threshold {
+		return
+	}
+
+	var err error
+	if mode == VacuumFull {
+		err = s.vacuumFull(ctx)
+	} else {
+		err = s.vacuumIncremental(ctx)
+	}
+	if err == nil {
+		err = s.truncateWAL(ctx)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to vacuum database", slog.String("mode", mode), slog.Any("error", err))
+		return
+	}
+	s.logger.Info("Vacuumed database", slog.String("mode", mode), slog.Int64("deleted", deleted))
+}
+
+// vacuumFull rewrites the whole database. The background writer waits until
+// it is done, so queued events are delayed rather than failing on the lock.
+func (s *Storage) vacuumFull(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	return nil
+}
+
+// truncateWAL checkpoints the write-ahead log and truncates it. A vacuum
+// writes the moved pages to the WAL, which otherwise keeps its size.
+func (s *Storage) truncateWAL(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
+
+// vacuumIncremental releases free pages in small steps, letting the
+// background writer in between
+func (s *Storage) vacuumIncremental(ctx context.Context) error {
+	previous := int64(-1)
+	for {
+		var free int64
+		if err := s.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+			return fmt.Errorf("failed to read freelist_count: %w", err)
+		}
+		// Stop once nothing is left or a step made no progress
+		if free == 0 || free == previous {
+			return nil
+		}
+		previous = free
+		if err := s.incrementalVacuumStep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Storage) incrementalVacuumStep(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// incremental_vacuum returns no rows but only runs when stepped through
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", incrementalVacuumPages))
+	if err != nil {
+		return fmt.Errorf("failed to vacuum incrementally: %w", err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	return rows.Err()
+}
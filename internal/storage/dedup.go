@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrDuplicateEvent is returned by SaveEventSync when the event was already
+// stored, e.g. when a restarted watcher lists objects it has seen before. The
+// event's ID is set to the stored one.
+var ErrDuplicateEvent = errors.New("event already stored")
+
+var deduplicatedEvents = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kubewatcher_events_deduplicated_total",
+	Help: "Number of events that were not stored because an identical event was stored within the dedup window.",
+})
+
+// SetDedupWindow skips events whose hash (see EventHash) matches an event
+// stored within window. Only events with a resourceVersion are deduplicated;
+// without one the hash does not identify a single change. 0 disables it.
+func (s *Storage) SetDedupWindow(window time.Duration) {
+	s.dedupWindow.Store(int64(window))
+}
+
+// EventHash is a stable hash of the fields that identify a change: namespace,
+// kind, name, action, diff and resourceVersion
+func EventHash(event *ChangeEvent) string {
+	h := sha256.New()
+	for _, field := range []string{event.Namespace, event.Kind, event.Name, event.Action, event.Diff, event.ResourceVersion} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// duplicateOf returns the ID of a stored event with the same hash within the
+// dedup window, or 0 if the event must be stored
+func (s *Storage) duplicateOf(ctx context.Context, db queryExecer, event *ChangeEvent) (int64, error) {
+	window := time.Duration(s.dedupWindow.Load())
+	if window <= 0 || event.ResourceVersion == "" {
+		return 0, nil
+	}
+
+	var id int64
+	err := db.QueryRowContext(ctx, `
+		SELECT id FROM change_events
+		WHERE event_hash = ? AND timestamp >= ?
+		ORDER BY id DESC LIMIT 1`,
+		EventHash(event), event.Timestamp.Add(-window),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up duplicate event: %w", err)
+	}
+
+	s.deduplicated.Add(1)
+	deduplicatedEvents.Inc()
+	return id, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour)
+
+	// A watcher restart relists objects it has already recorded: the events
+	// for resourceVersions 101 and 102 arrive a second time
+	type saved struct {
+		name, resourceVersion, diff string
+		at                          time.Duration
+		duplicate                   bool
+	}
+	sequence := []saved{
+		{"web", "101", "Image updated: nginx:1.26 → nginx:1.27", 0, false},
+		{"web", "102", "Scaled up: 1 → 3 replicas", time.Minute, false},
+		{"api", "201", "Scaled up: 2 → 4 replicas", 2 * time.Minute, false},
+		{"web", "101", "Image updated: nginx:1.26 → nginx:1.27", 10 * time.Minute, true},
+		{"web", "102", "Scaled up: 1 → 3 replicas", 10 * time.Minute, true},
+		{"web", "103", "Scaled up: 1 → 3 replicas", 11 * time.Minute, false}, // same change, new version
+		{"api", "", "Scaled up: 2 → 4 replicas", 12 * time.Minute, false},    // no resourceVersion
+		{"api", "", "Scaled up: 2 → 4 replicas", 13 * time.Minute, false},
+	}
+
+	s := newTestStorage(t)
+	s.SetDedupWindow(time.Hour)
+
+	ids := map[string]int64{}
+	for i, step := range sequence {
+		event := &ChangeEvent{
+			Timestamp:       base.Add(step.at),
+			Namespace:       "default",
+			Kind:            "Deployment",
+			Name:            step.name,
+			Action:          "MODIFIED",
+			Diff:            step.diff,
+			ResourceVersion: step.resourceVersion,
+		}
+		err := s.SaveEventSync(ctx, event)
+		if step.duplicate {
+			if !errors.Is(err, ErrDuplicateEvent) {
+				t.Fatalf("event %d: SaveEventSync = %v, want ErrDuplicateEvent", i, err)
+			}
+			if want := ids[step.name+step.resourceVersion]; event.ID != want {
+				t.Errorf("event %d: ID = %d, want the stored event's ID %d", i, event.ID, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("event %d: SaveEventSync: %v", i, err)
+		}
+		ids[step.name+step.resourceVersion] = event.ID
+	}
+
+	count, err := s.GetTotalCount(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Errorf("stored %d events, want 6", count)
+	}
+	stats, err := s.GetStats(ctx, StatsFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DeduplicatedEvents != 2 {
+		t.Errorf("DeduplicatedEvents = %d, want 2", stats.DeduplicatedEvents)
+	}
+}
+
+func TestDedupQueued(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	s.SetDedupWindow(time.Hour)
+
+	// Duplicates within one batch are dropped as well as ones already stored
+	now := time.Now().UTC()
+	for i := range 5 {
+		event := &ChangeEvent{
+			Timestamp:       now.Add(time.Duration(i) * time.Second),
+			Namespace:       "default",
+			Kind:            "ConfigMap",
+			Name:            "settings",
+			Action:          "MODIFIED",
+			Diff:            "Keys modified: [log_level]",
+			ResourceVersion: []string{"7", "8", "7", "8", "9"}[i],
+		}
+		if err := s.SaveEvent(ctx, event); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.GetEvents(ctx, Filter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, event := range events {
+		versions = append(versions, event.ResourceVersion)
+	}
+	if !slices.Equal(versions, []string{"9", "8", "7"}) {
+		t.Errorf("stored resourceVersions %v, want 9, 8 and 7", versions)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	event := func(at time.Time) *ChangeEvent {
+		return &ChangeEvent{Timestamp: at, Namespace: "default", Kind: "Secret", Name: "tls", Action: "MODIFIED", ResourceVersion: "42"}
+	}
+
+	tests := []struct {
+		name      string
+		window    time.Duration
+		gap       time.Duration
+		duplicate bool
+	}{
+		{name: "within the window", window: time.Hour, gap: 30 * time.Minute, duplicate: true},
+		{name: "outside the window", window: time.Hour, gap: 2 * time.Hour},
+		{name: "disabled", window: 0, gap: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			s.SetDedupWindow(tt.window)
+			if err := s.SaveEventSync(ctx, event(now.Add(-tt.gap))); err != nil {
+				t.Fatal(err)
+			}
+			err := s.SaveEventSync(ctx, event(now))
+			if errors.Is(err, ErrDuplicateEvent) != tt.duplicate {
+				t.Errorf("SaveEventSync = %v, want duplicate %v", err, tt.duplicate)
+			}
+		})
+	}
+}
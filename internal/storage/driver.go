@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQLite drivers selectable with NewStorage
+const (
+	// DriverCgo is github.com/mattn/go-sqlite3 and requires cgo
+	DriverCgo = "sqlite3"
+	// DriverPureGo is modernc.org/sqlite, compiled in with -tags purego or CGO_ENABLED=0
+	DriverPureGo = "sqlite"
+)
+
+// AvailableDrivers returns the SQLite drivers compiled into this binary
+func AvailableDrivers() []string {
+	var drivers []string
+	for _, name := range []string{DriverCgo, DriverPureGo} {
+		if driverRegistered(name) {
+			drivers = append(drivers, name)
+		}
+	}
+	return drivers
+}
+
+// resolveDriver picks the driver to use and builds its DSN.
+// An empty driver selects the first available one.
+func resolveDriver(driver, dbPath string) (string, string, error) {
+	available := AvailableDrivers()
+	if driver == "" {
+		if len(available) == 0 {
+			return "", "", fmt.Errorf("no SQLite driver compiled in")
+		}
+		driver = available[0]
+	}
+
+	switch driver {
+	case DriverCgo, DriverPureGo:
+	default:
+		return "", "", fmt.Errorf("unknown database driver %q (expected %s or %s)", driver, DriverCgo, DriverPureGo)
+	}
+	if !driverRegistered(driver) {
+		return "", "", fmt.Errorf("database driver %q is not compiled in (available: %s)", driver, strings.Join(available, ", "))
+	}
+
+	return driver, buildDSN(driver, dbPath), nil
+}
+
+// buildDSN appends the connection settings to the database path: WAL journaling so
+// API reads don't block watcher writes, a 5s busy timeout instead of failing with
+// "database is locked", NORMAL sync (safe with WAL) and foreign keys. The two
+// drivers spell these options differently.
+func buildDSN(driver, dbPath string) string {
+	var params []string
+	switch driver {
+	case DriverCgo:
+		params = []string{
+			"_journal_mode=WAL",
+			"_busy_timeout=5000",
+			"_synchronous=NORMAL",
+			"_foreign_keys=on",
+		}
+	case DriverPureGo:
+		params = []string{
+			"_pragma=journal_mode(WAL)",
+			"_pragma=busy_timeout(5000)",
+			"_pragma=synchronous(NORMAL)",
+			"_pragma=foreign_keys(1)",
+			// Store timestamps in the same text format as go-sqlite3 so both
+			// drivers can read each other's databases and compare time ranges
+			"_time_format=sqlite",
+		}
+	}
+
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + strings.Join(params, "&")
+}
+
+func driverRegistered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+//go:build cgo
+
+package storage
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
@@ -0,0 +1,7 @@
+//go:build purego || !cgo
+
+package storage
+
+import (
+	_ "modernc.org/sqlite"
+)
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJournalMode(t *testing.T) {
+	s := newTestStorage(t)
+
+	var mode string
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(mode, "wal") {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+	var timeout int
+	if err := s.db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != 5000 {
+		t.Errorf("busy_timeout = %d, want 5000", timeout)
+	}
+}
+
+// TestConcurrentReadWrite has writers and readers share the connection pool
+// the way the watchers and the API server do. None of them may see "database
+// is locked".
+func TestConcurrentReadWrite(t *testing.T) {
+	const (
+		writers         = 8
+		eventsPerWriter = 50
+		readers         = 8
+	)
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	var (
+		writing, reading sync.WaitGroup
+		done             = make(chan struct{})
+		reads            atomic.Int64
+		errs             = make(chan error, writers+readers)
+		base             = time.Now().UTC().Add(-time.Hour)
+	)
+
+	for w := range writers {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			for i := range eventsPerWriter {
+				event := &ChangeEvent{
+					Timestamp: base.Add(time.Duration(w*eventsPerWriter+i) * time.Millisecond),
+					Namespace: fmt.Sprintf("team-%d", w),
+					Kind:      "Deployment",
+					Name:      fmt.Sprintf("app-%d", i),
+					Action:    "MODIFIED",
+					Diff:      fmt.Sprintf("Scaled up: %d → %d replicas", i, i+1),
+				}
+				var err error
+				if i%2 == 0 {
+					err = s.SaveEventSync(ctx, event)
+					if err == nil && i%10 == 0 {
+						err = s.SetEventTags(ctx, event.ID, []string{fmt.Sprintf("writer-%d", w)})
+					}
+					if err == nil && i%10 == 0 {
+						err = s.AddAnnotation(ctx, &Annotation{EventID: event.ID, Note: "checked"})
+					}
+				} else {
+					err = s.SaveEvent(ctx, event) // through the write queue
+				}
+				if err != nil {
+					errs <- fmt.Errorf("writer %d: %w", w, err)
+					return
+				}
+			}
+		}()
+	}
+
+	for r := range readers {
+		reading.Add(1)
+		go func() {
+			defer reading.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var err error
+				switch r % 4 {
+				case 0:
+					_, err = s.GetEvents(ctx, Filter{Namespace: fmt.Sprintf("team-%d", r), Limit: 20})
+				case 1:
+					_, err = s.GetStats(ctx, StatsFilter{})
+				case 2:
+					_, err = s.GetTotalCount(ctx, Filter{Kind: "Deployment"})
+				case 3:
+					_, err = s.GetTags(ctx)
+				}
+				if err != nil {
+					errs <- fmt.Errorf("reader %d: %w", r, err)
+					return
+				}
+				reads.Add(1)
+			}
+		}()
+	}
+
+	writing.Wait()
+	close(done)
+	reading.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	count, err := s.GetTotalCount(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != writers*eventsPerWriter {
+		t.Errorf("stored %d events, want %d", count, writers*eventsPerWriter)
+	}
+	if reads.Load() == 0 {
+		t.Error("no reads completed while writing")
+	}
+}
+
+// TestEnableIncrementalVacuumUnderLoad switches auto_vacuum while events are
+// being saved. The switch rewrites the file; it must take effect and leave the
+// database in WAL mode with every event stored.
+func TestEnableIncrementalVacuumUnderLoad(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	seedEvents(t, s, 200)
+
+	var (
+		writing sync.WaitGroup
+		done    = make(chan struct{})
+		saved   atomic.Int64
+		errs    = make(chan error, 4)
+		base    = time.Now().UTC().Add(-time.Hour)
+	)
+	for w := range 4 {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				event := &ChangeEvent{
+					Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+					Namespace: fmt.Sprintf("team-%d", w),
+					Kind:      "Deployment",
+					Name:      fmt.Sprintf("app-%d", i),
+					Action:    "MODIFIED",
+				}
+				if err := s.SaveEvent(ctx, event); err != nil {
+					errs <- fmt.Errorf("writer %d: %w", w, err)
+					return
+				}
+				saved.Add(1)
+			}
+		}()
+	}
+
+	// Let the writers get going before the switch
+	for saved.Load() < 100 {
+		time.Sleep(time.Millisecond)
+	}
+	err := s.SetVacuum(ctx, VacuumIncremental, 1)
+	close(done)
+	writing.Wait()
+	close(errs)
+	if err != nil {
+		t.Fatalf("SetVacuum: %v", err)
+	}
+	for err := range errs {
+		t.Error(err)
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var autoVacuum int
+	if err := s.db.QueryRow("PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		t.Fatal(err)
+	}
+	if autoVacuumModes[autoVacuum] != VacuumIncremental {
+		t.Errorf("auto_vacuum = %s, want incremental", autoVacuumModes[autoVacuum])
+	}
+	var mode string
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(mode, "wal") {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+	count, err := s.GetTotalCount(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 200 + saved.Load(); count != want {
+		t.Errorf("stored %d events, want %d", count, want)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// maxEventsLowWater is the share of the cap left after an eviction, so the
+// next batch doesn't trigger another one straight away
+const maxEventsLowWater = 0.9
+
+// SetMaxEvents caps the number of stored events; 0 disables the cap. Once
+// the cap is exceeded the oldest events are evicted (and archived, with an
+// archiver set) down to 90% of it.
+func (s *Storage) SetMaxEvents(ctx context.Context, maxEvents int) error {
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM change_events").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count events: %w", err)
+	}
+	s.rowCount.Store(count)
+	s.maxEvents.Store(int64(maxEvents))
+	return nil
+}
+
+// evictIfFull evicts the oldest events when the row count is over the cap.
+// It runs on the writer goroutine after every flush.
+func (s *Storage) evictIfFull(ctx context.Context) {
+	maxEvents := s.maxEvents.Load()
+	if maxEvents <= 0 || s.rowCount.Load() <= maxEvents {
+		return
+	}
+
+	evicted, err := s.evictOldest(ctx, maxEvents)
+	if err != nil {
+		s.logger.Warn("Failed to evict events over the cap", slog.Int64("max_events", maxEvents), slog.Any("error", err))
+		return
+	}
+	if evicted > 0 {
+		s.logger.Info("Evicted oldest events over the cap", slog.Int64("evicted", evicted), slog.Int64("max_events", maxEvents))
+	}
+}
+
+// evictOldest deletes the oldest events down to the low-water mark and
+// returns how many were deleted
+func (s *Storage) evictOldest(ctx context.Context, maxEvents int64) (int64, error) {
+	// Retention cleanup may have made room since the count was last updated
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM change_events").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	s.rowCount.Store(count)
+	if count <= maxEvents {
+		return 0, nil
+	}
+
+	excess := count - int64(float64(maxEvents)*maxEventsLowWater)
+	where := "id IN (SELECT id FROM change_events ORDER BY timestamp ASC, id ASC LIMIT ?)"
+
+	if s.archiver != nil {
+		if err := s.archiveWhere(ctx, where, excess); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM change_events WHERE "+where, excess)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evict events: %w", err)
+	}
+	evicted, _ := result.RowsAffected()
+	s.rowCount.Add(-evicted)
+
+	if err := s.removeOrphans(ctx); err != nil {
+		return evicted, err
+	}
+	s.vacuumAfter(ctx, evicted)
+	return evicted, nil
+}
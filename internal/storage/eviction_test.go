@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEvictionAndCleanup runs the --max-events eviction and retention cleanup
+// at the same time over overlapping events. Whichever runs first, the newest
+// 90% of the cap must remain and the cached row count must match the table.
+func TestEvictionAndCleanup(t *testing.T) {
+	const (
+		total     = 100
+		expired   = 40 // older than the retention
+		maxEvents = 50
+		remaining = int(maxEvents * maxEventsLowWater)
+	)
+
+	for round := range 5 {
+		t.Run(fmt.Sprint(round), func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestStorage(t)
+
+			now := time.Now().UTC().Truncate(time.Second)
+			var want []string
+			for i := range total {
+				ts := now.Add(-time.Duration(total-i) * time.Hour)
+				if i < expired {
+					ts = now.AddDate(0, 0, -30).Add(time.Duration(i) * time.Minute)
+				}
+				name := fmt.Sprintf("event-%03d", i)
+				saveEvents(t, s, &ChangeEvent{Timestamp: ts, Namespace: "shop", Kind: "ConfigMap", Name: name, Action: "MODIFIED"})
+				if i >= total-remaining {
+					want = append(want, name)
+				}
+			}
+			if err := s.SetMaxEvents(ctx, maxEvents); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 2)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if _, err := s.evictOldest(ctx, maxEvents); err != nil {
+					errs <- fmt.Errorf("evictOldest: %w", err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if _, err := s.CleanupOldEvents(ctx, 7); err != nil {
+					errs <- fmt.Errorf("CleanupOldEvents: %w", err)
+				}
+			}()
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatal(err)
+			}
+
+			events, err := s.GetEvents(ctx, Filter{OrderBy: "name", Order: SortAsc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventNames(events); !slices.Equal(got, want) {
+				t.Errorf("kept %d events %v, want the newest %d %v", len(got), got, len(want), want)
+			}
+			if got := s.rowCount.Load(); got != int64(remaining) {
+				t.Errorf("cached row count = %d, want %d", got, remaining)
+			}
+		})
+	}
+}
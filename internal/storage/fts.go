@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrFullTextUnavailable is returned for full-text queries when the SQLite
+// build has no FTS5 support (e.g. go-sqlite3 built without the sqlite_fts5 tag)
+var ErrFullTextUnavailable = errors.New("full-text search is not available: SQLite was built without FTS5")
+
+// The FTS index is kept in sync by the writer rather than by triggers, so a
+// database indexed by an FTS5 build can still be written by a build without it.
+// Rows missed that way are indexed at the next FTS5-capable startup.
+
+// ftsTags and ftsNotes compute the tags and notes columns of the index from a
+// change_events row. The notes column holds the event's notes followed by the
+// notes of its annotations.
+const (
+	ftsTags  = `COALESCE((SELECT group_concat(value, ' ') FROM json_each(change_events.tags)), '')`
+	ftsNotes = `COALESCE((SELECT group_concat(json_extract(value, '$.text'), ' ') FROM json_each(change_events.notes)), '') || ' ' ||
+		COALESCE((SELECT group_concat(note, ' ') FROM event_annotations WHERE event_id = change_events.id), '')`
+)
+
+// initFullText creates the FTS5 index if the driver supports it and indexes
+// any events that are not in it yet
+func (s *Storage) initFullText() {
+	const createFTS = `CREATE VIRTUAL TABLE IF NOT EXISTS change_events_fts USING fts5(name, diff, metadata, tags, notes)`
+	_, err := s.db.Exec(createFTS)
+	if err != nil {
+		s.logger.Info("Full-text search disabled", slog.Any("reason", err))
+		return
+	}
+
+	// Indexes built before tags and notes were indexed are rebuilt from scratch
+	if _, err := s.db.Exec(`SELECT notes FROM change_events_fts LIMIT 0`); err != nil {
+		s.logger.Info("Rebuilding full-text index")
+		if _, err := s.db.Exec(`DROP TABLE change_events_fts`); err != nil {
+			s.logger.Warn("Failed to drop full-text index, search disabled", slog.Any("error", err))
+			return
+		}
+		if _, err := s.db.Exec(createFTS); err != nil {
+			s.logger.Warn("Failed to create full-text index, search disabled", slog.Any("error", err))
+			return
+		}
+	}
+
+	var indexed int64
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(rowid), 0) FROM change_events_fts`).Scan(&indexed); err != nil {
+		s.logger.Warn("Failed to read full-text index, search disabled", slog.Any("error", err))
+		return
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO change_events_fts (rowid, name, diff, metadata, tags, notes)
+		SELECT id, name, COALESCE(diff, ''), COALESCE(metadata, ''), `+ftsTags+`, `+ftsNotes+`
+		FROM change_events
+		WHERE id > ?
+	`, indexed)
+	if err == nil {
+		err = s.indexCompressedDiffs(indexed)
+	}
+	if err == nil {
+		// Annotations of indexed events may have been added by a build
+		// without FTS5
+		_, err = s.db.Exec(`
+			UPDATE change_events_fts SET notes = (
+				SELECT ` + ftsNotes + ` FROM change_events WHERE change_events.id = change_events_fts.rowid
+			) WHERE rowid IN (SELECT event_id FROM event_annotations)`)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to build full-text index, search disabled", slog.Any("error", err))
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Info("Indexed events for full-text search", slog.Int64("events", n))
+	}
+	s.ftsEnabled = true
+}
+
+// indexCompressedDiffs fills in the diffs of compressed events after id, which
+// the SQL that builds the index can only see as empty
+func (s *Storage) indexCompressedDiffs(after int64) error {
+	rows, err := s.db.Query(`SELECT id, diff_compressed FROM change_events WHERE id > ? AND diff_compressed IS NOT NULL`, after)
+	if err != nil {
+		return fmt.Errorf("failed to query compressed diffs: %w", err)
+	}
+	diffs := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var compressed []byte
+		if err := rows.Scan(&id, &compressed); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan compressed diff: %w", err)
+		}
+		if diffs[id], err = decompressDiff(compressed); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read compressed diffs: %w", err)
+	}
+
+	for id, diff := range diffs {
+		if _, err := s.db.Exec(`UPDATE change_events_fts SET diff = ? WHERE rowid = ?`, diff, id); err != nil {
+			return fmt.Errorf("failed to index compressed diff: %w", err)
+		}
+	}
+	return nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// indexEvent adds a saved event to the full-text index
+func (s *Storage) indexEvent(ctx context.Context, db execer, id int64, event *ChangeEvent) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO change_events_fts (rowid, name, diff, metadata, tags, notes) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, event.Name, event.Diff, event.Metadata, strings.Join(event.Tags, " "), notesText(event.Notes))
+	if err != nil {
+		return fmt.Errorf("failed to index event: %w", err)
+	}
+	return nil
+}
+
+// reindexAnnotations refreshes the tags and notes of an event's index entry.
+// Writers call it in the transaction that changed them.
+func (s *Storage) reindexAnnotations(ctx context.Context, db execer, id int64) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE change_events_fts SET (tags, notes) = (
+			SELECT `+ftsTags+`, `+ftsNotes+` FROM change_events WHERE id = ?
+		) WHERE rowid = ?`, id, id)
+	if err != nil {
+		return fmt.Errorf("failed to index event annotations: %w", err)
+	}
+	return nil
+}
+
+// pruneFullText drops index entries whose events were deleted
+func (s *Storage) pruneFullText(ctx context.Context) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM change_events_fts WHERE rowid NOT IN (SELECT id FROM change_events)`); err != nil {
+		return fmt.Errorf("failed to prune full-text index: %w", err)
+	}
+	return nil
+}
+
+// ftsQuery turns free text into an FTS5 query that matches all words. Each
+// word is quoted so punctuation such as "feature-flags.yaml" is matched as a
+// phrase instead of being parsed as query syntax.
+func ftsQuery(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
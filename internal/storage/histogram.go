@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Histogram bucket sizes supported by GetEventHistogram. Buckets are aligned to UTC.
+const (
+	BucketHour = time.Hour
+	BucketDay  = 24 * time.Hour
+)
+
+// HistogramGroupBy lists the columns a histogram can be split by
+var HistogramGroupBy = []string{"kind", "action"}
+
+// bucketFormats maps a bucket size to the strftime format that truncates a timestamp to it
+var bucketFormats = map[time.Duration]string{
+	BucketHour: "%Y-%m-%d %H:00:00",
+	BucketDay:  "%Y-%m-%d 00:00:00",
+}
+
+// GetEventHistogram counts the events matching filter per time bucket between
+// filter.StartTime and filter.EndTime, including empty buckets. If groupBy is
+// "kind" or "action" each bucket is also split by that column.
+func (s *Storage) GetEventHistogram(ctx context.Context, filter Filter, bucket time.Duration, groupBy string) ([]HistogramBucket, error) {
+	if err := validateHistogram(filter, bucket, groupBy); err != nil {
+		return nil, err
+	}
+	if filter.Query != "" && !s.ftsEnabled {
+		return nil, ErrFullTextUnavailable
+	}
+
+	group := "''"
+	if groupBy != "" {
+		group = groupBy // validated against HistogramGroupBy
+	}
+	where, args := buildWhere(filter)
+	query := `SELECT strftime('` + bucketFormats[bucket] + `', timestamp), ` + group + `, COUNT(*)
+		FROM change_events` + where + `
+		GROUP BY 1, 2`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event histogram: %w", err)
+	}
+	defer rows.Close()
+
+	h := newHistogram(filter.StartTime, filter.EndTime, bucket, groupBy != "")
+	for rows.Next() {
+		var start, key string
+		var count int64
+		if err := rows.Scan(&start, &key, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan event histogram: %w", err)
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse histogram bucket %q: %w", start, err)
+		}
+		h.add(t, key, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event histogram: %w", err)
+	}
+	return h.buckets, nil
+}
+
+func validateHistogram(filter Filter, bucket time.Duration, groupBy string) error {
+	if _, ok := bucketFormats[bucket]; !ok {
+		return fmt.Errorf("unsupported histogram bucket %s", bucket)
+	}
+	if groupBy != "" && !containsValue(HistogramGroupBy, groupBy) {
+		return fmt.Errorf("unsupported histogram group_by %q", groupBy)
+	}
+	if filter.StartTime.IsZero() || filter.EndTime.IsZero() || filter.EndTime.Before(filter.StartTime) {
+		return fmt.Errorf("histogram needs a start time before its end time")
+	}
+	return nil
+}
+
+// histogram holds one bucket per interval, so intervals without events are kept
+type histogram struct {
+	buckets []HistogramBucket
+	first   time.Time
+	size    time.Duration
+}
+
+func newHistogram(start, end time.Time, size time.Duration, grouped bool) *histogram {
+	h := &histogram{first: start.UTC().Truncate(size), size: size}
+	for t := h.first; !t.After(end); t = t.Add(size) {
+		b := HistogramBucket{Start: t}
+		if grouped {
+			b.Groups = map[string]int64{}
+		}
+		h.buckets = append(h.buckets, b)
+	}
+	return h
+}
+
+// add counts n events in the bucket containing t; times outside the range are ignored
+func (h *histogram) add(t time.Time, group string, n int64) {
+	i := int(t.Sub(h.first) / h.size)
+	if t.Before(h.first) || i >= len(h.buckets) {
+		return
+	}
+	h.buckets[i].Count += n
+	if h.buckets[i].Groups != nil {
+		h.buckets[i].Groups[group] += n
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// imageChangeWhere matches events of any kind that started running a new image
+const imageChangeWhere = `image_after IS NOT NULL AND image_after != ''
+	AND (image_before IS NULL OR image_before != image_after)`
+
+// imageRolloutWhere matches deployment events that started running a new image
+const imageRolloutWhere = `kind = 'Deployment' AND ` + imageChangeWhere
+
+// GetImageHistory returns the image transitions of a deployment, newest first.
+// A limit of 0 returns all of them.
+func (s *Storage) GetImageHistory(ctx context.Context, namespace, name string, limit int) ([]ImageTransition, error) {
+	query := `
+		SELECT id, timestamp, image_before, image_after
+		FROM change_events
+		WHERE namespace = ? AND name = ? AND ` + imageRolloutWhere + `
+		ORDER BY timestamp DESC, id DESC`
+	args := []interface{}{namespace, name}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []ImageTransition{}
+	for rows.Next() {
+		var t ImageTransition
+		var imageBefore sql.NullString
+		if err := rows.Scan(&t.EventID, &t.Timestamp, &imageBefore, &t.ImageAfter); err != nil {
+			return nil, fmt.Errorf("failed to scan image history: %w", err)
+		}
+		t.Timestamp = t.Timestamp.UTC()
+		t.ImageBefore = imageBefore.String
+		history = append(history, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image history: %w", err)
+	}
+	return history, nil
+}
+
+// GetImageHistoryByPrefix returns the image changes of any kind whose old or
+// new image starts with prefix (e.g. "myrepo/myapp" or "nginx:1.25"), newest
+// first. A limit of 0 returns all of them.
+func (s *Storage) GetImageHistoryByPrefix(ctx context.Context, prefix string, limit int) ([]ImageHistoryEntry, error) {
+	pattern := escapeLike(prefix) + "%"
+	query := `
+		SELECT id, namespace, kind, name, image_before, image_after, timestamp
+		FROM change_events
+		WHERE (image_before LIKE ? ESCAPE '\' OR image_after LIKE ? ESCAPE '\') AND ` + imageChangeWhere + `
+		ORDER BY timestamp DESC, id DESC`
+	args := []interface{}{pattern, pattern}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image history by prefix: %w", err)
+	}
+	defer rows.Close()
+
+	history := []ImageHistoryEntry{}
+	for rows.Next() {
+		var e ImageHistoryEntry
+		var imageBefore sql.NullString
+		if err := rows.Scan(&e.EventID, &e.Namespace, &e.Kind, &e.Resource, &imageBefore, &e.ImageAfter, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan image history by prefix: %w", err)
+		}
+		e.Timestamp = e.Timestamp.UTC()
+		e.ImageBefore = imageBefore.String
+		history = append(history, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image history by prefix: %w", err)
+	}
+	return history, nil
+}
+
+// GetImageRollouts returns the deployment events that rolled out exactly image,
+// newest first. A limit of 0 returns all of them.
+func (s *Storage) GetImageRollouts(ctx context.Context, image string, limit int) ([]ChangeEvent, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM change_events
+		WHERE image_after = ? AND ` + imageRolloutWhere + `
+		ORDER BY timestamp DESC, id DESC`
+	args := []interface{}{image}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image rollouts: %w", err)
+	}
+	defer rows.Close()
+
+	events := []ChangeEvent{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image rollouts: %w", err)
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image rollouts: %w", err)
+	}
+	return events, nil
+}
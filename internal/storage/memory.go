@@ -0,0 +1,1042 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory EventStore. It needs neither cgo nor SQLite,
+// which makes it suitable for tests and throwaway runs. Nothing is persisted.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	events    []ChangeEvent
+	snapshots map[int64]EventSnapshot
+	nextID    int64
+
+	annotations      map[int64][]Annotation // keyed by event ID
+	nextAnnotationID int64
+	notifications    []NotificationDelivery
+	nextDeliveryID   int64
+
+	retentionOverrides map[string]int // lowercased kind -> days
+	coalesceWindow     time.Duration
+	publisher          EventPublisher
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots:        make(map[int64]EventSnapshot),
+		nextID:           1,
+		annotations:      make(map[int64][]Annotation),
+		nextAnnotationID: 1,
+		nextDeliveryID:   1,
+	}
+}
+
+// SaveEvent stores a copy of the event and assigns its ID
+func (m *MemoryStore) SaveEvent(ctx context.Context, event *ChangeEvent) error {
+	event.Timestamp = event.Timestamp.UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.coalesceWindow > 0 {
+		since := event.Timestamp.Add(-m.coalesceWindow)
+		for i := len(m.events) - 1; i >= 0; i-- {
+			stored := &m.events[i]
+			if !repeats(stored, event) || stored.lastActivity().Before(since) {
+				continue
+			}
+			lastSeen := event.Timestamp
+			stored.RepeatCount++
+			stored.LastSeen = &lastSeen
+			event.ID = stored.ID
+			return nil
+		}
+	}
+
+	event.RepeatCount = 1
+	event.LastSeen = nil
+	event.ID = m.nextID
+	m.nextID++
+	m.events = append(m.events, *event)
+	if m.publisher != nil {
+		m.publisher.Publish(event)
+	}
+	return nil
+}
+
+// SetPublisher publishes newly stored events to publisher, see Storage.SetPublisher
+func (m *MemoryStore) SetPublisher(publisher EventPublisher) {
+	m.publisher = publisher
+}
+
+// SetCoalesceWindow merges repeats of an event into the stored one, see
+// Storage.SetCoalesceWindow. 0 disables coalescing.
+func (m *MemoryStore) SetCoalesceWindow(window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coalesceWindow = window
+}
+
+// SaveEventSync is the same as SaveEvent; the in-memory store never queues
+func (m *MemoryStore) SaveEventSync(ctx context.Context, event *ChangeEvent) error {
+	return m.SaveEvent(ctx, event)
+}
+
+// GetEvents returns events matching the filter, newest first
+func (m *MemoryStore) GetEvents(ctx context.Context, filter Filter) ([]ChangeEvent, error) {
+	if err := ValidateSort(filter.OrderBy, filter.Order); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return m.matches(e, filter) &&
+			(filter.Before == nil || filter.Before.isOlder(e)) &&
+			(filter.After == nil || filter.After.isNewer(e))
+	})
+	sortEvents(events, filter)
+	// Paging forward from After takes the page closest to the cursor
+	forward := filter.After != nil && filter.Before == nil
+	if forward {
+		slices.Reverse(events)
+	}
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(events) {
+			return nil, nil
+		}
+		events = events[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(events) {
+		events = events[:filter.Limit]
+	}
+	if forward {
+		slices.Reverse(events)
+	}
+	return events, nil
+}
+
+// StreamEvents calls fn for each event matching the filter, in the filter's
+// order. Cursors and the offset are ignored.
+func (m *MemoryStore) StreamEvents(ctx context.Context, filter Filter, fn func(*ChangeEvent) error) error {
+	filter.Before, filter.After, filter.Offset = nil, nil, 0
+	events, err := m.GetEvents(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		if err := fn(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchEvents returns events whose name, namespace, diff or metadata contain the
+// search text, ranked like the SQLite implementation
+func (m *MemoryStore) SearchEvents(ctx context.Context, text string, filter Filter) ([]ChangeEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return m.matches(e, filter) && searchMatches(e, text)
+	})
+	sort.SliceStable(events, func(i, j int) bool {
+		return searchRank(&events[i], text) < searchRank(&events[j], text)
+	})
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(events) {
+			return nil, nil
+		}
+		events = events[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(events) {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+// GetEventByID returns a single event with its annotations, or nil if it does
+// not exist
+func (m *MemoryStore) GetEventByID(ctx context.Context, id int64) (*ChangeEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := range m.events {
+		if m.events[i].ID == id {
+			event := m.events[i]
+			event.Annotations = slices.Clone(m.annotations[id])
+			return &event, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteEvent removes an event with its snapshot, annotations and
+// notification log
+func (m *MemoryStore) DeleteEvent(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.events, func(e ChangeEvent) bool { return e.ID == id })
+	if i < 0 {
+		return ErrEventNotFound
+	}
+	m.events = slices.Delete(m.events, i, i+1)
+	delete(m.snapshots, id)
+	delete(m.annotations, id)
+	m.notifications = slices.DeleteFunc(m.notifications, func(d NotificationDelivery) bool { return d.EventID == id })
+	return nil
+}
+
+// GetTotalCount returns total count of events matching filter
+func (m *MemoryStore) GetTotalCount(ctx context.Context, filter Filter) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+	for i := range m.events {
+		if m.matches(&m.events[i], filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetTimeline returns the events of one resource with their annotations, newest first
+func (m *MemoryStore) GetTimeline(ctx context.Context, namespace, kind, name string, filter Filter) ([]ChangeEvent, error) {
+	events, err := m.GetEvents(ctx, timelineFilter(namespace, kind, name, filter))
+	if err != nil {
+		return nil, err
+	}
+	return events, attachAnnotations(ctx, m, events)
+}
+
+// GetResourceSummary returns the summary of a resource, see Storage.GetResourceSummary
+func (m *MemoryStore) GetResourceSummary(ctx context.Context, namespace, kind, name string) (*ResourceSummary, error) {
+	events, err := m.GetEvents(ctx, timelineFilter(namespace, kind, name, Filter{}))
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	summary := newResourceSummary(&events[0], int64(len(events)))
+	for i := range events {
+		if summary.CurrentImage == "" && events[i].ImageAfter != "" {
+			summary.CurrentImage = events[i].ImageAfter
+		}
+		if summary.Replicas == nil {
+			var metadata struct {
+				Replicas *int64 `json:"replicas"`
+			}
+			if json.Unmarshal([]byte(events[i].Metadata), &metadata) == nil {
+				summary.Replicas = metadata.Replicas
+			}
+		}
+	}
+	return summary, nil
+}
+
+// GetResources lists the resources that have events, see Storage.GetResources
+func (m *MemoryStore) GetResources(ctx context.Context, filter ResourceFilter) ([]TrackedResource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resources := trackedResources(m.filtered(func(*ChangeEvent) bool { return true }), filter)
+	if filter.Limit <= 0 {
+		return resources, nil
+	}
+	if filter.Offset >= len(resources) {
+		return []TrackedResource{}, nil
+	}
+	return resources[filter.Offset:min(filter.Offset+filter.Limit, len(resources))], nil
+}
+
+// CountResources returns the number of resources GetResources would list without a limit
+func (m *MemoryStore) CountResources(ctx context.Context, filter ResourceFilter) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(trackedResources(m.filtered(func(*ChangeEvent) bool { return true }), filter))), nil
+}
+
+// GetAggregate counts the events of the last window per value of groupBy, see Storage.GetAggregate
+func (m *MemoryStore) GetAggregate(ctx context.Context, groupBy string, filter StatsFilter) ([]AggregateCount, error) {
+	if !containsValue(AggregateGroupBy, groupBy) {
+		return nil, fmt.Errorf("unsupported aggregate group_by %q", groupBy)
+	}
+	window := filter.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	since := time.Now().Add(-window)
+	totals := make(map[string]int64)
+	for i := range m.events {
+		event := &m.events[i]
+		if event.Timestamp.Before(since) || !inScope(filter.NamespacePatterns, event.Namespace) {
+			continue
+		}
+		switch groupBy {
+		case "namespace":
+			totals[event.Namespace]++
+		case "kind":
+			totals[event.Kind]++
+		case "name":
+			totals[event.Name]++
+		}
+	}
+
+	counts := make([]AggregateCount, 0, len(totals))
+	for key, count := range totals {
+		counts = append(counts, AggregateCount{Key: key, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts, nil
+}
+
+// GetTopChangedNamespaces returns the namespaces with the most events in the
+// last window, see Storage.GetTopChangedNamespaces
+func (m *MemoryStore) GetTopChangedNamespaces(ctx context.Context, window time.Duration, limit int) ([]NamespaceChangeCount, error) {
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	since := time.Now().Add(-window)
+	totals := make(map[string]int64)
+	for i := range m.events {
+		if !m.events[i].Timestamp.Before(since) {
+			totals[m.events[i].Namespace]++
+		}
+	}
+	return topNamespaces(totals, limit), nil
+}
+
+// GetEventHistogram counts matching events per time bucket, including empty buckets
+func (m *MemoryStore) GetEventHistogram(ctx context.Context, filter Filter, bucket time.Duration, groupBy string) ([]HistogramBucket, error) {
+	if err := validateHistogram(filter, bucket, groupBy); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	h := newHistogram(filter.StartTime, filter.EndTime, bucket, groupBy != "")
+	for i := range m.events {
+		event := &m.events[i]
+		if !m.matches(event, filter) {
+			continue
+		}
+		group := ""
+		switch groupBy {
+		case "kind":
+			group = event.Kind
+		case "action":
+			group = event.Action
+		}
+		h.add(event.Timestamp.UTC().Truncate(bucket), group, 1)
+	}
+	return h.buckets, nil
+}
+
+// GetImageHistory returns the image transitions of a deployment, newest first
+func (m *MemoryStore) GetImageHistory(ctx context.Context, namespace, name string, limit int) ([]ImageTransition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return e.Namespace == namespace && e.Name == name && isImageRollout(e)
+	})
+	history := []ImageTransition{}
+	for _, event := range limitEvents(events, limit) {
+		history = append(history, ImageTransition{
+			EventID:     event.ID,
+			Timestamp:   event.Timestamp,
+			ImageBefore: event.ImageBefore,
+			ImageAfter:  event.ImageAfter,
+		})
+	}
+	return history, nil
+}
+
+// GetImageHistoryByPrefix returns the image changes whose old or new image
+// starts with prefix, newest first
+func (m *MemoryStore) GetImageHistoryByPrefix(ctx context.Context, prefix string, limit int) ([]ImageHistoryEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return isImageChange(e) && (strings.HasPrefix(e.ImageBefore, prefix) || strings.HasPrefix(e.ImageAfter, prefix))
+	})
+	history := []ImageHistoryEntry{}
+	for _, event := range limitEvents(events, limit) {
+		history = append(history, ImageHistoryEntry{
+			EventID:     event.ID,
+			Namespace:   event.Namespace,
+			Kind:        event.Kind,
+			Resource:    event.Name,
+			ImageBefore: event.ImageBefore,
+			ImageAfter:  event.ImageAfter,
+			Timestamp:   event.Timestamp,
+		})
+	}
+	return history, nil
+}
+
+// GetChangesets returns the newest changesets, optionally only those of namespace
+func (m *MemoryStore) GetChangesets(ctx context.Context, namespace string, limit int) ([]Changeset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return e.ChangesetID != "" && (namespace == "" || e.Namespace == namespace)
+	})
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	// Changesets start with their oldest event; newest first
+	started := make(map[string]time.Time)
+	ids := []string{}
+	for _, event := range events {
+		if _, ok := started[event.ChangesetID]; !ok {
+			started[event.ChangesetID] = event.Timestamp
+			ids = append(ids, event.ChangesetID)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return started[ids[i]].After(started[ids[j]])
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return groupChangesets(ids, events), nil
+}
+
+// GetImageRollouts returns the deployment events that rolled out image, newest first
+func (m *MemoryStore) GetImageRollouts(ctx context.Context, image string, limit int) ([]ChangeEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return e.ImageAfter == image && isImageRollout(e)
+	})
+	return limitEvents(events, limit), nil
+}
+
+// isImageRollout reports whether a deployment event started a new image
+func isImageRollout(e *ChangeEvent) bool {
+	return e.Kind == "Deployment" && isImageChange(e)
+}
+
+func isImageChange(e *ChangeEvent) bool {
+	return e.ImageAfter != "" && e.ImageAfter != e.ImageBefore
+}
+
+func limitEvents(events []ChangeEvent, limit int) []ChangeEvent {
+	if limit > 0 && len(events) > limit {
+		return events[:limit]
+	}
+	return events
+}
+
+// GetStats computes dashboard statistics over the last window (DefaultStatsWindow if 0)
+func (m *MemoryStore) GetStats(ctx context.Context, filter StatsFilter) (*Stats, error) {
+	window := filter.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &Stats{
+		Window:             FormatWindow(window),
+		ChangesByKind:      make(map[string]int64),
+		ChangesByAction:    make(map[string]int64),
+		ChangesByNamespace: make(map[string]int64),
+		ChangesBySource:    make(map[string]int64),
+		ChangesBySeverity:  make(map[string]int64),
+	}
+
+	since := time.Now().Add(-window)
+	appCounts := make(map[AppChangeCount]int64) // keyed without Count
+	namespaceCounts := make(map[string]int64)
+	for i := range m.events {
+		event := &m.events[i]
+		if !inScope(filter.NamespacePatterns, event.Namespace) {
+			continue
+		}
+		stats.TotalChanges++
+		stats.ChangesByKind[event.Kind]++
+		stats.ChangesByAction[event.Action]++
+		stats.ChangesByNamespace[event.Namespace]++
+		stats.ChangesBySource[eventSource(event)]++
+		stats.ChangesBySeverity[eventSeverity(event)]++
+		if !event.Timestamp.Before(since) {
+			stats.ChangesLastWindow++
+			appCounts[AppChangeCount{Namespace: event.Namespace, Kind: event.Kind, Name: event.Name}]++
+			namespaceCounts[event.Namespace]++
+		}
+	}
+	stats.RowCount = stats.TotalChanges
+	stats.ChangesPerHour = float64(stats.ChangesLastWindow) / window.Hours()
+	stats.TopNamespaces = topNamespaces(namespaceCounts, statsTopNamespaces)
+
+	for app, count := range appCounts {
+		app.Count = count
+		stats.TopModifiedApps = append(stats.TopModifiedApps, app)
+	}
+	sort.Slice(stats.TopModifiedApps, func(i, j int) bool {
+		a, b := stats.TopModifiedApps[i], stats.TopModifiedApps[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Namespace+"/"+a.Kind+"/"+a.Name < b.Namespace+"/"+b.Kind+"/"+b.Name
+	})
+	if len(stats.TopModifiedApps) > 10 {
+		stats.TopModifiedApps = stats.TopModifiedApps[:10]
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range m.filtered(func(e *ChangeEvent) bool {
+		return e.ImageAfter != "" && !e.Timestamp.Before(since) && inScope(filter.NamespacePatterns, e.Namespace)
+	}) {
+		if seen[event.ImageAfter] {
+			continue
+		}
+		seen[event.ImageAfter] = true
+		stats.RecentImages = append(stats.RecentImages, event.ImageAfter)
+		if len(stats.RecentImages) == 10 {
+			break
+		}
+	}
+
+	return stats, nil
+}
+
+// GetNamespaceStats summarizes the changes in a namespace, see Storage.GetNamespaceStats
+func (m *MemoryStore) GetNamespaceStats(ctx context.Context, namespace string) (*NamespaceStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &NamespaceStats{
+		Namespace:          namespace,
+		ChangesByKind:      make(map[string]int64),
+		ChangesByAction:    make(map[string]int64),
+		TopResources:       []ResourceChangeCount{},
+		RecentImageChanges: []ImageChange{},
+	}
+
+	since := time.Now().Add(-namespaceStatsWindow)
+	resourceCounts := make(map[[2]string]int64)
+	events := m.filtered(func(e *ChangeEvent) bool { return e.Namespace == namespace })
+	for i := range events {
+		event := &events[i]
+		stats.TotalChanges++
+		stats.ChangesByKind[event.Kind]++
+		stats.ChangesByAction[event.Action]++
+		if !event.Timestamp.Before(since) {
+			stats.ChangesLast24h++
+			resourceCounts[[2]string{event.Kind, event.Name}]++
+		}
+		if isImageChange(event) && len(stats.RecentImageChanges) < 10 {
+			stats.RecentImageChanges = append(stats.RecentImageChanges, ImageChange{
+				Kind: event.Kind,
+				Name: event.Name,
+				ImageTransition: ImageTransition{
+					EventID:     event.ID,
+					Timestamp:   event.Timestamp,
+					ImageBefore: event.ImageBefore,
+					ImageAfter:  event.ImageAfter,
+				},
+			})
+		}
+	}
+
+	for resource, count := range resourceCounts {
+		stats.TopResources = append(stats.TopResources, ResourceChangeCount{Kind: resource[0], Name: resource[1], Count: count})
+	}
+	sort.Slice(stats.TopResources, func(i, j int) bool {
+		a, b := stats.TopResources[i], stats.TopResources[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	if len(stats.TopResources) > 10 {
+		stats.TopResources = stats.TopResources[:10]
+	}
+	return stats, nil
+}
+
+// SetRetentionOverrides sets per-kind retention periods in days that take
+// precedence over the retention passed to CleanupOldEvents
+func (m *MemoryStore) SetRetentionOverrides(overrides map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retentionOverrides = normalizeOverrides(overrides)
+}
+
+// CleanupOldEvents removes events older than their kind's retention period
+// and returns the deleted count per kind
+func (m *MemoryStore) CleanupOldEvents(ctx context.Context, retentionDays int) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, event := range m.events {
+		if !seen[event.Kind] {
+			seen[event.Kind] = true
+			kinds = append(kinds, event.Kind)
+		}
+	}
+	cutoffs := retentionCutoffs(kinds, m.retentionOverrides, retentionDays)
+
+	kept := m.events[:0]
+	deleted := make(map[string]int64)
+	for _, event := range m.events {
+		if event.Timestamp.Before(cutoffs[event.Kind]) {
+			delete(m.snapshots, event.ID)
+			delete(m.annotations, event.ID)
+			deleted[event.Kind]++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.events = kept
+	m.notifications = slices.DeleteFunc(m.notifications, func(d NotificationDelivery) bool {
+		return !slices.ContainsFunc(kept, func(e ChangeEvent) bool { return e.ID == d.EventID })
+	})
+	return deleted, nil
+}
+
+// SetEventTags replaces the tags of an event; no tags clears them
+func (m *MemoryStore) SetEventTags(ctx context.Context, id int64, tags []string) error {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.events {
+		if m.events[i].ID == id {
+			if len(tags) == 0 {
+				tags = nil
+			}
+			m.events[i].Tags = tags
+			return nil
+		}
+	}
+	return ErrEventNotFound
+}
+
+// AddEventNote appends a note to an event's notes
+func (m *MemoryStore) AddEventNote(ctx context.Context, id int64, note, author string) error {
+	note, author, err := ValidateNote(note, author)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.events {
+		if m.events[i].ID == id {
+			// Copy so events handed out earlier keep their notes
+			notes := slices.Clone(m.events[i].Notes)
+			m.events[i].Notes = append(notes, EventNote{Text: note, Author: author, At: time.Now().UTC()})
+			return nil
+		}
+	}
+	return ErrEventNotFound
+}
+
+// AddAnnotation attaches an annotation to its event and sets its ID
+func (m *MemoryStore) AddAnnotation(ctx context.Context, annotation *Annotation) error {
+	if err := ValidateAnnotation(annotation); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.ContainsFunc(m.events, func(e ChangeEvent) bool { return e.ID == annotation.EventID }) {
+		return ErrEventNotFound
+	}
+	if annotation.CreatedAt.IsZero() {
+		annotation.CreatedAt = time.Now()
+	}
+	annotation.CreatedAt = annotation.CreatedAt.UTC()
+	annotation.ID = m.nextAnnotationID
+	m.nextAnnotationID++
+	m.annotations[annotation.EventID] = append(m.annotations[annotation.EventID], *annotation)
+	return nil
+}
+
+// GetAnnotations returns the annotations of the given events, oldest first,
+// keyed by event ID
+func (m *MemoryStore) GetAnnotations(ctx context.Context, eventIDs []int64) (map[int64][]Annotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	annotations := make(map[int64][]Annotation)
+	for _, id := range eventIDs {
+		if stored := m.annotations[id]; len(stored) > 0 {
+			annotations[id] = slices.Clone(stored)
+		}
+	}
+	return annotations, nil
+}
+
+// RecordNotification stores a delivery and sets its ID
+func (m *MemoryStore) RecordNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+	delivery.DeliveredAt = utcTime(delivery.DeliveredAt)
+	delivery.ID = m.nextDeliveryID
+	m.nextDeliveryID++
+	m.notifications = append(m.notifications, *delivery)
+	return nil
+}
+
+// UpdateNotification replaces a recorded delivery
+func (m *MemoryStore) UpdateNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.notifications {
+		if m.notifications[i].ID == delivery.ID {
+			m.notifications[i].Status = delivery.Status
+			m.notifications[i].Attempts = delivery.Attempts
+			m.notifications[i].LastError = delivery.LastError
+			m.notifications[i].DeliveredAt = utcTime(delivery.DeliveredAt)
+			return nil
+		}
+	}
+	return ErrNotificationNotFound
+}
+
+// GetNotifications returns recorded deliveries, newest first
+func (m *MemoryStore) GetNotifications(ctx context.Context, filter NotificationFilter) ([]NotificationDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultNotificationLimit
+	}
+	deliveries := []NotificationDelivery{}
+	for i := len(m.notifications) - 1; i >= 0 && len(deliveries) < filter.Limit; i-- {
+		d := m.notifications[i]
+		if (filter.Channel == "" || d.Channel == filter.Channel) && (filter.Status == "" || d.Status == filter.Status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// GetNotification returns a single delivery, or nil if it does not exist
+func (m *MemoryStore) GetNotification(ctx context.Context, id int64) (*NotificationDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := range m.notifications {
+		if m.notifications[i].ID == id {
+			delivery := m.notifications[i]
+			return &delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetTags returns every tag in use with its event count, most used first
+func (m *MemoryStore) GetTags(ctx context.Context) ([]TagCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, event := range m.events {
+		for _, tag := range event.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// DBStats returns the event rows per kind; the in-memory store has no file
+func (m *MemoryStore) DBStats(ctx context.Context) (*DBStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &DBStats{RowsByKind: make(map[string]int64)}
+	for _, event := range m.events {
+		stats.RowsByKind[event.Kind]++
+	}
+	stats.TotalRows = int64(len(m.events))
+	return stats, nil
+}
+
+// ExportRange writes the events from start (inclusive) to end (exclusive) to
+// w as NDJSON, oldest first. A zero start or end leaves that side open.
+func (m *MemoryStore) ExportRange(ctx context.Context, start, end time.Time, w io.Writer) error {
+	m.mu.RLock()
+	events := m.filtered(func(e *ChangeEvent) bool {
+		return (start.IsZero() || !e.Timestamp.Before(start)) && (end.IsZero() || e.Timestamp.Before(end))
+	})
+	m.mu.RUnlock()
+	slices.Reverse(events)
+
+	encoder := json.NewEncoder(w)
+	for i := range events {
+		if err := encoder.Encode(&events[i]); err != nil {
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot stores the before/after documents for an event
+func (m *MemoryStore) SaveSnapshot(ctx context.Context, snapshot *EventSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *snapshot
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	m.snapshots[snapshot.EventID] = stored
+	return nil
+}
+
+// GetSnapshot returns the snapshot for an event, or nil if none was stored
+func (m *MemoryStore) GetSnapshot(ctx context.Context, eventID int64) (*EventSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot, ok := m.snapshots[eventID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// CleanupOldSnapshots removes snapshots older than the specified number of days
+func (m *MemoryStore) CleanupOldSnapshots(ctx context.Context, retentionDays int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
+	var deleted int64
+	for id, snapshot := range m.snapshots {
+		if snapshot.CreatedAt.Before(cutoffDate) {
+			delete(m.snapshots, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Flush is a no-op for the in-memory store, which writes events immediately
+func (m *MemoryStore) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// filtered returns copies of the matching events, newest first.
+// Callers must hold the lock.
+func (m *MemoryStore) filtered(match func(*ChangeEvent) bool) []ChangeEvent {
+	var events []ChangeEvent
+	for i := range m.events {
+		if match(&m.events[i]) {
+			events = append(events, m.events[i])
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].ID > events[j].ID
+		}
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	return events
+}
+
+// matches is matchesFilter plus the filters that depend on annotations.
+// Callers must hold the lock.
+func (m *MemoryStore) matches(event *ChangeEvent, filter Filter) bool {
+	if filter.Acknowledged != nil {
+		acknowledged := slices.ContainsFunc(m.annotations[event.ID], func(a Annotation) bool { return a.Acknowledged })
+		if acknowledged != *filter.Acknowledged {
+			return false
+		}
+	}
+	if filter.Query != "" {
+		// Full-text search also covers the notes of annotations
+		text := eventText(event)
+		for _, a := range m.annotations[event.ID] {
+			text += "\n" + a.Note
+		}
+		if !matchesQuery(text, filter.Query) {
+			return false
+		}
+		filter.Query = ""
+	}
+	return matchesFilter(event, filter)
+}
+
+// matchesFilter mirrors the WHERE clause built by buildWhere
+func matchesFilter(event *ChangeEvent, filter Filter) bool {
+	if filter.Namespace != "" && event.Namespace != filter.Namespace {
+		return false
+	}
+	if filter.Kind != "" && event.Kind != filter.Kind {
+		return false
+	}
+	if filter.Name != "" && !strings.Contains(strings.ToLower(event.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.Action != "" && event.Action != filter.Action {
+		return false
+	}
+	if filter.Author != "" && event.Author != filter.Author {
+		return false
+	}
+	if filter.UID != "" && event.UID != filter.UID {
+		return false
+	}
+	if filter.App != "" && event.App != filter.App {
+		return false
+	}
+	if filter.Source != "" && eventSource(event) != filter.Source {
+		return false
+	}
+	if filter.Severity != "" && eventSeverity(event) != filter.Severity {
+		return false
+	}
+	if filter.Tag != "" && !slices.Contains(event.Tags, filter.Tag) {
+		return false
+	}
+	if filter.Query != "" && !matchesQuery(eventText(event), filter.Query) {
+		return false
+	}
+	if len(filter.Namespaces) > 0 && !containsValue(filter.Namespaces, event.Namespace) {
+		return false
+	}
+	if len(filter.Kinds) > 0 && !containsValue(filter.Kinds, event.Kind) {
+		return false
+	}
+	if len(filter.Actions) > 0 && !containsValue(filter.Actions, event.Action) {
+		return false
+	}
+	if len(filter.NamespacePatterns) > 0 && !MatchNamespace(filter.NamespacePatterns, event.Namespace) {
+		return false
+	}
+	if containsValue(filter.ExcludeNamespaces, event.Namespace) || containsValue(filter.ExcludeKinds, event.Kind) ||
+		containsValue(filter.ExcludeActions, event.Action) {
+		return false
+	}
+	if !filter.StartTime.IsZero() && event.Timestamp.Before(filter.StartTime) {
+		return false
+	}
+	if !filter.EndTime.IsZero() && event.Timestamp.After(filter.EndTime) {
+		return false
+	}
+	if event.ID <= filter.SinceID {
+		return false
+	}
+	return true
+}
+
+// eventSource returns the event's source, counting a missing one as unknown
+func eventSource(event *ChangeEvent) string {
+	if event.Source == "" {
+		return "unknown"
+	}
+	return event.Source
+}
+
+func eventSeverity(event *ChangeEvent) string {
+	if event.Severity == "" {
+		return SeverityInfo
+	}
+	return event.Severity
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// eventText is the text of an event covered by full-text search
+func eventText(event *ChangeEvent) string {
+	return event.Name + "\n" + event.Diff + "\n" + event.Metadata + "\n" + strings.Join(event.Tags, " ") + "\n" + notesText(event.Notes)
+}
+
+// matchesQuery reports whether text contains every word of a full-text query,
+// ignoring case
+func matchesQuery(text, query string) bool {
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// inScope reports whether namespace matches the globs patterns; no patterns
+// match every namespace
+func inScope(patterns []string, namespace string) bool {
+	return len(patterns) == 0 || MatchNamespace(patterns, namespace)
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one step of the schema history. Migrations run in version
+// order, each in its own transaction, and are recorded in schema_migrations.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations is the full schema history. Never edit or reorder an existing
+// entry; append a new one instead.
+var migrations = []migration{
+	{1, "initial schema", execSQL(`
+		CREATE TABLE IF NOT EXISTS change_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			namespace TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			name TEXT NOT NULL,
+			action TEXT NOT NULL,
+			diff TEXT,
+			metadata TEXT,
+			image_before TEXT,
+			image_after TEXT
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_timestamp ON change_events(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_namespace ON change_events(namespace);
+		CREATE INDEX IF NOT EXISTS idx_kind ON change_events(kind);
+		CREATE INDEX IF NOT EXISTS idx_name ON change_events(name);
+		CREATE INDEX IF NOT EXISTS idx_action ON change_events(action);
+
+		-- Composite indexes for common queries
+		CREATE INDEX IF NOT EXISTS idx_namespace_kind_name ON change_events(namespace, kind, name);
+		CREATE INDEX IF NOT EXISTS idx_kind_timestamp ON change_events(kind, timestamp DESC);
+		CREATE INDEX IF NOT EXISTS idx_namespace_timestamp ON change_events(namespace, timestamp DESC);
+	`)},
+	{2, "add author column", addColumn("change_events", "author", "TEXT")},
+	{3, "add uid and resource_version columns", steps(
+		addColumn("change_events", "uid", "TEXT"),
+		addColumn("change_events", "resource_version", "TEXT"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_uid ON change_events(uid)`),
+	)},
+	{4, "add event_snapshots table", execSQL(`
+		-- Optional full object snapshots, kept separately because of their size
+		CREATE TABLE IF NOT EXISTS event_snapshots (
+			event_id INTEGER PRIMARY KEY,
+			created_at DATETIME NOT NULL,
+			before_json TEXT,
+			after_json TEXT
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_snapshots_created_at ON event_snapshots(created_at);
+	`)},
+	{5, "add actor column", addColumn("change_events", "actor", "TEXT")},
+	{6, "add source column", addColumn("change_events", "source", "TEXT")},
+	{7, "add image_after index", execSQL(`CREATE INDEX IF NOT EXISTS idx_image_after ON change_events(image_after)`)},
+	{8, "add severity column", addColumn("change_events", "severity", "TEXT")},
+	{9, "index resource timelines by time", execSQL(`
+		-- Covers paged timeline queries; replaces the (namespace, kind, name) index
+		CREATE INDEX IF NOT EXISTS idx_resource_timestamp ON change_events(namespace, kind, name, timestamp DESC, id DESC);
+		DROP INDEX IF EXISTS idx_namespace_kind_name;
+	`)},
+	{10, "add tags column", addColumn("change_events", "tags", "TEXT")},
+	{11, "add repeat_count and last_seen columns", steps(
+		addColumn("change_events", "repeat_count", "INTEGER NOT NULL DEFAULT 1"),
+		addColumn("change_events", "last_seen", "DATETIME"),
+	)},
+	{12, "add diff_compressed and diff_size columns", steps(
+		addColumn("change_events", "diff_compressed", "BLOB"),
+		addColumn("change_events", "diff_size", "INTEGER"),
+	)},
+	{13, "add event_annotations table", execSQL(`
+		-- Review notes and acknowledgements, kept out of change_events so the
+		-- write path is unaffected
+		CREATE TABLE IF NOT EXISTS event_annotations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id INTEGER NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			note TEXT NOT NULL DEFAULT '',
+			acknowledged INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_annotations_event_id ON event_annotations(event_id);
+	`)},
+	{14, "add notification_log table", execSQL(`
+		CREATE TABLE IF NOT EXISTS notification_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id INTEGER NOT NULL,
+			channel TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			created_at DATETIME NOT NULL,
+			delivered_at DATETIME
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_notification_log_created_at ON notification_log(created_at);
+		CREATE INDEX IF NOT EXISTS idx_notification_log_event_id ON notification_log(event_id);
+	`)},
+	{15, "store timestamps in UTC", steps(
+		utcColumns("change_events", "timestamp", "last_seen"),
+		utcColumns("event_snapshots", "created_at"),
+		utcColumns("event_annotations", "created_at"),
+		utcColumns("notification_log", "created_at", "delivered_at"),
+	)},
+	{16, "add event_hash column", steps(
+		addColumn("change_events", "event_hash", "TEXT"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_event_hash ON change_events(event_hash)`),
+	)},
+	{17, "add changeset_id column", steps(
+		addColumn("change_events", "changeset_id", "TEXT"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_changeset_id ON change_events(changeset_id)`),
+	)},
+	{18, "add notes column", addColumn("change_events", "notes", "TEXT")},
+	{19, "add app column", steps(
+		addColumn("change_events", "app", "TEXT"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_namespace_app_timestamp ON change_events(namespace, app, timestamp DESC, id DESC)`),
+	)},
+}
+
+// migrate applies all pending migrations and returns the resulting schema version
+func (s *Storage) migrate() (int, error) {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return current, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		s.logger.Info("Applied schema migration", slog.Int("version", m.version), slog.String("description", m.description))
+		current = m.version
+	}
+
+	return current, nil
+}
+
+func (s *Storage) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", m.version, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execSQL is a migration step that runs a fixed SQL script
+func execSQL(script string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(script)
+		return err
+	}
+}
+
+// addColumn is a migration step that adds a column unless it already exists.
+// Databases created before migrations were tracked may already have it.
+func addColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				cid        int
+				name       string
+				colType    string
+				notNull    int
+				defaultVal sql.NullString
+				primaryKey int
+			)
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+				return fmt.Errorf("failed to scan table info: %w", err)
+			}
+			if name == column {
+				return nil
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read table info of %s: %w", table, err)
+		}
+		rows.Close()
+
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+		}
+		return nil
+	}
+}
+
+// utcColumns is a migration step that rewrites the DATETIME columns of a table
+// to UTC. Timestamps are compared as text, so values stored with the local
+// offset of the host that wrote them don't sort correctly against UTC ones.
+func utcColumns(table string, columns ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, column := range columns {
+			rows, err := tx.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s IS NOT NULL AND %s NOT LIKE '%%+00:00'", column, table, column, column))
+			if err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+			}
+			type storedTime struct {
+				rowid int64
+				t     time.Time
+			}
+			var local []storedTime
+			for rows.Next() {
+				var row storedTime
+				if err := rows.Scan(&row.rowid, &row.t); err != nil {
+					rows.Close()
+					return fmt.Errorf("failed to scan %s.%s: %w", table, column, err)
+				}
+				local = append(local, row)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+			}
+
+			update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column)
+			for _, row := range local {
+				if _, err := tx.Exec(update, row.t.UTC(), row.rowid); err != nil {
+					return fmt.Errorf("failed to convert %s.%s to UTC: %w", table, column, err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// steps combines several migration steps into one
+func steps(fns ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, fn := range fns {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
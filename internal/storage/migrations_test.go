@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// latestVersion is the version of the last migration
+var latestVersion = migrations[len(migrations)-1].version
+
+// createV1Database creates a database at path holding only the initial
+// schema, as written by the first release, with two events
+func createV1Database(t *testing.T, path string) {
+	t.Helper()
+	db, err := openDB(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	old := &Storage{db: db, logger: slog.New(slog.DiscardHandler)}
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.applyMigration(migrations[0]); err != nil {
+		t.Fatalf("migration 1: %v", err)
+	}
+
+	// The first release stored the host's local time
+	_, err = db.Exec(`
+		INSERT INTO change_events (timestamp, namespace, kind, name, action, diff, metadata, image_before, image_after)
+		VALUES
+			('2026-01-05 13:00:00+01:00', 'shop', 'Deployment', 'web', 'MODIFIED', 'Image updated: nginx:1.26 → nginx:1.27', '{"replicas":3}', 'nginx:1.26', 'nginx:1.27'),
+			('2026-01-05 14:30:00+01:00', 'shop', 'ConfigMap', 'settings', 'ADDED', 'ADDED', '{"keys":["log_level"]}', NULL, NULL)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateFromV1(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+	createV1Database(t, path)
+
+	s, err := NewStorage(path, "", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewStorage on a v1 database: %v", err)
+	}
+	defer s.Close()
+
+	if s.SchemaVersion() != latestVersion {
+		t.Errorf("SchemaVersion = %d, want %d", s.SchemaVersion(), latestVersion)
+	}
+	var applied int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+
+	events, err := s.GetEvents(ctx, Filter{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetEvents after the upgrade: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("GetEvents returned %d events, want 2", len(events))
+	}
+	web := events[1]
+	if web.Namespace != "shop" || web.Kind != "Deployment" || web.Name != "web" || web.Action != "MODIFIED" ||
+		web.Diff != "Image updated: nginx:1.26 → nginx:1.27" || web.Metadata != `{"replicas":3}` ||
+		web.ImageBefore != "nginx:1.26" || web.ImageAfter != "nginx:1.27" {
+		t.Errorf("upgraded event = %+v", web)
+	}
+	if want := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC); !web.Timestamp.Equal(want) || web.Timestamp.Location() != time.UTC {
+		t.Errorf("Timestamp = %v, want %v", web.Timestamp, want)
+	}
+	if settings := events[0]; settings.Name != "settings" || settings.ImageBefore != "" || settings.Metadata != `{"keys":["log_level"]}` {
+		t.Errorf("upgraded event = %+v", settings)
+	}
+
+	// Upgraded rows work with the features added since
+	history, err := s.GetImageHistory(ctx, "shop", "web", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].ImageAfter != "nginx:1.27" {
+		t.Errorf("GetImageHistory = %+v", history)
+	}
+	if err := s.SetEventTags(ctx, web.ID, []string{"legacy"}); err != nil {
+		t.Errorf("SetEventTags on an upgraded event: %v", err)
+	}
+	event := &ChangeEvent{Timestamp: time.Now(), Namespace: "shop", Kind: "Deployment", Name: "web", Action: "MODIFIED", Author: "ci", ResourceVersion: "12"}
+	if err := s.SaveEventSync(ctx, event); err != nil {
+		t.Fatalf("SaveEventSync after the upgrade: %v", err)
+	}
+	if event.ID <= web.ID {
+		t.Errorf("new event ID %d does not follow the upgraded ones", event.ID)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.db")
+	createV1Database(t, path)
+
+	for range 2 {
+		s, err := NewStorage(path, "", slog.New(slog.DiscardHandler))
+		if err != nil {
+			t.Fatalf("NewStorage: %v", err)
+		}
+		count, err := s.GetTotalCount(context.Background(), Filter{})
+		s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Errorf("GetTotalCount = %d after reopening, want 2", count)
+		}
+	}
+}
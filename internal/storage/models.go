@@ -1,36 +1,258 @@
 package storage
 
-import "time"
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Event severities, from least to most severe. Events recorded before
+// severities existed count as info.
+const (
+	SeverityInfo     = "info"
+	SeverityNotice   = "notice"
+	SeverityWarn     = "warn"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the event severities from least to most severe
+var Severities = []string{SeverityInfo, SeverityNotice, SeverityWarn, SeverityCritical}
+
+// SeverityRank orders severities from 0 (info) up; unknown severities rank as info
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+// ValidateSeverity checks that severity is one of Severities
+func ValidateSeverity(severity string) error {
+	for _, s := range Severities {
+		if s == severity {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid severity %q (expected %s)", severity, strings.Join(Severities, ", "))
+}
+
+// ActionThresholdExceeded marks synthetic events raised when a resource
+// crosses a usage threshold (e.g. a ResourceQuota near capacity)
+const ActionThresholdExceeded = "THRESHOLD_EXCEEDED"
+
+// ActionMissedSchedule marks synthetic events raised when a CronJob stopped
+// running on its schedule
+const ActionMissedSchedule = "MISSED_SCHEDULE"
 
 // ChangeEvent represents a Kubernetes resource change
 type ChangeEvent struct {
-	ID          int64     `json:"id"`
+	ID              int64       `json:"id"`
+	Timestamp       time.Time   `json:"timestamp"`
+	Namespace       string      `json:"namespace"`
+	Kind            string      `json:"kind"` // Deployment, ConfigMap, Secret
+	Name            string      `json:"name"`
+	Action          string      `json:"action"`   // ADDED, MODIFIED, DELETED, THRESHOLD_EXCEEDED or MISSED_SCHEDULE
+	Diff            string      `json:"diff"`     // JSON diff or text diff
+	Metadata        string      `json:"metadata"` // JSON metadata (labels, annotations, etc)
+	ImageBefore     string      `json:"image_before,omitempty"`
+	ImageAfter      string      `json:"image_after,omitempty"`
+	Author          string      `json:"author,omitempty"`           // field manager that made the change (best effort)
+	UID             string      `json:"uid,omitempty"`              // object UID, distinguishes recreated resources
+	ResourceVersion string      `json:"resource_version,omitempty"` // object resourceVersion at the time of the change
+	Actor           string      `json:"actor,omitempty"`            // user that issued the API call, from the audit log
+	Source          string      `json:"source,omitempty"`           // tool behind the change: helm, argocd, flux, kubectl or unknown
+	Severity        string      `json:"severity,omitempty"`         // info, notice, warn or critical
+	Tags            []string    `json:"tags,omitempty"`             // set by users to classify events
+	RepeatCount     int64       `json:"repeat_count"`               // occurrences coalesced into this event, at least 1
+	LastSeen        *time.Time  `json:"last_seen,omitempty"`        // time of the last coalesced repeat
+	ChangesetID     string      `json:"changeset_id,omitempty"`     // groups a deployment rollout with the ReplicaSet events it caused
+	Notes           []EventNote `json:"notes,omitempty"`            // operator notes, oldest first
+	App             string      `json:"app,omitempty"`              // application label of the resource when the change was seen
+
+	// Annotations are only loaded for single events and timelines
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// utcTime returns a copy of t in UTC; nil stays nil
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// EventSnapshot holds the sanitized object JSON before and after a change
+type EventSnapshot struct {
+	EventID   int64     `json:"event_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+}
+
+// TimelineSegment groups the events of a single resource incarnation (UID)
+type TimelineSegment struct {
+	UID       string        `json:"uid"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	Events    []ChangeEvent `json:"events"`
+}
+
+// SegmentByUID splits a timeline into consecutive runs of events sharing a UID,
+// preserving the input order. Events recorded before UIDs were tracked have an
+// empty UID and are kept with their neighbours.
+func SegmentByUID(events []ChangeEvent) []TimelineSegment {
+	var segments []TimelineSegment
+	for _, event := range events {
+		n := len(segments)
+		if n == 0 || (event.UID != "" && segments[n-1].UID != "" && event.UID != segments[n-1].UID) {
+			segments = append(segments, TimelineSegment{
+				UID:       event.UID,
+				FirstSeen: event.Timestamp,
+				LastSeen:  event.Timestamp,
+			})
+			n++
+		}
+		seg := &segments[n-1]
+		if seg.UID == "" {
+			seg.UID = event.UID
+		}
+		if event.Timestamp.Before(seg.FirstSeen) {
+			seg.FirstSeen = event.Timestamp
+		}
+		if event.Timestamp.After(seg.LastSeen) {
+			seg.LastSeen = event.Timestamp
+		}
+		seg.Events = append(seg.Events, event)
+	}
+	return segments
+}
+
+// DefaultStatsWindow is the period GetStats covers when no window is given
+const DefaultStatsWindow = 24 * time.Hour
+
+// StatsFilter selects the events counted by GetStats and GetAggregate
+type StatsFilter struct {
+	// Window is the period of the recent-change counts; 0 means DefaultStatsWindow
+	Window time.Duration
+
+	// NamespacePatterns are globs, as in Filter.NamespacePatterns
+	NamespacePatterns []string
+}
+
+// namespaceStatsWindow is the period of NamespaceStats.ChangesLast24h and TopResources
+const namespaceStatsWindow = 24 * time.Hour
+
+// FormatWindow formats a stats window the way /api/stats accepts it, e.g. 7d or 1h30m
+func FormatWindow(d time.Duration) string {
+	if d > 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// timelineFilter restricts filter to a single resource
+func timelineFilter(namespace, kind, name string, filter Filter) Filter {
+	filter.Namespace, filter.Kind, filter.Name = namespace, kind, name
+	filter.Namespaces, filter.Kinds = nil, nil
+	return filter
+}
+
+// Stats represents dashboard statistics. ChangesLastWindow, ChangesPerHour,
+// TopModifiedApps and RecentImages cover the last Window; the rest cover all events.
+type Stats struct {
+	Window             string                 `json:"window"` // period covered by the windowed fields below
+	TotalChanges       int64                  `json:"total_changes"`
+	ChangesLastWindow  int64                  `json:"changes_last_window"`
+	ChangesPerHour     float64                `json:"changes_per_hour"`
+	TopModifiedApps    []AppChangeCount       `json:"top_modified_apps"`
+	TopNamespaces      []NamespaceChangeCount `json:"top_namespaces"` // most changed in the window
+	RecentImages       []string               `json:"recent_images"`
+	ChangesByKind      map[string]int64       `json:"changes_by_kind"`
+	ChangesByAction    map[string]int64       `json:"changes_by_action"`
+	ChangesByNamespace map[string]int64       `json:"changes_by_namespace"`
+	ChangesBySource    map[string]int64       `json:"changes_by_source"`
+	ChangesBySeverity  map[string]int64       `json:"changes_by_severity"`
+	RowCount           int64                  `json:"row_count"`                // events currently stored
+	MaxEvents          int64                  `json:"max_events,omitempty"`     // row count cap, if one is set
+	DeduplicatedEvents int64                  `json:"deduplicated_events"`      // duplicates skipped since start
+	SchemaVersion      int                    `json:"schema_version,omitempty"` // database schema migration version
+}
+
+// NamespaceStats summarizes the changes in one namespace
+type NamespaceStats struct {
+	Namespace          string                `json:"namespace"`
+	TotalChanges       int64                 `json:"total_changes"`
+	ChangesLast24h     int64                 `json:"changes_last_24h"`
+	ChangesByKind      map[string]int64      `json:"changes_by_kind"`
+	ChangesByAction    map[string]int64      `json:"changes_by_action"`
+	TopResources       []ResourceChangeCount `json:"top_resources"` // most changed in the last 24h
+	RecentImageChanges []ImageChange         `json:"recent_image_changes"`
+}
+
+// ResourceChangeCount is the number of changes to one resource
+type ResourceChangeCount struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ImageChange is an image transition of a named resource
+type ImageChange struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	ImageTransition
+}
+
+// ImageTransition is a point where a deployment started running a new image
+type ImageTransition struct {
+	EventID     int64     `json:"event_id"`
 	Timestamp   time.Time `json:"timestamp"`
+	ImageBefore string    `json:"image_before,omitempty"`
+	ImageAfter  string    `json:"image_after"`
+}
+
+// ImageHistoryEntry is an image change of a resource whose image matched a
+// repository prefix
+type ImageHistoryEntry struct {
+	EventID     int64     `json:"event_id"`
 	Namespace   string    `json:"namespace"`
-	Kind        string    `json:"kind"` // Deployment, ConfigMap, Secret
-	Name        string    `json:"name"`
-	Action      string    `json:"action"`   // ADDED, MODIFIED, DELETED
-	Diff        string    `json:"diff"`     // JSON diff or text diff
-	Metadata    string    `json:"metadata"` // JSON metadata (labels, annotations, etc)
+	Kind        string    `json:"kind"`
+	Resource    string    `json:"resource"` // resource name
 	ImageBefore string    `json:"image_before,omitempty"`
-	ImageAfter  string    `json:"image_after,omitempty"`
+	ImageAfter  string    `json:"image_after"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
-// Stats represents dashboard statistics
-type Stats struct {
-	TotalChanges    int64            `json:"total_changes"`
-	ChangesLast24h  int64            `json:"changes_last_24h"`
-	ChangesPerHour  float64          `json:"changes_per_hour"`
-	TopModifiedApps []AppChangeCount `json:"top_modified_apps"`
-	RecentImages    []string         `json:"recent_images"`
-	ChangesByKind   map[string]int64 `json:"changes_by_kind"`
-	ChangesByAction map[string]int64 `json:"changes_by_action"`
+// HistogramBucket is the number of events in one time interval
+type HistogramBucket struct {
+	Start  time.Time        `json:"start"`
+	Count  int64            `json:"count"`
+	Groups map[string]int64 `json:"groups,omitempty"` // counts per kind or action, if requested
 }
 
 // AppChangeCount represents changes per app
 type AppChangeCount struct {
-	Name  string `json:"name"`
-	Count int64  `json:"count"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`
+}
+
+// NamespaceChangeCount is the number of changes in one namespace
+type NamespaceChangeCount struct {
+	Namespace string `json:"namespace"`
+	Count     int64  `json:"count"`
 }
 
 // Filter represents query filters
@@ -39,8 +261,55 @@ type Filter struct {
 	Kind      string
 	Name      string
 	Action    string
+	Author    string
+	UID       string
+	Source    string
+	Severity  string
+	Tag       string
+	App       string
+	Query     string // full-text query over name, diff and metadata
 	StartTime time.Time
 	EndTime   time.Time
 	Limit     int
 	Offset    int
+	OrderBy   string // one of SortFields; timestamp when empty
+	Order     string // asc or desc; desc when empty
+
+	// Keyset pagination for GetEvents: only events older than Before or
+	// newer than After are returned. Ignored when counting.
+	Before *Cursor
+	After  *Cursor
+
+	// Multi-value filters; an event must match one of the values
+	Namespaces []string
+	Kinds      []string
+	Actions    []string
+
+	// NamespacePatterns are globs (path.Match syntax, e.g. team-a-*); an event's
+	// namespace must match one of them
+	NamespacePatterns []string
+
+	// Exclusions; events matching any of the values are left out
+	ExcludeNamespaces []string
+	ExcludeKinds      []string
+	ExcludeActions    []string
+
+	// Acknowledged keeps only events with (true) or without (false) an
+	// acknowledging annotation; nil matches all
+	Acknowledged *bool
+
+	// SinceID keeps only events stored after the event with this ID; 0
+	// matches all
+	SinceID int64
+}
+
+// MatchNamespace reports whether namespace matches one of the glob patterns
+// of Filter.NamespacePatterns
+func MatchNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
 }
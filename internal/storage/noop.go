@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// NoopStorage is an EventStore that discards every write and returns empty
+// results. It backs --dry-run, where events are only logged.
+type NoopStorage struct{}
+
+// NewNoopStorage creates a store that persists nothing
+func NewNoopStorage() *NoopStorage {
+	return &NoopStorage{}
+}
+
+func (NoopStorage) SaveEvent(ctx context.Context, event *ChangeEvent) error     { return nil }
+func (NoopStorage) SaveEventSync(ctx context.Context, event *ChangeEvent) error { return nil }
+
+func (NoopStorage) GetEvents(ctx context.Context, filter Filter) ([]ChangeEvent, error) {
+	return []ChangeEvent{}, nil
+}
+
+func (NoopStorage) StreamEvents(ctx context.Context, filter Filter, fn func(*ChangeEvent) error) error {
+	return nil
+}
+
+func (NoopStorage) GetEventByID(ctx context.Context, id int64) (*ChangeEvent, error) {
+	return nil, nil
+}
+
+func (NoopStorage) DeleteEvent(ctx context.Context, id int64) error {
+	return ErrEventNotFound
+}
+
+func (NoopStorage) GetAggregate(ctx context.Context, groupBy string, filter StatsFilter) ([]AggregateCount, error) {
+	return []AggregateCount{}, nil
+}
+
+func (NoopStorage) GetResourceSummary(ctx context.Context, namespace, kind, name string) (*ResourceSummary, error) {
+	return nil, nil
+}
+
+func (NoopStorage) GetResources(ctx context.Context, filter ResourceFilter) ([]TrackedResource, error) {
+	return []TrackedResource{}, nil
+}
+
+func (NoopStorage) CountResources(ctx context.Context, filter ResourceFilter) (int64, error) {
+	return 0, nil
+}
+
+func (NoopStorage) GetTopChangedNamespaces(ctx context.Context, window time.Duration, limit int) ([]NamespaceChangeCount, error) {
+	return []NamespaceChangeCount{}, nil
+}
+
+func (NoopStorage) GetImageHistoryByPrefix(ctx context.Context, prefix string, limit int) ([]ImageHistoryEntry, error) {
+	return []ImageHistoryEntry{}, nil
+}
+
+func (NoopStorage) GetChangesets(ctx context.Context, namespace string, limit int) ([]Changeset, error) {
+	return []Changeset{}, nil
+}
+
+func (NoopStorage) SearchEvents(ctx context.Context, text string, filter Filter) ([]ChangeEvent, error) {
+	return []ChangeEvent{}, nil
+}
+
+func (NoopStorage) GetTotalCount(ctx context.Context, filter Filter) (int64, error) {
+	return 0, nil
+}
+
+func (NoopStorage) GetTimeline(ctx context.Context, namespace, kind, name string, filter Filter) ([]ChangeEvent, error) {
+	return []ChangeEvent{}, nil
+}
+
+func (NoopStorage) GetStats(ctx context.Context, filter StatsFilter) (*Stats, error) {
+	window := filter.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+	return &Stats{
+		Window:            FormatWindow(window),
+		TopModifiedApps:   []AppChangeCount{},
+		RecentImages:      []string{},
+		ChangesByKind:     map[string]int64{},
+		ChangesByAction:   map[string]int64{},
+		ChangesBySource:   map[string]int64{},
+		ChangesBySeverity: map[string]int64{},
+	}, nil
+}
+
+func (NoopStorage) GetNamespaceStats(ctx context.Context, namespace string) (*NamespaceStats, error) {
+	return &NamespaceStats{
+		Namespace:          namespace,
+		ChangesByKind:      map[string]int64{},
+		ChangesByAction:    map[string]int64{},
+		TopResources:       []ResourceChangeCount{},
+		RecentImageChanges: []ImageChange{},
+	}, nil
+}
+
+func (NoopStorage) GetEventHistogram(ctx context.Context, filter Filter, bucket time.Duration, groupBy string) ([]HistogramBucket, error) {
+	return []HistogramBucket{}, nil
+}
+
+func (NoopStorage) GetImageHistory(ctx context.Context, namespace, name string, limit int) ([]ImageTransition, error) {
+	return []ImageTransition{}, nil
+}
+
+func (NoopStorage) GetImageRollouts(ctx context.Context, image string, limit int) ([]ChangeEvent, error) {
+	return []ChangeEvent{}, nil
+}
+
+func (NoopStorage) SetEventTags(ctx context.Context, id int64, tags []string) error { return nil }
+
+func (NoopStorage) GetTags(ctx context.Context) ([]TagCount, error) {
+	return []TagCount{}, nil
+}
+
+func (NoopStorage) AddEventNote(ctx context.Context, id int64, note, author string) error {
+	return ErrEventNotFound
+}
+
+func (NoopStorage) AddAnnotation(ctx context.Context, annotation *Annotation) error {
+	return ErrEventNotFound
+}
+
+func (NoopStorage) GetAnnotations(ctx context.Context, eventIDs []int64) (map[int64][]Annotation, error) {
+	return map[int64][]Annotation{}, nil
+}
+
+func (NoopStorage) RecordNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	return nil
+}
+
+func (NoopStorage) UpdateNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	return nil
+}
+
+func (NoopStorage) GetNotifications(ctx context.Context, filter NotificationFilter) ([]NotificationDelivery, error) {
+	return []NotificationDelivery{}, nil
+}
+
+func (NoopStorage) GetNotification(ctx context.Context, id int64) (*NotificationDelivery, error) {
+	return nil, nil
+}
+
+func (NoopStorage) CleanupOldEvents(ctx context.Context, retentionDays int) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (NoopStorage) DBStats(ctx context.Context) (*DBStats, error) {
+	return &DBStats{RowsByKind: map[string]int64{}}, nil
+}
+
+func (NoopStorage) ExportRange(ctx context.Context, start, end time.Time, w io.Writer) error {
+	return nil
+}
+
+func (NoopStorage) SaveSnapshot(ctx context.Context, snapshot *EventSnapshot) error { return nil }
+
+func (NoopStorage) GetSnapshot(ctx context.Context, eventID int64) (*EventSnapshot, error) {
+	return nil, nil
+}
+
+func (NoopStorage) CleanupOldSnapshots(ctx context.Context, retentionDays int) (int64, error) {
+	return 0, nil
+}
+
+func (NoopStorage) Flush(ctx context.Context) error { return nil }
+
+func (NoopStorage) Close() error { return nil }
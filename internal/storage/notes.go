@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventNote is an operator note appended to an event, e.g. the incident that
+// caused it or who approved it
+type EventNote struct {
+	Text   string    `json:"text"`
+	Author string    `json:"author,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ValidateNote trims a note and its author and checks their lengths. Notes
+// share the limits of annotations.
+func ValidateNote(note, author string) (string, string, error) {
+	note = strings.TrimSpace(note)
+	author = strings.TrimSpace(author)
+	switch {
+	case note == "":
+		return "", "", fmt.Errorf("note must not be empty")
+	case len(note) > maxAnnotationNote:
+		return "", "", fmt.Errorf("note is longer than %d characters", maxAnnotationNote)
+	case len(author) > maxAnnotationAuthor:
+		return "", "", fmt.Errorf("author is longer than %d characters", maxAnnotationAuthor)
+	}
+	return note, author, nil
+}
+
+// AddEventNote appends a note to an event's notes. It returns ErrEventNotFound
+// if the event does not exist.
+func (s *Storage) AddEventNote(ctx context.Context, id int64, note, author string) error {
+	note, author, err := ValidateNote(note, author)
+	if err != nil {
+		return err
+	}
+	encoded, _ := json.Marshal(EventNote{Text: note, Author: author, At: time.Now().UTC()})
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE change_events SET notes = json_insert(COALESCE(notes, '[]'), '$[#]', json(?)) WHERE id = ?`,
+		string(encoded), id)
+	if err != nil {
+		return fmt.Errorf("failed to add event note: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEventNotFound
+	}
+
+	if err := s.reindexAnnotations(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// parseNotes decodes the notes column; NULL or malformed values yield no notes
+func parseNotes(value sql.NullString) []EventNote {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	var notes []EventNote
+	if err := json.Unmarshal([]byte(value.String), &notes); err != nil {
+		return nil
+	}
+	for i := range notes {
+		notes[i].At = notes[i].At.UTC()
+	}
+	return notes
+}
+
+// notesText joins the text of notes for text search
+func notesText(notes []EventNote) string {
+	texts := make([]string, len(notes))
+	for i, note := range notes {
+		texts[i] = note.Text
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Notification delivery statuses. A delivery is pending while it is being
+// attempted or waits for a retry.
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+)
+
+// defaultNotificationLimit caps GetNotifications when no limit is given
+const defaultNotificationLimit = 100
+
+var (
+	// ErrNotificationNotFound is returned for an unknown notification delivery
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrNotificationNotRetryable is returned when retrying a delivery that
+	// has not failed or whose channel is not configured
+	ErrNotificationNotRetryable = errors.New("notification cannot be retried")
+)
+
+// NotificationDelivery records the delivery of an event to a notification channel
+type NotificationDelivery struct {
+	ID          int64      `json:"id"`
+	EventID     int64      `json:"event_id"`
+	Channel     string     `json:"channel"` // notifier name: Slack, Alertmanager, Telegram or NATS
+	Status      string     `json:"status"`  // pending, delivered or failed
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// NotificationFilter narrows GetNotifications; empty fields match all
+type NotificationFilter struct {
+	Channel string
+	Status  string
+	Limit   int
+}
+
+// RecordNotification inserts a delivery and sets its ID
+func (s *Storage) RecordNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO notification_log (event_id, channel, status, attempts, last_error, created_at, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		delivery.EventID, delivery.Channel, delivery.Status, delivery.Attempts, delivery.LastError,
+		delivery.CreatedAt, utcTime(delivery.DeliveredAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record notification: %w", err)
+	}
+	delivery.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// UpdateNotification stores the status, attempts, last error and delivery
+// time of a recorded delivery
+func (s *Storage) UpdateNotification(ctx context.Context, delivery *NotificationDelivery) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notification_log SET status = ?, attempts = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.LastError, utcTime(delivery.DeliveredAt), delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+const notificationColumns = `id, event_id, channel, status, attempts, last_error, created_at, delivered_at`
+
+// GetNotifications returns recorded deliveries, newest first
+func (s *Storage) GetNotifications(ctx context.Context, filter NotificationFilter) ([]NotificationDelivery, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notification_log WHERE 1=1`
+	var args []interface{}
+	if filter.Channel != "" {
+		query += " AND channel = ?"
+		args = append(args, filter.Channel)
+	}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultNotificationLimit
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []NotificationDelivery{}
+	for rows.Next() {
+		delivery, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notifications: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetNotification returns a single delivery, or nil if it does not exist
+func (s *Storage) GetNotification(ctx context.Context, id int64) (*NotificationDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+notificationColumns+` FROM notification_log WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read notification: %w", err)
+		}
+		return nil, nil
+	}
+	return scanNotification(rows)
+}
+
+func scanNotification(rows *sql.Rows) (*NotificationDelivery, error) {
+	var delivery NotificationDelivery
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+	if err := rows.Scan(&delivery.ID, &delivery.EventID, &delivery.Channel, &delivery.Status,
+		&delivery.Attempts, &lastError, &delivery.CreatedAt, &deliveredAt); err != nil {
+		return nil, fmt.Errorf("failed to scan notification: %w", err)
+	}
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = utcTime(&deliveredAt.Time)
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,33 @@
+package storage
+
+// EventPublisher receives every event once it is stored, with its ID set.
+// Publish must not block; it is called by the writer.
+type EventPublisher interface {
+	Publish(event *ChangeEvent)
+}
+
+// SetPublisher publishes newly stored events to publisher, e.g. for live
+// streams. Coalesced repeats and duplicates are not published. Set it before
+// events are saved.
+func (s *Storage) SetPublisher(publisher EventPublisher) {
+	s.publisher = publisher
+}
+
+func (s *Storage) publish(event *ChangeEvent) {
+	if s.publisher != nil {
+		s.publisher.Publish(event)
+	}
+}
+
+// MultiPublisher publishes every event to all publishers in order
+func MultiPublisher(publishers ...EventPublisher) EventPublisher {
+	return multiPublisher(publishers)
+}
+
+type multiPublisher []EventPublisher
+
+func (m multiPublisher) Publish(event *ChangeEvent) {
+	for _, publisher := range m {
+		publisher.Publish(event)
+	}
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TrackedResource is one resource that has events, with the time span and
+// number of its events
+type TrackedResource struct {
+	Namespace  string    `json:"namespace"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	EventCount int64     `json:"event_count"`
+	LastAction string    `json:"last_action"`
+	Deleted    bool      `json:"deleted"` // the latest event is a deletion
+}
+
+// ResourceFilter selects the resources returned by GetResources
+type ResourceFilter struct {
+	Namespace string
+	Kind      string
+
+	// NamespacePatterns are globs, as in Filter.NamespacePatterns
+	NamespacePatterns []string
+
+	// Deleted keeps only resources whose latest event is (true) or is not
+	// (false) a deletion; nil matches all
+	Deleted *bool
+
+	Limit  int
+	Offset int
+}
+
+// resourcesQuery selects one row per resource matching filter. The grouping
+// is covered by idx_resource_timestamp; the latest action is looked up per
+// resource through the same index.
+func resourcesQuery(filter ResourceFilter) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if filter.Namespace != "" {
+		where += " AND namespace = ?"
+		args = append(args, filter.Namespace)
+	}
+	if filter.Kind != "" {
+		where += " AND kind = ?"
+		args = append(args, filter.Kind)
+	}
+	if len(filter.NamespacePatterns) > 0 {
+		clause, clauseArgs := namespaceGlobs(filter.NamespacePatterns)
+		where += clause
+		args = append(args, clauseArgs...)
+	}
+
+	// strftime also normalizes rows stored with a local offset to UTC
+	query := `
+		SELECT namespace, kind, name, first_seen, last_seen, event_count, last_action FROM (
+			SELECT r.namespace, r.kind, r.name, r.first_seen, r.last_seen, r.event_count,
+				(SELECT e.action FROM change_events e
+				 WHERE e.namespace = r.namespace AND e.kind = r.kind AND e.name = r.name
+				 ORDER BY e.timestamp DESC, e.id DESC LIMIT 1) AS last_action
+			FROM (
+				SELECT namespace, kind, name,
+					strftime('%Y-%m-%dT%H:%M:%SZ', MIN(timestamp)) AS first_seen,
+					strftime('%Y-%m-%dT%H:%M:%SZ', MAX(timestamp)) AS last_seen,
+					COUNT(*) AS event_count
+				FROM change_events` + where + `
+				GROUP BY namespace, kind, name
+			) r
+		)`
+	if filter.Deleted != nil {
+		if *filter.Deleted {
+			query += " WHERE last_action = 'DELETED'"
+		} else {
+			query += " WHERE last_action != 'DELETED'"
+		}
+	}
+	return query, args
+}
+
+// GetResources lists the distinct resources that have events, ordered by
+// namespace, kind and name. A limit of 0 returns all of them.
+func (s *Storage) GetResources(ctx context.Context, filter ResourceFilter) ([]TrackedResource, error) {
+	query, args := resourcesQuery(filter)
+	query += " ORDER BY namespace, kind, name"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resources: %w", err)
+	}
+	defer rows.Close()
+
+	resources := []TrackedResource{}
+	for rows.Next() {
+		var r TrackedResource
+		var firstSeen, lastSeen string
+		if err := rows.Scan(&r.Namespace, &r.Kind, &r.Name, &firstSeen, &lastSeen, &r.EventCount, &r.LastAction); err != nil {
+			return nil, fmt.Errorf("failed to scan resources: %w", err)
+		}
+		if r.FirstSeen, err = time.Parse(time.RFC3339, firstSeen); err != nil {
+			return nil, fmt.Errorf("failed to parse first seen time %q: %w", firstSeen, err)
+		}
+		if r.LastSeen, err = time.Parse(time.RFC3339, lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to parse last seen time %q: %w", lastSeen, err)
+		}
+		r.Deleted = r.LastAction == "DELETED"
+		resources = append(resources, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read resources: %w", err)
+	}
+	return resources, nil
+}
+
+// CountResources returns the number of resources GetResources would list
+// without a limit
+func (s *Storage) CountResources(ctx context.Context, filter ResourceFilter) (int64, error) {
+	query, args := resourcesQuery(filter)
+	var count int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+")", args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count resources: %w", err)
+	}
+	return count, nil
+}
+
+// trackedResources groups events into resources, applying filter. events
+// must be ordered newest first.
+func trackedResources(events []ChangeEvent, filter ResourceFilter) []TrackedResource {
+	type key struct{ namespace, kind, name string }
+	index := make(map[key]int)
+	resources := []TrackedResource{}
+	for i := range events {
+		e := &events[i]
+		if filter.Namespace != "" && e.Namespace != filter.Namespace {
+			continue
+		}
+		if filter.Kind != "" && e.Kind != filter.Kind {
+			continue
+		}
+		if len(filter.NamespacePatterns) > 0 && !MatchNamespace(filter.NamespacePatterns, e.Namespace) {
+			continue
+		}
+		k := key{e.Namespace, e.Kind, e.Name}
+		j, ok := index[k]
+		if !ok {
+			j = len(resources)
+			index[k] = j
+			resources = append(resources, TrackedResource{
+				Namespace:  e.Namespace,
+				Kind:       e.Kind,
+				Name:       e.Name,
+				LastSeen:   e.Timestamp,
+				LastAction: e.Action,
+				Deleted:    e.Action == "DELETED",
+			})
+		}
+		r := &resources[j]
+		r.EventCount++
+		r.FirstSeen = e.Timestamp
+	}
+
+	kept := resources[:0]
+	for _, r := range resources {
+		if filter.Deleted == nil || r.Deleted == *filter.Deleted {
+			kept = append(kept, r)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return kept
+}
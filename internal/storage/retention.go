@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// retentionCutoffs returns the deletion cutoff of each kind. Kinds listed in
+// overrides (matched case-insensitively) use their own number of days, all
+// others use defaultDays.
+func retentionCutoffs(kinds []string, overrides map[string]int, defaultDays int) map[string]time.Time {
+	now := time.Now().UTC()
+	cutoffs := make(map[string]time.Time, len(kinds))
+	for _, kind := range kinds {
+		days := defaultDays
+		if d, ok := overrides[strings.ToLower(kind)]; ok {
+			days = d
+		}
+		cutoffs[kind] = now.AddDate(0, 0, -days)
+	}
+	return cutoffs
+}
+
+// normalizeOverrides lowercases the kinds of a retention override map
+func normalizeOverrides(overrides map[string]int) map[string]int {
+	normalized := make(map[string]int, len(overrides))
+	for kind, days := range overrides {
+		normalized[strings.ToLower(kind)] = days
+	}
+	return normalized
+}
+
+// SumCounts adds up per-kind counts such as those returned by CleanupOldEvents
+func SumCounts(counts map[string]int64) int64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// SortFields are the fields GetEvents can order by. Only these names reach
+// the ORDER BY clause.
+var SortFields = []string{"timestamp", "id", "namespace", "kind", "name"}
+
+// ValidateSort checks an order_by/order pair. Empty values mean the default,
+// timestamp descending.
+func ValidateSort(orderBy, order string) error {
+	if orderBy != "" && !containsValue(SortFields, orderBy) {
+		return fmt.Errorf("unsupported order_by %q: must be one of %s", orderBy, strings.Join(SortFields, ", "))
+	}
+	if order != "" && order != SortAsc && order != SortDesc {
+		return fmt.Errorf("unsupported order %q: must be asc or desc", order)
+	}
+	return nil
+}
+
+// DefaultOrder reports whether the filter uses the newest-first ordering,
+// the only one cursors work with
+func (f Filter) DefaultOrder() bool {
+	return (f.OrderBy == "" || f.OrderBy == "timestamp") && f.Order != SortAsc
+}
+
+// orderClause returns the ORDER BY clause for a validated filter. Ties are
+// broken newest first so pages stay stable.
+func orderClause(filter Filter) string {
+	direction := "DESC"
+	if filter.Order == SortAsc {
+		direction = "ASC"
+	}
+	if filter.OrderBy == "" || filter.OrderBy == "timestamp" {
+		return " ORDER BY timestamp " + direction + ", id " + direction
+	}
+	if filter.OrderBy == "id" {
+		return " ORDER BY id " + direction // insertion order, walks the primary key
+	}
+	return " ORDER BY " + filter.OrderBy + " " + direction + ", timestamp DESC, id DESC"
+}
+
+// sortEvents orders newest-first events like orderClause
+func sortEvents(events []ChangeEvent, filter Filter) {
+	if filter.DefaultOrder() {
+		return
+	}
+	if filter.OrderBy == "" || filter.OrderBy == "timestamp" {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+		return
+	}
+	if filter.OrderBy == "id" {
+		sort.SliceStable(events, func(i, j int) bool {
+			if filter.Order == SortAsc {
+				return events[i].ID < events[j].ID
+			}
+			return events[i].ID > events[j].ID
+		})
+		return
+	}
+
+	field := func(e *ChangeEvent) string {
+		switch filter.OrderBy {
+		case "namespace":
+			return e.Namespace
+		case "kind":
+			return e.Kind
+		default:
+			return e.Name
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if filter.Order == SortAsc {
+			return field(&events[i]) < field(&events[j])
+		}
+		return field(&events[i]) > field(&events[j])
+	})
+}
@@ -1,142 +1,322 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
+// maxOpenConns bounds the connection pool of the SQLite database
+const maxOpenConns = 4
+
 type Storage struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
+
+	// Events are written by a single background writer (see writer.go)
+	queue      chan *ChangeEvent
+	flushReq   chan chan struct{}
+	writerDone chan struct{}
+	closeMu    sync.RWMutex
+	closed     bool
+
+	archiver           Archiver       // optional, see archive.go
+	publisher          EventPublisher // optional, see publish.go
+	retentionOverrides map[string]int // lowercased kind -> days
+	schemaVersion      int
+	ftsEnabled         bool
+
+	// Row count cap, see eviction.go
+	maxEvents atomic.Int64
+	rowCount  atomic.Int64
+	evictMu   sync.Mutex // serializes eviction and retention cleanup
+
+	// Vacuuming after cleanups, see dbstats.go
+	writeMu         sync.Mutex // held by the writer per batch and by vacuums
+	vacuumMu        sync.Mutex
+	vacuumMode      string
+	vacuumThreshold int64
+
+	coalesceWindow atomic.Int64 // see coalesce.go
+
+	// Deduplication of events stored twice, see dedup.go
+	dedupWindow  atomic.Int64
+	deduplicated atomic.Int64 // since start
+
+	// Compression of diffs stored before compression existed, see compress.go
+	stopBackground context.CancelFunc
+	backgroundDone chan struct{}
 }
 
-// NewStorage creates a new SQLite storage instance
-func NewStorage(dbPath string) (*Storage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewStorage creates a new SQLite storage instance using the given driver
+// (DriverCgo or DriverPureGo); an empty driver picks the first one compiled in.
+// A nil logger uses slog.Default().
+func NewStorage(dbPath, driver string, logger *slog.Logger) (*Storage, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db, err := openDB(dbPath, driver)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	storage := &Storage{db: db}
+	storage := &Storage{
+		db:         db,
+		logger:     logger,
+		queue:      make(chan *ChangeEvent, writeQueueSize),
+		flushReq:   make(chan chan struct{}),
+		writerDone: make(chan struct{}),
+	}
 	if err := storage.initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	go storage.runWriter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	storage.stopBackground = cancel
+	storage.backgroundDone = make(chan struct{})
+	go storage.compressStoredDiffs(ctx)
+
 	return storage, nil
 }
 
-// initialize creates the database schema
+// Migrate applies the pending schema migrations to the database at dbPath
+// and returns the resulting schema version, without starting the writer or
+// any background work. It suits init containers that upgrade the schema
+// before the new version starts.
+func Migrate(dbPath, driver string, logger *slog.Logger) (int, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db, err := openDB(dbPath, driver)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	storage := &Storage{db: db, logger: logger}
+	version, err := storage.migrate()
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return version, nil
+}
+
+// openDB opens the database at dbPath with the given driver (see NewStorage)
+func openDB(dbPath, driver string) (*sql.DB, error) {
+	driver, dsn, err := resolveDriver(driver, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite serializes writers anyway; a small pool lets WAL readers run
+	// alongside the writer without piling up connections
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxOpenConns)
+	return db, nil
+}
+
+// initialize brings the database schema up to date
 func (s *Storage) initialize() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS change_events (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		timestamp DATETIME NOT NULL,
-		namespace TEXT NOT NULL,
-		kind TEXT NOT NULL,
-		name TEXT NOT NULL,
-		action TEXT NOT NULL,
-		diff TEXT,
-		metadata TEXT,
-		image_before TEXT,
-		image_after TEXT
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_timestamp ON change_events(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_namespace ON change_events(namespace);
-	CREATE INDEX IF NOT EXISTS idx_kind ON change_events(kind);
-	CREATE INDEX IF NOT EXISTS idx_name ON change_events(name);
-	CREATE INDEX IF NOT EXISTS idx_action ON change_events(action);
-	
-	-- Composite indexes for common queries
-	CREATE INDEX IF NOT EXISTS idx_namespace_kind_name ON change_events(namespace, kind, name);
-	CREATE INDEX IF NOT EXISTS idx_kind_timestamp ON change_events(kind, timestamp DESC);
-	CREATE INDEX IF NOT EXISTS idx_namespace_timestamp ON change_events(namespace, timestamp DESC);
-	`
-	_, err := s.db.Exec(schema)
-	return err
-}
-
-// CleanupOldEvents removes events older than the specified number of days
-func (s *Storage) CleanupOldEvents(retentionDays int) (int64, error) {
-	cutoffDate := time.Now().AddDate(0, 0, -retentionDays)
-	result, err := s.db.Exec("DELETE FROM change_events WHERE timestamp < ?", cutoffDate)
+	version, err := s.migrate()
 	if err != nil {
-		return 0, fmt.Errorf("failed to cleanup old events: %w", err)
+		return err
 	}
-	deleted, _ := result.RowsAffected()
+	s.schemaVersion = version
+	s.logger.Info("Database schema ready", slog.Int("version", version))
+
+	s.initFullText()
+	return nil
+}
+
+// SchemaVersion returns the schema migration version of the database
+func (s *Storage) SchemaVersion() int {
+	return s.schemaVersion
+}
+
+// SetRetentionOverrides sets per-kind retention periods in days that take
+// precedence over the retention passed to CleanupOldEvents
+func (s *Storage) SetRetentionOverrides(overrides map[string]int) {
+	s.retentionOverrides = normalizeOverrides(overrides)
+}
+
+// CleanupOldEvents removes events older than their kind's retention period
+// (retentionDays unless overridden) and returns the deleted count per kind.
+// With an archiver set, the events are archived first.
+func (s *Storage) CleanupOldEvents(ctx context.Context, retentionDays int) (map[string]int64, error) {
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	kinds, err := s.distinctKinds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoffs := retentionCutoffs(kinds, s.retentionOverrides, retentionDays)
+
+	if s.archiver != nil {
+		if err := s.archiveExpired(ctx, cutoffs); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := make(map[string]int64)
+	for _, kind := range kinds {
+		result, err := s.db.ExecContext(ctx, "DELETE FROM change_events WHERE kind = ? AND timestamp < ?", kind, cutoffs[kind])
+		if err != nil {
+			return deleted, fmt.Errorf("failed to cleanup old %s events: %w", kind, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			deleted[kind] = n
+		}
+	}
+
+	total := SumCounts(deleted)
+	s.rowCount.Add(-total)
+
+	if err := s.removeOrphans(ctx); err != nil {
+		return deleted, err
+	}
+	s.vacuumAfter(ctx, total)
 	return deleted, nil
 }
 
-// GetTotalCount returns total count of events matching filter
-func (s *Storage) GetTotalCount(filter Filter) (int64, error) {
-	query := `SELECT COUNT(*) FROM change_events WHERE 1=1`
-	args := []interface{}{}
+// removeOrphans drops the full-text index entries, snapshots, annotations and
+// notification log of deleted events
+func (s *Storage) removeOrphans(ctx context.Context) error {
+	if err := s.pruneFullText(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM event_snapshots WHERE event_id NOT IN (SELECT id FROM change_events)"); err != nil {
+		return fmt.Errorf("failed to cleanup orphaned snapshots: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM event_annotations WHERE event_id NOT IN (SELECT id FROM change_events)"); err != nil {
+		return fmt.Errorf("failed to cleanup orphaned annotations: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM notification_log WHERE event_id NOT IN (SELECT id FROM change_events)"); err != nil {
+		return fmt.Errorf("failed to cleanup orphaned notification log: %w", err)
+	}
+	return nil
+}
 
-	if filter.Namespace != "" {
-		query += " AND namespace = ?"
-		args = append(args, filter.Namespace)
+// distinctKinds returns every kind that has stored events
+func (s *Storage) distinctKinds(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT kind FROM change_events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kinds: %w", err)
 	}
-	if filter.Kind != "" {
-		query += " AND kind = ?"
-		args = append(args, filter.Kind)
+	defer rows.Close()
+
+	var kinds []string
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			return nil, fmt.Errorf("failed to scan kind: %w", err)
+		}
+		kinds = append(kinds, kind)
 	}
-	if filter.Name != "" {
-		query += " AND name LIKE ?"
-		args = append(args, "%"+filter.Name+"%")
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read kinds: %w", err)
 	}
-	if filter.Action != "" {
-		query += " AND action = ?"
-		args = append(args, filter.Action)
+	return kinds, nil
+}
+
+// CleanupOldSnapshots removes snapshots older than the specified number of days.
+// Snapshots usually have a shorter retention than the events they belong to.
+func (s *Storage) CleanupOldSnapshots(ctx context.Context, retentionDays int) (int64, error) {
+	cutoffDate := time.Now().UTC().AddDate(0, 0, -retentionDays)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM event_snapshots WHERE created_at < ?", cutoffDate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup old snapshots: %w", err)
 	}
-	if !filter.StartTime.IsZero() {
-		query += " AND timestamp >= ?"
-		args = append(args, filter.StartTime)
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// inClause builds " AND column [NOT] IN (?, ...)" for a list of values,
+// or nothing if the list is empty
+func inClause(column string, values []string, negate bool) (string, []interface{}) {
+	if len(values) == 0 {
+		return "", nil
 	}
-	if !filter.EndTime.IsZero() {
-		query += " AND timestamp <= ?"
-		args = append(args, filter.EndTime)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
 	}
 
-	var count int64
-	err := s.db.QueryRow(query, args...).Scan(&count)
-	return count, err
+	op := "IN"
+	if negate {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf(" AND %s %s (%s)", column, op, placeholders), args
 }
 
-// SaveEvent saves a change event to the database
-func (s *Storage) SaveEvent(event *ChangeEvent) error {
-	query := `
-		INSERT INTO change_events (timestamp, namespace, kind, name, action, diff, metadata, image_before, image_after)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	result, err := s.db.Exec(query,
-		event.Timestamp,
-		event.Namespace,
-		event.Kind,
-		event.Name,
-		event.Action,
-		event.Diff,
-		event.Metadata,
-		event.ImageBefore,
-		event.ImageAfter,
+// SaveSnapshot stores the before/after documents for an event
+func (s *Storage) SaveSnapshot(ctx context.Context, snapshot *EventSnapshot) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO event_snapshots (event_id, created_at, before_json, after_json) VALUES (?, ?, ?, ?)`,
+		snapshot.EventID,
+		snapshot.CreatedAt.UTC(),
+		snapshot.Before,
+		snapshot.After,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to save event: %w", err)
+		return fmt.Errorf("failed to save snapshot: %w", err)
 	}
+	return nil
+}
 
-	id, err := result.LastInsertId()
-	if err == nil {
-		event.ID = id
+// GetSnapshot returns the snapshot for an event, or nil if none was stored
+func (s *Storage) GetSnapshot(ctx context.Context, eventID int64) (*EventSnapshot, error) {
+	snapshot := &EventSnapshot{EventID: eventID}
+	var before, after sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT created_at, before_json, after_json FROM event_snapshots WHERE event_id = ?`,
+		eventID,
+	).Scan(&snapshot.CreatedAt, &before, &after)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot: %w", err)
+	}
+	snapshot.CreatedAt = snapshot.CreatedAt.UTC()
+	snapshot.Before = before.String
+	snapshot.After = after.String
+	return snapshot, nil
+}
 
-	return nil
+// GetTotalCount returns total count of events matching filter
+func (s *Storage) GetTotalCount(ctx context.Context, filter Filter) (int64, error) {
+	if filter.Query != "" && !s.ftsEnabled {
+		return 0, ErrFullTextUnavailable
+	}
+	where, args := buildWhere(filter)
+	query := `SELECT COUNT(*) FROM change_events` + where
+
+	var count int64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
 }
 
-// GetEvents retrieves events with filters
-func (s *Storage) GetEvents(filter Filter) ([]ChangeEvent, error) {
-	query := `SELECT id, timestamp, namespace, kind, name, action, diff, metadata, image_before, image_after
-	          FROM change_events WHERE 1=1`
+// buildWhere builds the WHERE clause shared by event listing and counting
+func buildWhere(filter Filter) (string, []interface{}) {
+	query := ` WHERE 1=1`
 	args := []interface{}{}
 
 	if filter.Namespace != "" {
@@ -155,16 +335,116 @@ func (s *Storage) GetEvents(filter Filter) ([]ChangeEvent, error) {
 		query += " AND action = ?"
 		args = append(args, filter.Action)
 	}
+	if filter.Author != "" {
+		query += " AND author = ?"
+		args = append(args, filter.Author)
+	}
+	if filter.UID != "" {
+		query += " AND uid = ?"
+		args = append(args, filter.UID)
+	}
+	if filter.App != "" {
+		query += " AND app = ?"
+		args = append(args, filter.App)
+	}
+	if filter.Tag != "" {
+		query += " AND EXISTS (SELECT 1 FROM json_each(change_events.tags) WHERE value = ?)"
+		args = append(args, filter.Tag)
+	}
+	if filter.Acknowledged != nil {
+		clause := " AND EXISTS (SELECT 1 FROM event_annotations WHERE event_id = change_events.id AND acknowledged)"
+		if !*filter.Acknowledged {
+			clause = strings.Replace(clause, "EXISTS", "NOT EXISTS", 1)
+		}
+		query += clause
+	}
+	if filter.Query != "" {
+		query += " AND id IN (SELECT rowid FROM change_events_fts WHERE change_events_fts MATCH ?)"
+		args = append(args, ftsQuery(filter.Query))
+	}
+	if filter.Source != "" {
+		if filter.Source == "unknown" {
+			// Events recorded before source detection have no source
+			query += " AND (source = ? OR source IS NULL OR source = '')"
+		} else {
+			query += " AND source = ?"
+		}
+		args = append(args, filter.Source)
+	}
+	if filter.Severity != "" {
+		if filter.Severity == SeverityInfo {
+			// Events recorded before severities existed have none
+			query += " AND (severity = ? OR severity IS NULL OR severity = '')"
+		} else {
+			query += " AND severity = ?"
+		}
+		args = append(args, filter.Severity)
+	}
+	for _, list := range []struct {
+		column string
+		values []string
+		negate bool
+	}{
+		{"namespace", filter.Namespaces, false},
+		{"kind", filter.Kinds, false},
+		{"action", filter.Actions, false},
+		{"namespace", filter.ExcludeNamespaces, true},
+		{"kind", filter.ExcludeKinds, true},
+		{"action", filter.ExcludeActions, true},
+	} {
+		clause, clauseArgs := inClause(list.column, list.values, list.negate)
+		query += clause
+		args = append(args, clauseArgs...)
+	}
+	if len(filter.NamespacePatterns) > 0 {
+		clause, clauseArgs := namespaceGlobs(filter.NamespacePatterns)
+		query += clause
+		args = append(args, clauseArgs...)
+	}
 	if !filter.StartTime.IsZero() {
 		query += " AND timestamp >= ?"
-		args = append(args, filter.StartTime)
+		args = append(args, filter.StartTime.UTC())
 	}
 	if !filter.EndTime.IsZero() {
 		query += " AND timestamp <= ?"
-		args = append(args, filter.EndTime)
+		args = append(args, filter.EndTime.UTC())
+	}
+	if filter.SinceID > 0 {
+		query += " AND id > ?"
+		args = append(args, filter.SinceID)
 	}
 
-	query += " ORDER BY timestamp DESC"
+	return query, args
+}
+
+// GetEvents retrieves events with filters
+func (s *Storage) GetEvents(ctx context.Context, filter Filter) ([]ChangeEvent, error) {
+	if filter.Query != "" && !s.ftsEnabled {
+		return nil, ErrFullTextUnavailable
+	}
+	if err := ValidateSort(filter.OrderBy, filter.Order); err != nil {
+		return nil, err
+	}
+	where, args := buildWhere(filter)
+	if filter.Before != nil {
+		cond, condArgs := cursorCondition(filter.Before, "<")
+		where += cond
+		args = append(args, condArgs...)
+	}
+	if filter.After != nil {
+		cond, condArgs := cursorCondition(filter.After, ">")
+		where += cond
+		args = append(args, condArgs...)
+	}
+	query := `SELECT ` + eventColumns + ` FROM change_events` + where
+
+	// Paging forward from After walks towards newer events; the page is
+	// reversed below to keep the newest-first order
+	if filter.After != nil && filter.Before == nil {
+		query += " ORDER BY timestamp ASC, id ASC"
+	} else {
+		query += orderClause(filter)
+	}
 
 	if filter.Limit > 0 {
 		query += " LIMIT ?"
@@ -175,7 +455,7 @@ func (s *Storage) GetEvents(filter Filter) ([]ChangeEvent, error) {
 		args = append(args, filter.Offset)
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
@@ -183,169 +463,564 @@ func (s *Storage) GetEvents(filter Filter) ([]ChangeEvent, error) {
 
 	var events []ChangeEvent
 	for rows.Next() {
-		var event ChangeEvent
-		var imageBefore, imageAfter sql.NullString
-		err := rows.Scan(
-			&event.ID,
-			&event.Timestamp,
-			&event.Namespace,
-			&event.Kind,
-			&event.Name,
-			&event.Action,
-			&event.Diff,
-			&event.Metadata,
-			&imageBefore,
-			&imageAfter,
-		)
+		event, err := scanEvent(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan events: %w", err)
 		}
-		if imageBefore.Valid {
-			event.ImageBefore = imageBefore.String
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	if filter.After != nil && filter.Before == nil {
+		slices.Reverse(events)
+	}
+
+	return events, nil
+}
+
+// StreamEvents calls fn for each event matching the filter, in the filter's
+// order, reading rows from the database as they are needed. Cursors and the
+// offset are ignored. The read stays open until fn has seen every event or fails.
+func (s *Storage) StreamEvents(ctx context.Context, filter Filter, fn func(*ChangeEvent) error) error {
+	if filter.Query != "" && !s.ftsEnabled {
+		return ErrFullTextUnavailable
+	}
+	if err := ValidateSort(filter.OrderBy, filter.Order); err != nil {
+		return err
+	}
+	where, args := buildWhere(filter)
+	query := `SELECT ` + eventColumns + ` FROM change_events` + where + orderClause(filter)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan events: %w", err)
 		}
-		if imageAfter.Valid {
-			event.ImageAfter = imageAfter.String
+		if err := fn(event); err != nil {
+			return err
 		}
-		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return nil
+}
+
+// SearchEvents returns events whose name, namespace, diff or metadata contain the
+// search text (case-insensitive), ranked exact name match > name contains >
+// diff contains > other matches, newest first within a rank. Diffs stored
+// compressed (see compress.go) can't be matched with LIKE; those events are
+// decompressed and matched by searchCompressed, then merged into the ranking.
+func (s *Storage) SearchEvents(ctx context.Context, text string, filter Filter) ([]ChangeEvent, error) {
+	if filter.Query != "" && !s.ftsEnabled {
+		return nil, ErrFullTextUnavailable
+	}
+	pattern := "%" + escapeLike(text) + "%"
+
+	where, args := buildWhere(filter)
+	plainWhere := where + ` AND diff_compressed IS NULL AND (name LIKE ? ESCAPE '\' OR namespace LIKE ? ESCAPE '\' OR diff LIKE ? ESCAPE '\' OR metadata LIKE ? ESCAPE '\')`
+	plainArgs := append(slices.Clone(args), pattern, pattern, pattern, pattern)
+
+	query := `SELECT ` + eventColumns + ` FROM change_events` + plainWhere + `
+		ORDER BY CASE
+			WHEN lower(name) = lower(?) THEN 0
+			WHEN name LIKE ? ESCAPE '\' THEN 1
+			WHEN diff LIKE ? ESCAPE '\' THEN 2
+			ELSE 3
+		END, timestamp DESC, id DESC`
+	plainArgs = append(plainArgs, text, pattern, pattern)
+
+	// The page is cut after merging, so the plain query only needs the
+	// events up to its end
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		plainArgs = append(plainArgs, filter.Offset+filter.Limit)
+	}
+
+	events, err := s.queryEvents(ctx, query, plainArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	compressed, err := s.searchCompressed(ctx, text, where, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) > 0 {
+		events = append(events, compressed...)
+		sort.SliceStable(events, func(i, j int) bool {
+			ri, rj := searchRank(&events[i], text), searchRank(&events[j], text)
+			if ri != rj {
+				return ri < rj
+			}
+			if !events[i].Timestamp.Equal(events[j].Timestamp) {
+				return events[i].Timestamp.After(events[j].Timestamp)
+			}
+			return events[i].ID > events[j].ID
+		})
 	}
 
+	if filter.Offset > 0 {
+		if filter.Offset >= len(events) {
+			return nil, nil
+		}
+		events = events[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(events) {
+		events = events[:filter.Limit]
+	}
 	return events, nil
 }
 
-// GetStats retrieves dashboard statistics
-func (s *Storage) GetStats() (*Stats, error) {
-	stats := &Stats{
-		ChangesByKind:   make(map[string]int64),
-		ChangesByAction: make(map[string]int64),
+// searchCompressed returns the events matching where whose diff is stored
+// compressed and which contain text. Only diffs above compressDiffThreshold
+// are compressed, so there are few of them.
+func (s *Storage) searchCompressed(ctx context.Context, text, where string, args []interface{}) ([]ChangeEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM change_events`+where+` AND diff_compressed IS NOT NULL`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search compressed diffs: %w", err)
 	}
+	defer rows.Close()
 
-	// Total changes
-	err := s.db.QueryRow("SELECT COUNT(*) FROM change_events").Scan(&stats.TotalChanges)
+	var events []ChangeEvent
+	for rows.Next() {
+		event, err := scanEvent(rows) // decompresses the diff
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search results: %w", err)
+		}
+		if searchMatches(event, text) {
+			events = append(events, *event)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+	return events, nil
+}
+
+// queryEvents runs a query selecting eventColumns and scans the events
+func (s *Storage) queryEvents(ctx context.Context, query string, args ...interface{}) ([]ChangeEvent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var events []ChangeEvent
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+// searchMatches reports whether the name, namespace, diff or metadata of an
+// event contain text, ignoring case
+func searchMatches(event *ChangeEvent, text string) bool {
+	return containsFold(event.Name, text) || containsFold(event.Namespace, text) ||
+		containsFold(event.Diff, text) || containsFold(event.Metadata, text)
+}
+
+// searchRank ranks a search result like the ORDER BY of SearchEvents: exact
+// name match, name contains, diff contains, other matches
+func searchRank(event *ChangeEvent, text string) int {
+	switch {
+	case strings.EqualFold(event.Name, text):
+		return 0
+	case containsFold(event.Name, text):
+		return 1
+	case containsFold(event.Diff, text):
+		return 2
+	default:
+		return 3
+	}
+}
+
+func containsFold(value, text string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(text))
+}
+
+// escapeLike escapes LIKE wildcards so the text is matched literally
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
+
+// GetEventByID returns a single event with its annotations, or nil if it does
+// not exist
+func (s *Storage) GetEventByID(ctx context.Context, id int64) (*ChangeEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+eventColumns+` FROM change_events WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read event: %w", err)
+		}
+		return nil, nil
+	}
+	event, err := scanEvent(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan event: %w", err)
+	}
+	rows.Close()
+
+	annotations, err := s.GetAnnotations(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	event.Annotations = annotations[id]
+	return event, nil
+}
+
+// DeleteEvent removes an event with its snapshot, annotations, notification
+// log and full-text index entry. It returns ErrEventNotFound if the event does
+// not exist.
+func (s *Storage) DeleteEvent(ctx context.Context, id int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	// Changes in last 24h
-	last24h := time.Now().Add(-24 * time.Hour)
-	err = s.db.QueryRow("SELECT COUNT(*) FROM change_events WHERE timestamp >= ?", last24h).Scan(&stats.ChangesLast24h)
+	result, err := tx.ExecContext(ctx, "DELETE FROM change_events WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEventNotFound
+	}
+
+	related := []string{
+		"DELETE FROM event_snapshots WHERE event_id = ?",
+		"DELETE FROM event_annotations WHERE event_id = ?",
+		"DELETE FROM notification_log WHERE event_id = ?",
+	}
+	if s.ftsEnabled {
+		related = append(related, "DELETE FROM change_events_fts WHERE rowid = ?")
+	}
+	for _, query := range related {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete event data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.rowCount.Add(-1)
+	return nil
+}
+
+// eventColumns lists the change_events columns in the order scanEvent expects
+const eventColumns = `id, timestamp, namespace, kind, name, action, diff, diff_compressed, metadata, image_before, image_after, author, uid, resource_version, actor, source, severity, tags, repeat_count, last_seen, changeset_id, notes, app`
+
+// scanEvent reads a single change event from the current row
+func scanEvent(rows *sql.Rows) (*ChangeEvent, error) {
+	var event ChangeEvent
+	var imageBefore, imageAfter, author, uid, resourceVersion, actor, source, severity, tags, changesetID, notes, app sql.NullString
+	var lastSeen sql.NullTime
+	var diffCompressed []byte
+	err := rows.Scan(
+		&event.ID,
+		&event.Timestamp,
+		&event.Namespace,
+		&event.Kind,
+		&event.Name,
+		&event.Action,
+		&event.Diff,
+		&diffCompressed,
+		&event.Metadata,
+		&imageBefore,
+		&imageAfter,
+		&author,
+		&uid,
+		&resourceVersion,
+		&actor,
+		&source,
+		&severity,
+		&tags,
+		&event.RepeatCount,
+		&lastSeen,
+		&changesetID,
+		&notes,
+		&app,
+	)
 	if err != nil {
 		return nil, err
 	}
+	if diffCompressed != nil {
+		if event.Diff, err = decompressDiff(diffCompressed); err != nil {
+			return nil, err
+		}
+	}
+	event.Timestamp = event.Timestamp.UTC()
+	event.ImageBefore = imageBefore.String
+	event.ImageAfter = imageAfter.String
+	event.Author = author.String
+	event.UID = uid.String
+	event.ResourceVersion = resourceVersion.String
+	event.Actor = actor.String
+	event.Source = source.String
+	event.Severity = severity.String
+	event.Tags = parseTags(tags)
+	event.ChangesetID = changesetID.String
+	event.Notes = parseNotes(notes)
+	event.App = app.String
+	if lastSeen.Valid {
+		t := lastSeen.Time.UTC()
+		event.LastSeen = &t
+	}
+	return &event, nil
+}
+
+// GetStats retrieves dashboard statistics over the events matching filter
+func (s *Storage) GetStats(ctx context.Context, filter StatsFilter) (*Stats, error) {
+	window := filter.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+	scope, scopeArgs := namespaceGlobs(filter.NamespacePatterns)
+	stats := &Stats{
+		Window:             FormatWindow(window),
+		SchemaVersion:      s.schemaVersion,
+		ChangesByKind:      make(map[string]int64),
+		ChangesByAction:    make(map[string]int64),
+		ChangesByNamespace: make(map[string]int64),
+		ChangesBySource:    make(map[string]int64),
+		ChangesBySeverity:  make(map[string]int64),
+	}
 
-	stats.ChangesPerHour = float64(stats.ChangesLast24h) / 24.0
+	// Total changes and changes in the window
+	since := time.Now().UTC().Add(-window)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(timestamp >= ?), 0) FROM change_events WHERE 1=1`+scope,
+		append([]interface{}{since}, scopeArgs...)...,
+	).Scan(&stats.TotalChanges, &stats.ChangesLastWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+	stats.RowCount = stats.TotalChanges
+	stats.MaxEvents = s.maxEvents.Load()
+	stats.DeduplicatedEvents = s.deduplicated.Load()
+	stats.ChangesPerHour = float64(stats.ChangesLastWindow) / window.Hours()
 
 	// Top modified apps
-	rows, err := s.db.Query(`
-		SELECT name, COUNT(*) as count 
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT namespace, kind, name, COUNT(*) as count 
 		FROM change_events 
-		WHERE timestamp >= ? 
-		GROUP BY name 
-		ORDER BY count DESC 
+		WHERE timestamp >= ?`+scope+`
+		GROUP BY namespace, kind, name 
+		ORDER BY count DESC, namespace, kind, name 
 		LIMIT 10
-	`, last24h)
+	`, append([]interface{}{since}, scopeArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query top modified apps: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var app AppChangeCount
-		rows.Scan(&app.Name, &app.Count)
+		if err := rows.Scan(&app.Namespace, &app.Kind, &app.Name, &app.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top modified apps: %w", err)
+		}
 		stats.TopModifiedApps = append(stats.TopModifiedApps, app)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top modified apps: %w", err)
+	}
+
+	if stats.TopNamespaces, err = s.topChangedNamespaces(ctx, since, filter.NamespacePatterns, statsTopNamespaces); err != nil {
+		return nil, err
+	}
 
 	// Recent images
-	imageRows, err := s.db.Query(`
+	imageRows, err := s.db.QueryContext(ctx, `
 		SELECT DISTINCT image_after 
 		FROM change_events 
-		WHERE image_after IS NOT NULL AND image_after != '' 
+		WHERE image_after IS NOT NULL AND image_after != '' AND timestamp >= ?`+scope+`
 		ORDER BY timestamp DESC 
 		LIMIT 10
-	`)
+	`, append([]interface{}{since}, scopeArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query recent images: %w", err)
 	}
 	defer imageRows.Close()
 	for imageRows.Next() {
 		var image string
-		imageRows.Scan(&image)
+		if err := imageRows.Scan(&image); err != nil {
+			return nil, fmt.Errorf("failed to scan recent images: %w", err)
+		}
 		stats.RecentImages = append(stats.RecentImages, image)
 	}
-
-	// Changes by kind
-	kindRows, err := s.db.Query("SELECT kind, COUNT(*) FROM change_events GROUP BY kind")
-	if err != nil {
-		return nil, err
-	}
-	defer kindRows.Close()
-	for kindRows.Next() {
-		var kind string
-		var count int64
-		kindRows.Scan(&kind, &count)
-		stats.ChangesByKind[kind] = count
+	if err := imageRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recent images: %w", err)
 	}
 
-	// Changes by action
-	actionRows, err := s.db.Query("SELECT action, COUNT(*) FROM change_events GROUP BY action")
+	// Changes by kind, action, namespace, source and severity in one query.
+	// Events without a source count as unknown, events without a severity as info.
+	breakdowns := map[string]map[string]int64{
+		"kind":      stats.ChangesByKind,
+		"action":    stats.ChangesByAction,
+		"namespace": stats.ChangesByNamespace,
+		"source":    stats.ChangesBySource,
+		"severity":  stats.ChangesBySeverity,
+	}
+	where := ` WHERE 1=1` + scope
+	breakdownArgs := slices.Concat(scopeArgs, scopeArgs, scopeArgs, scopeArgs, []interface{}{SeverityInfo}, scopeArgs)
+	breakdownRows, err := s.db.QueryContext(ctx, `
+		SELECT 'kind', kind, COUNT(*) FROM change_events`+where+` GROUP BY 2
+		UNION ALL
+		SELECT 'action', action, COUNT(*) FROM change_events`+where+` GROUP BY 2
+		UNION ALL
+		SELECT 'namespace', namespace, COUNT(*) FROM change_events`+where+` GROUP BY 2
+		UNION ALL
+		SELECT 'source', COALESCE(NULLIF(source, ''), 'unknown'), COUNT(*) FROM change_events`+where+` GROUP BY 2
+		UNION ALL
+		SELECT 'severity', COALESCE(NULLIF(severity, ''), ?), COUNT(*) FROM change_events`+where+` GROUP BY 2`, breakdownArgs...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query change breakdowns: %w", err)
 	}
-	defer actionRows.Close()
-	for actionRows.Next() {
-		var action string
+	defer breakdownRows.Close()
+	for breakdownRows.Next() {
+		var breakdown, key string
 		var count int64
-		actionRows.Scan(&action, &count)
-		stats.ChangesByAction[action] = count
+		if err := breakdownRows.Scan(&breakdown, &key, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan change breakdowns: %w", err)
+		}
+		breakdowns[breakdown][key] = count
+	}
+	if err := breakdownRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read change breakdowns: %w", err)
 	}
 
 	return stats, nil
 }
 
-// GetTimeline retrieves timeline for a specific resource
-func (s *Storage) GetTimeline(namespace, kind, name string) ([]ChangeEvent, error) {
-	query := `
-		SELECT id, timestamp, namespace, kind, name, action, diff, metadata, image_before, image_after
-		FROM change_events 
-		WHERE namespace = ? AND kind = ? AND name = ?
-		ORDER BY timestamp DESC
-	`
-	rows, err := s.db.Query(query, namespace, kind, name)
+// GetNamespaceStats summarizes the changes in a namespace: totals, breakdowns
+// by kind and action, the most changed resources of the last 24h and the 10
+// most recent image changes
+func (s *Storage) GetNamespaceStats(ctx context.Context, namespace string) (*NamespaceStats, error) {
+	stats := &NamespaceStats{
+		Namespace:          namespace,
+		ChangesByKind:      make(map[string]int64),
+		ChangesByAction:    make(map[string]int64),
+		TopResources:       []ResourceChangeCount{},
+		RecentImageChanges: []ImageChange{},
+	}
+
+	since := time.Now().UTC().Add(-namespaceStatsWindow)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(timestamp >= ?), 0)
+		FROM change_events WHERE namespace = ?`, since, namespace,
+	).Scan(&stats.TotalChanges, &stats.ChangesLast24h)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query timeline: %w", err)
+		return nil, fmt.Errorf("failed to count namespace events: %w", err)
 	}
-	defer rows.Close()
 
-	var events []ChangeEvent
-	for rows.Next() {
-		var event ChangeEvent
-		var imageBefore, imageAfter sql.NullString
-		err := rows.Scan(
-			&event.ID,
-			&event.Timestamp,
-			&event.Namespace,
-			&event.Kind,
-			&event.Name,
-			&event.Action,
-			&event.Diff,
-			&event.Metadata,
-			&imageBefore,
-			&imageAfter,
-		)
+	for column, counts := range map[string]map[string]int64{"kind": stats.ChangesByKind, "action": stats.ChangesByAction} {
+		rows, err := s.db.QueryContext(ctx, `SELECT `+column+`, COUNT(*) FROM change_events WHERE namespace = ? GROUP BY 1`, namespace)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to count namespace events by %s: %w", column, err)
 		}
-		if imageBefore.Valid {
-			event.ImageBefore = imageBefore.String
+		for rows.Next() {
+			var key string
+			var count int64
+			if err := rows.Scan(&key, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan namespace events by %s: %w", column, err)
+			}
+			counts[key] = count
 		}
-		if imageAfter.Valid {
-			event.ImageAfter = imageAfter.String
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read namespace events by %s: %w", column, err)
 		}
-		events = append(events, event)
 	}
 
-	return events, nil
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT kind, name, COUNT(*) AS count
+		FROM change_events
+		WHERE namespace = ? AND timestamp >= ?
+		GROUP BY kind, name
+		ORDER BY count DESC, kind, name
+		LIMIT 10`, namespace, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top resources: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r ResourceChangeCount
+		if err := rows.Scan(&r.Kind, &r.Name, &r.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top resources: %w", err)
+		}
+		stats.TopResources = append(stats.TopResources, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top resources: %w", err)
+	}
+
+	imageRows, err := s.db.QueryContext(ctx, `
+		SELECT id, timestamp, kind, name, image_before, image_after
+		FROM change_events
+		WHERE namespace = ? AND `+imageChangeWhere+`
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 10`, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image changes: %w", err)
+	}
+	defer imageRows.Close()
+	for imageRows.Next() {
+		var c ImageChange
+		var imageBefore sql.NullString
+		if err := imageRows.Scan(&c.EventID, &c.Timestamp, &c.Kind, &c.Name, &imageBefore, &c.ImageAfter); err != nil {
+			return nil, fmt.Errorf("failed to scan image changes: %w", err)
+		}
+		c.Timestamp = c.Timestamp.UTC()
+		c.ImageBefore = imageBefore.String
+		stats.RecentImageChanges = append(stats.RecentImageChanges, c)
+	}
+	if err := imageRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image changes: %w", err)
+	}
+	return stats, nil
 }
 
-// Close closes the database connection
+// GetTimeline returns the events of one resource with their annotations,
+// newest first. filter narrows and pages the result; its namespace, kind and
+// name are ignored.
+func (s *Storage) GetTimeline(ctx context.Context, namespace, kind, name string, filter Filter) ([]ChangeEvent, error) {
+	events, err := s.GetEvents(ctx, timelineFilter(namespace, kind, name, filter))
+	if err != nil {
+		return nil, err
+	}
+	return events, attachAnnotations(ctx, s, events)
+}
+
+// Close writes any queued events and closes the database
 func (s *Storage) Close() error {
+	s.closeMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.closeMu.Unlock()
+
+	s.stopBackground()
+	<-s.backgroundDone
+	<-s.writerDone
 	return s.db.Close()
 }
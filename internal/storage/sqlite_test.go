@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStorage opens a SQLite database in a temporary directory
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := NewStorage(filepath.Join(t.TempDir(), "events.db"), "", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestTimestampsInUTC(t *testing.T) {
+	// Write from a host east of UTC and query with bounds west of it
+	local := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	ctx := context.Background()
+	s := newTestStorage(t)
+	base := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"first", "second", "third"} {
+		event := &ChangeEvent{
+			Timestamp: base.Add(time.Duration(i) * time.Hour).In(time.Local),
+			Namespace: "default",
+			Kind:      "ConfigMap",
+			Name:      name,
+			Action:    "MODIFIED",
+		}
+		if err := s.SaveEventSync(ctx, event); err != nil {
+			t.Fatalf("SaveEventSync: %v", err)
+		}
+	}
+
+	var stored []string
+	rows, err := s.db.QueryContext(ctx, "SELECT CAST(timestamp AS TEXT) FROM change_events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var ts string
+		if err := rows.Scan(&ts); err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, ts)
+	}
+	rows.Close()
+	if !strings.HasPrefix(stored[0], "2026-01-05 12:00:00") {
+		t.Errorf("stored timestamp = %q, want 12:00 UTC", stored[0])
+	}
+
+	west := time.FixedZone("UTC-5", -5*60*60)
+	events, err := s.GetEvents(ctx, Filter{
+		StartTime: base.Add(30 * time.Minute).In(west),
+		EndTime:   base.Add(90 * time.Minute).In(time.Local),
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Name != "second" {
+		t.Fatalf("GetEvents between 12:30 and 13:30 UTC = %v, want only the second event", events)
+	}
+	if events[0].Timestamp.Location() != time.UTC || !events[0].Timestamp.Equal(base.Add(time.Hour)) {
+		t.Errorf("Timestamp = %v, want %v", events[0].Timestamp, base.Add(time.Hour))
+	}
+}
+
+func TestUTCColumnsMigration(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+	event := &ChangeEvent{Timestamp: time.Now(), Namespace: "default", Kind: "ConfigMap", Name: "settings", Action: "ADDED"}
+	if err := s.SaveEventSync(ctx, event); err != nil {
+		t.Fatalf("SaveEventSync: %v", err)
+	}
+
+	// Rows written by earlier versions carry the writer's local offset
+	if _, err := s.db.ExecContext(ctx, "UPDATE change_events SET timestamp = ? WHERE id = ?", "2026-01-05 21:00:00+09:00", event.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := utcColumns("change_events", "timestamp")(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("utcColumns: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := s.db.QueryRowContext(ctx, "SELECT CAST(timestamp AS TEXT) FROM change_events WHERE id = ?", event.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, "2026-01-05 12:00:00") {
+		t.Errorf("timestamp after the migration = %q, want 12:00 UTC", stored)
+	}
+}
+
+// seedEvents saves count ConfigMap events a minute apart, oldest first
+func seedEvents(t *testing.T, s EventStore, count int) {
+	t.Helper()
+	base := time.Now().UTC().Add(-time.Duration(count) * time.Minute)
+	for i := range count {
+		event := &ChangeEvent{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Namespace: "default",
+			Kind:      "ConfigMap",
+			Name:      fmt.Sprintf("settings-%d", i),
+			Action:    "MODIFIED",
+			Diff:      "Keys modified: [log_level]",
+		}
+		if err := s.SaveEventSync(context.Background(), event); err != nil {
+			t.Fatalf("SaveEventSync: %v", err)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	ctx := context.Background()
+	queries := map[string]func(s *Storage) error{
+		"GetEvents": func(s *Storage) error {
+			_, err := s.GetEvents(ctx, Filter{Limit: 10})
+			return err
+		},
+		"GetTimeline": func(s *Storage) error {
+			_, err := s.GetTimeline(ctx, "default", "ConfigMap", "settings-1", Filter{Limit: 10})
+			return err
+		},
+		"GetStats": func(s *Storage) error {
+			_, err := s.GetStats(ctx, StatsFilter{})
+			return err
+		},
+		"GetAggregate": func(s *Storage) error {
+			_, err := s.GetAggregate(ctx, "severity", StatsFilter{})
+			return err
+		},
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, s *Storage)
+		queries []string
+	}{
+		{
+			name: "closed database",
+			corrupt: func(t *testing.T, s *Storage) {
+				if err := s.db.Close(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			queries: []string{"GetEvents", "GetTimeline", "GetStats", "GetAggregate"},
+		},
+		{
+			name: "renamed column",
+			corrupt: func(t *testing.T, s *Storage) {
+				if _, err := s.db.Exec("ALTER TABLE change_events RENAME COLUMN severity TO level"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			queries: []string{"GetEvents", "GetTimeline", "GetStats", "GetAggregate"},
+		},
+		{
+			name: "value of the wrong type",
+			corrupt: func(t *testing.T, s *Storage) {
+				if _, err := s.db.Exec("UPDATE change_events SET repeat_count = 'many' WHERE name = 'settings-1'"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			queries: []string{"GetEvents", "GetTimeline"}, // the row is only scanned into an event
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			seedEvents(t, s, 3)
+			tt.corrupt(t, s)
+			for _, name := range tt.queries {
+				if err := queries[name](s); err == nil {
+					t.Errorf("%s succeeded", name)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildWhere(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    Filter
+		wantWhere string
+		wantArgs  []interface{}
+		wantCount int64 // of filterEvents
+	}{
+		{
+			name:      "empty filter",
+			wantWhere: " WHERE 1=1",
+			wantArgs:  []interface{}{},
+			wantCount: 4,
+		},
+		{
+			name:      "empty lists",
+			filter:    Filter{Namespaces: []string{}, Kinds: []string{}, Actions: []string{}, ExcludeNamespaces: []string{}, ExcludeKinds: []string{}, ExcludeActions: []string{}},
+			wantWhere: " WHERE 1=1",
+			wantArgs:  []interface{}{},
+			wantCount: 4,
+		},
+		{
+			name:      "namespaces",
+			filter:    Filter{Namespaces: []string{"shop", "billing"}},
+			wantWhere: " WHERE 1=1 AND namespace IN (?, ?)",
+			wantArgs:  []interface{}{"shop", "billing"},
+			wantCount: 3,
+		},
+		{
+			name:      "single kind",
+			filter:    Filter{Kinds: []string{"Deployment"}},
+			wantWhere: " WHERE 1=1 AND kind IN (?)",
+			wantArgs:  []interface{}{"Deployment"},
+			wantCount: 2,
+		},
+		{
+			name:      "actions",
+			filter:    Filter{Actions: []string{"ADDED", "DELETED"}},
+			wantWhere: " WHERE 1=1 AND action IN (?, ?)",
+			wantArgs:  []interface{}{"ADDED", "DELETED"},
+			wantCount: 2,
+		},
+		{
+			name:      "excluded namespaces",
+			filter:    Filter{ExcludeNamespaces: []string{"monitoring", "kube-dashboard"}},
+			wantWhere: " WHERE 1=1 AND namespace NOT IN (?, ?)",
+			wantArgs:  []interface{}{"monitoring", "kube-dashboard"},
+			wantCount: 3,
+		},
+		{
+			name:      "excluded kinds",
+			filter:    Filter{ExcludeKinds: []string{"ConfigMap"}},
+			wantWhere: " WHERE 1=1 AND kind NOT IN (?)",
+			wantArgs:  []interface{}{"ConfigMap"},
+			wantCount: 3,
+		},
+		{
+			name:      "excluded actions",
+			filter:    Filter{ExcludeActions: []string{"MODIFIED"}},
+			wantWhere: " WHERE 1=1 AND action NOT IN (?)",
+			wantArgs:  []interface{}{"MODIFIED"},
+			wantCount: 2,
+		},
+		{
+			name:      "included and excluded",
+			filter:    Filter{Kinds: []string{"Deployment", "StatefulSet"}, ExcludeNamespaces: []string{"monitoring"}, ExcludeActions: []string{"ADDED"}},
+			wantWhere: " WHERE 1=1 AND kind IN (?, ?) AND namespace NOT IN (?) AND action NOT IN (?)",
+			wantArgs:  []interface{}{"Deployment", "StatefulSet", "monitoring", "ADDED"},
+			wantCount: 1,
+		},
+		{
+			name:      "single values and lists",
+			filter:    Filter{Namespace: "shop", Namespaces: []string{"shop", "billing"}, Actions: []string{"MODIFIED"}, ExcludeKinds: []string{"ConfigMap"}},
+			wantWhere: " WHERE 1=1 AND namespace = ? AND namespace IN (?, ?) AND action IN (?) AND kind NOT IN (?)",
+			wantArgs:  []interface{}{"shop", "shop", "billing", "MODIFIED", "ConfigMap"},
+			wantCount: 1,
+		},
+		{
+			name:      "namespace included and excluded",
+			filter:    Filter{Namespaces: []string{"shop"}, ExcludeNamespaces: []string{"shop"}},
+			wantWhere: " WHERE 1=1 AND namespace IN (?) AND namespace NOT IN (?)",
+			wantArgs:  []interface{}{"shop", "shop"},
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := buildWhere(tt.filter)
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+			if placeholders := strings.Count(where, "?"); placeholders != len(args) {
+				t.Errorf("%d placeholders for %d args", placeholders, len(args))
+			}
+
+			for name, newStore := range stores {
+				s := newStore(t)
+				saveEvents(t, s, filterEvents()...)
+				count, err := s.GetTotalCount(context.Background(), tt.filter)
+				if err != nil {
+					t.Fatalf("%s: GetTotalCount: %v", name, err)
+				}
+				if count != tt.wantCount {
+					t.Errorf("%s: GetTotalCount = %d, want %d", name, count, tt.wantCount)
+				}
+			}
+		})
+	}
+}
+
+// filterEvents are four events in three namespaces with different kinds and
+// actions
+func filterEvents() []*ChangeEvent {
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	return []*ChangeEvent{
+		{Timestamp: base, Namespace: "shop", Kind: "Deployment", Name: "web", Action: "ADDED"},
+		{Timestamp: base.Add(time.Minute), Namespace: "shop", Kind: "Deployment", Name: "web", Action: "MODIFIED", Diff: "Scaled up: 1 → 3 replicas"},
+		{Timestamp: base.Add(2 * time.Minute), Namespace: "billing", Kind: "ConfigMap", Name: "rates", Action: "MODIFIED", Diff: "Keys modified: [vat]"},
+		{Timestamp: base.Add(3 * time.Minute), Namespace: "monitoring", Kind: "StatefulSet", Name: "prometheus", Action: "DELETED"},
+	}
+}
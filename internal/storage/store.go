@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// EventStore is the persistence interface used by the watcher and the API server.
+// Storage (SQLite) is the default implementation.
+type EventStore interface {
+	// SaveEvent may return before the event is written and leave its ID unset;
+	// SaveEventSync always writes immediately and sets the ID
+	SaveEvent(ctx context.Context, event *ChangeEvent) error
+	SaveEventSync(ctx context.Context, event *ChangeEvent) error
+	// Flush waits until the events queued by SaveEvent are written
+	Flush(ctx context.Context) error
+	GetEvents(ctx context.Context, filter Filter) ([]ChangeEvent, error)
+	StreamEvents(ctx context.Context, filter Filter, fn func(*ChangeEvent) error) error
+	GetEventByID(ctx context.Context, id int64) (*ChangeEvent, error)
+	DeleteEvent(ctx context.Context, id int64) error
+	SearchEvents(ctx context.Context, text string, filter Filter) ([]ChangeEvent, error)
+	GetTotalCount(ctx context.Context, filter Filter) (int64, error)
+	GetTimeline(ctx context.Context, namespace, kind, name string, filter Filter) ([]ChangeEvent, error)
+	GetResourceSummary(ctx context.Context, namespace, kind, name string) (*ResourceSummary, error)
+	GetResources(ctx context.Context, filter ResourceFilter) ([]TrackedResource, error)
+	CountResources(ctx context.Context, filter ResourceFilter) (int64, error)
+	GetStats(ctx context.Context, filter StatsFilter) (*Stats, error)
+	GetNamespaceStats(ctx context.Context, namespace string) (*NamespaceStats, error)
+	GetEventHistogram(ctx context.Context, filter Filter, bucket time.Duration, groupBy string) ([]HistogramBucket, error)
+	GetAggregate(ctx context.Context, groupBy string, filter StatsFilter) ([]AggregateCount, error)
+	GetTopChangedNamespaces(ctx context.Context, window time.Duration, limit int) ([]NamespaceChangeCount, error)
+	GetImageHistory(ctx context.Context, namespace, name string, limit int) ([]ImageTransition, error)
+	GetImageHistoryByPrefix(ctx context.Context, prefix string, limit int) ([]ImageHistoryEntry, error)
+	GetImageRollouts(ctx context.Context, image string, limit int) ([]ChangeEvent, error)
+	GetChangesets(ctx context.Context, namespace string, limit int) ([]Changeset, error)
+	SetEventTags(ctx context.Context, id int64, tags []string) error
+	GetTags(ctx context.Context) ([]TagCount, error)
+	AddEventNote(ctx context.Context, id int64, note, author string) error
+	AddAnnotation(ctx context.Context, annotation *Annotation) error
+	GetAnnotations(ctx context.Context, eventIDs []int64) (map[int64][]Annotation, error)
+	RecordNotification(ctx context.Context, delivery *NotificationDelivery) error
+	UpdateNotification(ctx context.Context, delivery *NotificationDelivery) error
+	GetNotifications(ctx context.Context, filter NotificationFilter) ([]NotificationDelivery, error)
+	GetNotification(ctx context.Context, id int64) (*NotificationDelivery, error)
+	CleanupOldEvents(ctx context.Context, retentionDays int) (map[string]int64, error)
+	DBStats(ctx context.Context) (*DBStats, error)
+	ExportRange(ctx context.Context, start, end time.Time, w io.Writer) error
+
+	SaveSnapshot(ctx context.Context, snapshot *EventSnapshot) error
+	GetSnapshot(ctx context.Context, eventID int64) (*EventSnapshot, error)
+	CleanupOldSnapshots(ctx context.Context, retentionDays int) (int64, error)
+
+	Close() error
+}
+
+var (
+	_ EventStore = (*Storage)(nil)
+	_ EventStore = (*MemoryStore)(nil)
+	_ EventStore = (*NoopStorage)(nil)
+)
@@ -0,0 +1,407 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// stores are the EventStore implementations every conformance test runs
+// against. The SQLite driver is the one selected by the build tags (cgo by
+// default, modernc with -tags purego).
+var stores = map[string]func(t *testing.T) EventStore{
+	"sqlite": func(t *testing.T) EventStore { return newTestStorage(t) },
+	"memory": func(t *testing.T) EventStore { return NewMemoryStore() },
+}
+
+// forEachStore runs test against a fresh instance of every store
+func forEachStore(t *testing.T, test func(t *testing.T, s EventStore)) {
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+// saveEvents stores events with SaveEventSync, oldest first
+func saveEvents(t *testing.T, s EventStore, events ...*ChangeEvent) {
+	t.Helper()
+	for _, event := range events {
+		if err := s.SaveEventSync(context.Background(), event); err != nil {
+			t.Fatalf("SaveEventSync(%s/%s): %v", event.Kind, event.Name, err)
+		}
+	}
+}
+
+// eventNames returns the names of events in order
+func eventNames(events []ChangeEvent) []string {
+	names := make([]string, len(events))
+	for i := range events {
+		names[i] = events[i].Name
+	}
+	return names
+}
+
+// conformanceEvents are three changes in two namespaces, a minute apart
+func conformanceEvents() []*ChangeEvent {
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	return []*ChangeEvent{
+		{Timestamp: base, Namespace: "shop", Kind: "Deployment", Name: "web", Action: "ADDED", Diff: "ADDED", ImageAfter: "nginx:1.26"},
+		{Timestamp: base.Add(time.Minute), Namespace: "shop", Kind: "Deployment", Name: "web", Action: "MODIFIED", Diff: "Image updated: nginx:1.26 → nginx:1.27", ImageBefore: "nginx:1.26", ImageAfter: "nginx:1.27"},
+		{Timestamp: base.Add(2 * time.Minute), Namespace: "billing", Kind: "ConfigMap", Name: "rates", Action: "MODIFIED", Diff: "Keys modified: [vat]"},
+	}
+}
+
+func TestStoreSaveAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		events := conformanceEvents()
+		saveEvents(t, s, events...)
+
+		for i, event := range events {
+			if event.ID == 0 {
+				t.Fatalf("event %d has no ID after SaveEventSync", i)
+			}
+		}
+		if events[0].ID == events[1].ID || events[1].ID == events[2].ID {
+			t.Fatal("events share an ID")
+		}
+
+		got, err := s.GetEventByID(ctx, events[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || got.Name != "web" || got.Action != "MODIFIED" || got.Diff != events[1].Diff ||
+			got.ImageBefore != "nginx:1.26" || got.ImageAfter != "nginx:1.27" || !got.Timestamp.Equal(events[1].Timestamp) {
+			t.Errorf("GetEventByID = %+v, want %+v", got, events[1])
+		}
+
+		if missing, err := s.GetEventByID(ctx, events[2].ID+100); err != nil || missing != nil {
+			t.Errorf("GetEventByID of a missing event = %v, %v; want nil, nil", missing, err)
+		}
+	})
+}
+
+func TestStoreGetEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "newest first", filter: Filter{}, want: []string{"rates", "web", "web"}},
+		{name: "namespace", filter: Filter{Namespace: "shop"}, want: []string{"web", "web"}},
+		{name: "kind and action", filter: Filter{Kind: "Deployment", Action: "ADDED"}, want: []string{"web"}},
+		{name: "several namespaces", filter: Filter{Namespaces: []string{"billing", "other"}}, want: []string{"rates"}},
+		{name: "namespace pattern", filter: Filter{NamespacePatterns: []string{"bil*"}}, want: []string{"rates"}},
+		{name: "excluded kind", filter: Filter{ExcludeKinds: []string{"Deployment"}}, want: []string{"rates"}},
+		{name: "limit and offset", filter: Filter{Limit: 1, Offset: 1}, want: []string{"web"}},
+		{name: "no match", filter: Filter{Namespace: "missing"}, want: []string{}},
+	}
+
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		saveEvents(t, s, conformanceEvents()...)
+
+		for _, tt := range tests {
+			events, err := s.GetEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: GetEvents: %v", tt.name, err)
+			}
+			if got := eventNames(events); !slices.Equal(got, tt.want) {
+				t.Errorf("%s: GetEvents = %v, want %v", tt.name, got, tt.want)
+			}
+
+			if tt.filter.Limit > 0 {
+				continue // counts ignore pagination
+			}
+			count, err := s.GetTotalCount(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: GetTotalCount: %v", tt.name, err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("%s: GetTotalCount = %d, want %d", tt.name, count, len(tt.want))
+			}
+		}
+	})
+}
+
+func TestStoreTimelineAndImages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		events := conformanceEvents()
+		saveEvents(t, s, events...)
+
+		timeline, err := s.GetTimeline(ctx, "shop", "Deployment", "web", Filter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(timeline) != 2 || timeline[0].ID != events[1].ID || timeline[1].ID != events[0].ID {
+			t.Errorf("GetTimeline = %v, want the two web events newest first", timeline)
+		}
+
+		history, err := s.GetImageHistory(ctx, "shop", "web", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) == 0 || history[0].ImageAfter != "nginx:1.27" || history[0].ImageBefore != "nginx:1.26" {
+			t.Errorf("GetImageHistory = %+v, want nginx:1.26 → nginx:1.27 first", history)
+		}
+	})
+}
+
+func TestStoreDeleteEvent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		events := conformanceEvents()
+		saveEvents(t, s, events...)
+
+		if err := s.DeleteEvent(ctx, events[0].ID); err != nil {
+			t.Fatalf("DeleteEvent: %v", err)
+		}
+		if got, err := s.GetEventByID(ctx, events[0].ID); err != nil || got != nil {
+			t.Errorf("GetEventByID after DeleteEvent = %v, %v; want nil, nil", got, err)
+		}
+		if err := s.DeleteEvent(ctx, events[0].ID); !errors.Is(err, ErrEventNotFound) {
+			t.Errorf("second DeleteEvent = %v, want ErrEventNotFound", err)
+		}
+		if count, err := s.GetTotalCount(ctx, Filter{}); err != nil || count != 2 {
+			t.Errorf("GetTotalCount = %d, %v; want 2", count, err)
+		}
+	})
+}
+
+func TestStoreTagsAndAnnotations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		events := conformanceEvents()
+		saveEvents(t, s, events...)
+
+		if err := s.SetEventTags(ctx, events[1].ID, []string{"release", "hotfix"}); err != nil {
+			t.Fatalf("SetEventTags: %v", err)
+		}
+		if err := s.SetEventTags(ctx, events[2].ID, []string{"release"}); err != nil {
+			t.Fatalf("SetEventTags: %v", err)
+		}
+		tags, err := s.GetTags(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []TagCount{{Tag: "release", Count: 2}, {Tag: "hotfix", Count: 1}}
+		if !slices.Equal(tags, want) {
+			t.Errorf("GetTags = %v, want %v", tags, want)
+		}
+		tagged, err := s.GetEvents(ctx, Filter{Tag: "hotfix"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tagged) != 1 || tagged[0].ID != events[1].ID {
+			t.Errorf("GetEvents(tag=hotfix) = %v, want the web rollout", tagged)
+		}
+
+		annotation := &Annotation{EventID: events[1].ID, Author: "oncall", Note: "expected rollout", Acknowledged: true}
+		if err := s.AddAnnotation(ctx, annotation); err != nil {
+			t.Fatalf("AddAnnotation: %v", err)
+		}
+		if err := s.AddAnnotation(ctx, &Annotation{EventID: events[2].ID + 100, Note: "lost"}); !errors.Is(err, ErrEventNotFound) {
+			t.Errorf("AddAnnotation on a missing event = %v, want ErrEventNotFound", err)
+		}
+		annotations, err := s.GetAnnotations(ctx, []int64{events[1].ID, events[2].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := annotations[events[1].ID]; len(got) != 1 || got[0].Note != "expected rollout" || !got[0].Acknowledged {
+			t.Errorf("GetAnnotations = %+v, want the acknowledged note", got)
+		}
+		if len(annotations[events[2].ID]) != 0 {
+			t.Errorf("GetAnnotations returned annotations for an unannotated event")
+		}
+
+		acknowledged := true
+		acked, err := s.GetEvents(ctx, Filter{Acknowledged: &acknowledged})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(acked) != 1 || acked[0].ID != events[1].ID {
+			t.Errorf("GetEvents(acknowledged) = %v, want the annotated event", acked)
+		}
+	})
+}
+
+func TestStoreSearchEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		saveEvents(t, s, conformanceEvents()...)
+
+		events, err := s.SearchEvents(ctx, "nginx:1.27", Filter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventNames(events); !slices.Equal(got, []string{"web"}) {
+			t.Errorf("SearchEvents(nginx:1.27) = %v, want [web]", got)
+		}
+
+		events, err = s.SearchEvents(ctx, "rates", Filter{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventNames(events); !slices.Equal(got, []string{"rates"}) {
+			t.Errorf("SearchEvents(rates) = %v, want [rates]", got)
+		}
+	})
+}
+
+// TestStoreCursorPagination pages through events with next cursors while
+// events are inserted, some newer and some with the timestamp of the cursor's
+// event. The pages must hold every event that was older than page 1 exactly
+// once, and paging back with After must return the events newer than page 1.
+// tied-cursor sorts between the events of page 1, so neither direction
+// returns it.
+func TestStoreCursorPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		event := func(name string, ts time.Time) *ChangeEvent {
+			return &ChangeEvent{Timestamp: ts, Namespace: "shop", Kind: "ConfigMap", Name: name, Action: "MODIFIED"}
+		}
+
+		// Ten events, in groups of three sharing a timestamp, so pages of
+		// four split them
+		var seeded []*ChangeEvent
+		for i := range 10 {
+			seeded = append(seeded, event(fmt.Sprintf("seeded-%d", i), base.Add(time.Duration(i/3)*time.Minute)))
+		}
+		saveEvents(t, s, seeded...)
+
+		const pageSize = 4
+		page, err := s.GetEvents(ctx, Filter{Limit: pageSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != pageSize {
+			t.Fatalf("page 1 has %d events, want %d", len(page), pageSize)
+		}
+		first := CursorFor(&page[0])
+		cursor := CursorFor(&page[len(page)-1])
+
+		// New events: newer than everything, and tied with the cursor's event
+		// and with the newest event on page 1
+		inserted := []*ChangeEvent{
+			event("newer-1", base.Add(time.Hour)),
+			event("tied-cursor", cursor.Timestamp),
+			event("newer-2", base.Add(2*time.Hour)),
+			event("tied-first", first.Timestamp),
+		}
+		saveEvents(t, s, inserted...)
+
+		seen := map[int64]int{}
+		for _, e := range page {
+			seen[e.ID]++
+		}
+		var rest []string
+		for pages := 0; ; pages++ {
+			if pages > len(seeded) {
+				t.Fatal("pagination does not end")
+			}
+			page, err = s.GetEvents(ctx, Filter{Limit: pageSize, Before: &cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range page {
+				seen[e.ID]++
+				rest = append(rest, e.Name)
+			}
+			if len(page) < pageSize {
+				break
+			}
+			cursor = CursorFor(&page[len(page)-1])
+		}
+
+		for _, e := range seeded {
+			if seen[e.ID] != 1 {
+				t.Errorf("%s seen %d times, want once", e.Name, seen[e.ID])
+			}
+		}
+		for _, e := range inserted {
+			if seen[e.ID] != 0 {
+				t.Errorf("%s, inserted after page 1, appears on a following page", e.Name)
+			}
+		}
+		if want := []string{"seeded-5", "seeded-4", "seeded-3", "seeded-2", "seeded-1", "seeded-0"}; !slices.Equal(rest, want) {
+			t.Errorf("following pages = %v, want %v", rest, want)
+		}
+
+		newer, err := s.GetEvents(ctx, Filter{Limit: 10, After: &first})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := eventNames(newer), []string{"newer-2", "newer-1", "tied-first"}; !slices.Equal(got, want) {
+			t.Errorf("GetEvents(After: page 1) = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStoreStats(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		saveEvents(t, s, conformanceEvents()...)
+
+		stats, err := s.GetStats(ctx, StatsFilter{Window: 24 * time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.TotalChanges != 3 || stats.ChangesLastWindow != 3 {
+			t.Errorf("TotalChanges = %d, ChangesLastWindow = %d; want 3 and 3", stats.TotalChanges, stats.ChangesLastWindow)
+		}
+		if stats.ChangesByKind["Deployment"] != 2 || stats.ChangesByKind["ConfigMap"] != 1 {
+			t.Errorf("ChangesByKind = %v", stats.ChangesByKind)
+		}
+		if stats.ChangesByAction["MODIFIED"] != 2 || stats.ChangesByAction["ADDED"] != 1 {
+			t.Errorf("ChangesByAction = %v", stats.ChangesByAction)
+		}
+
+		scoped, err := s.GetStats(ctx, StatsFilter{Window: 24 * time.Hour, NamespacePatterns: []string{"billing"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scoped.TotalChanges != 1 || scoped.ChangesByKind["Deployment"] != 0 {
+			t.Errorf("scoped stats = %d changes, by kind %v; want only billing", scoped.TotalChanges, scoped.ChangesByKind)
+		}
+
+		aggregate, err := s.GetAggregate(ctx, "namespace", StatsFilter{Window: 24 * time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int64{}
+		for _, a := range aggregate {
+			counts[a.Key] = a.Count
+		}
+		if counts["shop"] != 2 || counts["billing"] != 1 {
+			t.Errorf("GetAggregate(namespace) = %+v", aggregate)
+		}
+	})
+}
+
+func TestStoreCleanupOldEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s EventStore) {
+		ctx := context.Background()
+		old := &ChangeEvent{Timestamp: time.Now().UTC().AddDate(0, 0, -90), Namespace: "shop", Kind: "Deployment", Name: "legacy", Action: "DELETED"}
+		saveEvents(t, s, append([]*ChangeEvent{old}, conformanceEvents()...)...)
+
+		deleted, err := s.CleanupOldEvents(ctx, 30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted["Deployment"] != 1 || deleted["ConfigMap"] != 0 {
+			t.Errorf("CleanupOldEvents = %v, want one Deployment", deleted)
+		}
+		if got, err := s.GetEventByID(ctx, old.ID); err != nil || got != nil {
+			t.Errorf("GetEventByID of the expired event = %v, %v; want nil, nil", got, err)
+		}
+		if count, err := s.GetTotalCount(ctx, Filter{}); err != nil || count != 3 {
+			t.Errorf("GetTotalCount = %d, %v; want 3", count, err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ResourceSummary is the latest known state of one resource, derived from its
+// most recent events
+type ResourceSummary struct {
+	Namespace    string `json:"namespace"`
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	TotalChanges int64  `json:"total_changes"`
+
+	// The most recent event
+	LastEventID int64     `json:"last_event_id"`
+	LastChange  time.Time `json:"last_change"`
+	LastAction  string    `json:"last_action"`
+	LastAuthor  string    `json:"last_author,omitempty"`
+	LastActor   string    `json:"last_actor,omitempty"`
+	LastSource  string    `json:"last_source,omitempty"`
+
+	// CurrentImage is the latest non-empty image_after; Replicas the latest
+	// replicas value in the event metadata, for kinds that record one
+	CurrentImage string `json:"current_image,omitempty"`
+	Replicas     *int64 `json:"replicas,omitempty"`
+
+	// Deleted is set when the latest event is a deletion, at DeletedAt
+	Deleted   bool       `json:"deleted"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// newResourceSummary fills in the fields taken from the latest event
+func newResourceSummary(latest *ChangeEvent, total int64) *ResourceSummary {
+	summary := &ResourceSummary{
+		Namespace:    latest.Namespace,
+		Kind:         latest.Kind,
+		Name:         latest.Name,
+		TotalChanges: total,
+		LastEventID:  latest.ID,
+		LastChange:   latest.Timestamp,
+		LastAction:   latest.Action,
+		LastAuthor:   latest.Author,
+		LastActor:    latest.Actor,
+		LastSource:   latest.Source,
+	}
+	if latest.Action == "DELETED" {
+		deletedAt := latest.Timestamp
+		summary.Deleted = true
+		summary.DeletedAt = &deletedAt
+	}
+	return summary
+}
+
+// GetResourceSummary returns the summary of a resource, or nil if it has no
+// events. A deleted resource keeps its last image and replica count.
+func (s *Storage) GetResourceSummary(ctx context.Context, namespace, kind, name string) (*ResourceSummary, error) {
+	filter := timelineFilter(namespace, kind, name, Filter{Limit: 1})
+	latest, err := s.GetEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 {
+		return nil, nil
+	}
+	total, err := s.GetTotalCount(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	summary := newResourceSummary(&latest[0], total)
+
+	// Both walk idx_resource_timestamp newest first and stop at the first hit
+	var image sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT image_after FROM change_events
+		WHERE namespace = ? AND kind = ? AND name = ? AND image_after IS NOT NULL AND image_after != ''
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`, namespace, kind, name).Scan(&image)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query current image: %w", err)
+	}
+	summary.CurrentImage = image.String
+
+	var replicas sql.NullInt64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT replicas FROM (
+			SELECT CASE WHEN json_valid(metadata) THEN json_extract(metadata, '$.replicas') END AS replicas, timestamp, id
+			FROM change_events
+			WHERE namespace = ? AND kind = ? AND name = ?
+		)
+		WHERE replicas IS NOT NULL
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`, namespace, kind, name).Scan(&replicas)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query replicas: %w", err)
+	}
+	if replicas.Valid {
+		summary.Replicas = &replicas.Int64
+	}
+	return summary, nil
+}
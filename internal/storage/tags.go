@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 64
+)
+
+// ErrEventNotFound is returned when an event to update does not exist
+var ErrEventNotFound = errors.New("event not found")
+
+// TagCount is a tag and the number of events carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// NormalizeTags trims, deduplicates and sorts tags. Tags must be non-empty,
+// at most 64 characters and free of whitespace and commas.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		switch {
+		case tag == "":
+			return nil, fmt.Errorf("tags must not be empty")
+		case len(tag) > maxTagLength:
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		case strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }):
+			return nil, fmt.Errorf("tag %q must not contain whitespace or commas", tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// SetEventTags replaces the tags of an event; no tags clears them
+func (s *Storage) SetEventTags(ctx context.Context, id int64, tags []string) error {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	var value interface{} // NULL when there are no tags
+	if len(tags) > 0 {
+		encoded, _ := json.Marshal(tags)
+		value = string(encoded)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE change_events SET tags = ? WHERE id = ?", value, id)
+	if err != nil {
+		return fmt.Errorf("failed to set event tags: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEventNotFound
+	}
+
+	if err := s.reindexAnnotations(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetTags returns every tag in use with its event count, most used first
+func (s *Storage) GetTags(ctx context.Context) ([]TagCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tag.value, COUNT(*) FROM change_events, json_each(change_events.tags) AS tag
+		WHERE change_events.tags IS NOT NULL
+		GROUP BY tag.value
+		ORDER BY COUNT(*) DESC, tag.value
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tags: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	return tags, nil
+}
+
+// parseTags decodes the JSON array stored in the tags column
+func parseTags(value sql.NullString) []string {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	var tags []string
+	json.Unmarshal([]byte(value.String), &tags)
+	return tags
+}
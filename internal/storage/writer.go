@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// writeQueueSize is the number of events that can wait for the writer
+	writeQueueSize = 1000
+	// maxBatchSize flushes the pending batch once it holds this many events
+	maxBatchSize = 100
+	// flushInterval flushes whatever is pending at least this often
+	flushInterval = 200 * time.Millisecond
+)
+
+const insertEventQuery = `
+	INSERT INTO change_events (timestamp, namespace, kind, name, action, diff, diff_compressed, diff_size, metadata, image_before, image_after, author, uid, resource_version, actor, source, severity, event_hash, changeset_id, app)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+func insertEventArgs(event *ChangeEvent) []interface{} {
+	diff, diffCompressed, diffSize := storedDiff(event.Diff)
+	return []interface{}{
+		event.Timestamp,
+		event.Namespace,
+		event.Kind,
+		event.Name,
+		event.Action,
+		diff,
+		diffCompressed,
+		diffSize,
+		event.Metadata,
+		event.ImageBefore,
+		event.ImageAfter,
+		event.Author,
+		event.UID,
+		event.ResourceVersion,
+		event.Actor,
+		event.Source,
+		event.Severity,
+		EventHash(event),
+		event.ChangesetID,
+		event.App,
+	}
+}
+
+// SaveEvent queues a change event for the background writer and returns
+// without waiting for it to be written. The event's ID is not set; use
+// SaveEventSync when the ID is needed.
+func (s *Storage) SaveEvent(ctx context.Context, event *ChangeEvent) error {
+	event.Timestamp = event.Timestamp.UTC()
+
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("failed to save event: storage is closed")
+	}
+
+	// Queue a copy so the caller may keep using the event
+	queued := *event
+	select {
+	case s.queue <- &queued:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to queue event: %w", ctx.Err())
+	}
+}
+
+// SaveEventSync writes a change event immediately and sets its ID. A repeat
+// that is coalesced gets the ID of the event it was merged into. A duplicate
+// gets the ID of the stored event and ErrDuplicateEvent.
+func (s *Storage) SaveEventSync(ctx context.Context, event *ChangeEvent) error {
+	event.Timestamp = event.Timestamp.UTC()
+	id, err := s.duplicateOf(ctx, s.db, event)
+	if err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	if id != 0 {
+		event.ID = id
+		return ErrDuplicateEvent
+	}
+
+	id, err = s.coalesce(ctx, s.db, event)
+	if err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	if id != 0 {
+		event.ID = id
+		return nil
+	}
+
+	result, err := s.db.ExecContext(ctx, insertEventQuery, insertEventArgs(event)...)
+	if err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+
+	s.rowCount.Add(1)
+
+	id, err = result.LastInsertId()
+	if err == nil {
+		event.ID = id
+		if err := s.indexEvent(ctx, s.db, id, event); err != nil {
+			s.logger.Warn("Failed to index event for full-text search", slog.Int64("event_id", id), slog.Any("error", err))
+		}
+	}
+
+	s.publish(event)
+	return nil
+}
+
+// runWriter is the single writer goroutine. It batches queued events into one
+// transaction per flush and drains the queue when it is closed.
+func (s *Storage) runWriter() {
+	defer close(s.writerDone)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*ChangeEvent, 0, maxBatchSize)
+	for {
+		select {
+		case event, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= maxBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case done := <-s.flushReq:
+			// Write what was queued before the request, then report back
+			for n := len(s.queue); n > 0; n-- {
+				event, ok := <-s.queue
+				if !ok {
+					break
+				}
+				batch = append(batch, event)
+			}
+			s.flush(batch)
+			batch = batch[:0]
+			close(done)
+		}
+	}
+}
+
+// Flush writes the events queued so far and waits until they are stored or
+// ctx is done. Events queued while it runs may be written later.
+func (s *Storage) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.flushReq <- done:
+	case <-s.writerDone:
+		return nil // closed, everything is written
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush events: %w", ctx.Err())
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush events: %w", ctx.Err())
+	}
+}
+
+// flush writes a batch in a single transaction. If the transaction fails the
+// events are retried one by one so a single bad row doesn't lose the batch.
+func (s *Storage) flush(batch []*ChangeEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// Not bound to the root context: queued events must still be written
+	// while shutting down
+	ctx := context.Background()
+	// Runs after writeMu is released, since eviction may vacuum
+	defer s.evictIfFull(ctx)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	inserted, err := s.insertBatch(ctx, batch)
+	if err == nil {
+		s.rowCount.Add(int64(len(inserted)))
+		for _, event := range inserted {
+			s.publish(event)
+		}
+		return
+	}
+	s.logger.Warn("Batched write failed, retrying events individually", slog.Int("events", len(batch)), slog.Any("error", err))
+
+	for _, event := range batch {
+		if err := s.SaveEventSync(ctx, event); err != nil && !errors.Is(err, ErrDuplicateEvent) {
+			s.logger.Error("Failed to save event",
+				slog.String("kind", event.Kind),
+				slog.String("namespace", event.Namespace),
+				slog.String("name", event.Name),
+				slog.String("action", event.Action),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// insertBatch writes a batch in one transaction and returns the inserted
+// events with their IDs set; coalesced repeats update an existing row instead
+func (s *Storage) insertBatch(ctx context.Context, batch []*ChangeEvent) ([]*ChangeEvent, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertEventQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	inserted := make([]*ChangeEvent, 0, len(batch))
+	for _, event := range batch {
+		// Earlier events of the batch are visible inside the transaction
+		if id, err := s.duplicateOf(ctx, tx, event); err != nil {
+			return nil, err
+		} else if id != 0 {
+			continue
+		}
+		if id, err := s.coalesce(ctx, tx, event); err != nil {
+			return nil, err
+		} else if id != 0 {
+			continue
+		}
+
+		result, err := stmt.ExecContext(ctx, insertEventArgs(event)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert event: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event id: %w", err)
+		}
+		if err := s.indexEvent(ctx, tx, id, event); err != nil {
+			return nil, err
+		}
+		event.ID = id
+		inserted = append(inserted, event)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return inserted, nil
+}
@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8watch/internal/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client reads events from the /api/stream WebSocket of a k8watch server
+type Client struct {
+	conn *websocket.Conn
+}
+
+// Dial connects to the event stream of the server at baseURL (e.g.
+// http://k8watch:8080) and receives the events that match filter. header is
+// sent with the handshake, e.g. for an Authorization bearer token; it may be nil.
+func Dial(ctx context.Context, baseURL string, filter Filter, header http.Header) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stream URL: %w", err)
+	}
+	location := *base
+	location.Path += "/api/stream"
+	location.RawQuery = filter.Query().Encode()
+	switch location.Scheme {
+	case "http":
+		location.Scheme = "ws"
+	case "https":
+		location.Scheme = "wss"
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, location.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to event stream: %w (status %s)", err, resp.Status)
+		}
+		return nil, fmt.Errorf("failed to connect to event stream: %w", err)
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Next blocks until the next event arrives. It returns io.EOF once the server
+// ends the stream, e.g. on shutdown or because the client fell behind.
+func (c *Client) Next() (*storage.ChangeEvent, error) {
+	var event storage.ChangeEvent
+	if err := c.conn.ReadJSON(&event); err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8watch/internal/storage"
+)
+
+// sendBuffer is the number of events a subscriber may fall behind before it
+// is disconnected
+const sendBuffer = 64
+
+var (
+	// ErrClosed is the error of subscriptions ended by closing the hub
+	ErrClosed = errors.New("event stream closed")
+	// ErrSlowSubscriber is the error of subscriptions that fell more than
+	// sendBuffer events behind
+	ErrSlowSubscriber = errors.New("subscriber too slow")
+)
+
+// Filter selects the events a subscriber receives. Each field holds values of
+// which an event must match one; an empty field matches every event.
+type Filter struct {
+	Namespaces []string
+	Kinds      []string
+	Actions    []string
+}
+
+// FilterFromQuery reads a filter from the comma-separated namespace, kind and
+// action query parameters, as accepted by /api/events
+func FilterFromQuery(query url.Values) Filter {
+	return Filter{
+		Namespaces: splitValues(query.Get("namespace")),
+		Kinds:      splitValues(query.Get("kind")),
+		Actions:    splitValues(query.Get("action")),
+	}
+}
+
+// Query encodes the filter as query parameters, the inverse of FilterFromQuery
+func (f Filter) Query() url.Values {
+	query := url.Values{}
+	if len(f.Namespaces) > 0 {
+		query.Set("namespace", strings.Join(f.Namespaces, ","))
+	}
+	if len(f.Kinds) > 0 {
+		query.Set("kind", strings.Join(f.Kinds, ","))
+	}
+	if len(f.Actions) > 0 {
+		query.Set("action", strings.Join(f.Actions, ","))
+	}
+	return query
+}
+
+// Matches reports whether the filter selects event
+func (f Filter) Matches(event *storage.ChangeEvent) bool {
+	return matchesAny(f.Namespaces, event.Namespace) &&
+		matchesAny(f.Kinds, event.Kind) &&
+		matchesAny(f.Actions, event.Action)
+}
+
+// EventFilter returns the storage filter selecting the same events, stored
+// after the event with ID sinceID, e.g. to replay the events a client missed
+func (f Filter) EventFilter(sinceID int64) storage.Filter {
+	return storage.Filter{
+		Namespaces: f.Namespaces,
+		Kinds:      f.Kinds,
+		Actions:    f.Actions,
+		SinceID:    sinceID,
+	}
+}
+
+func matchesAny(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Subscription receives the events of a Hub that match its filter
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan storage.ChangeEvent
+	err    error // why events was closed, guarded by hub.mu
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription ends; Err then tells why.
+func (s *Subscription) Events() <-chan storage.ChangeEvent {
+	return s.events
+}
+
+// Err returns ErrClosed or ErrSlowSubscriber once Events is closed, nil before
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.remove(s, ErrClosed)
+}
+
+// Hub fans stored events out to subscribers, e.g. the WebSocket clients of
+// /api/stream. It implements storage.EventPublisher.
+type Hub struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub creates a hub without subscribers. A nil logger uses slog.Default().
+func NewHub(logger *slog.Logger) *Hub {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Hub{
+		logger: logger,
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts delivering the events that match filter. It fails with
+// ErrClosed once the hub is closed.
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan storage.ChangeEvent, sendBuffer),
+	}
+	h.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish delivers a copy of event to every matching subscriber without
+// blocking. Subscribers whose buffer is full are disconnected.
+func (h *Hub) Publish(event *storage.ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- *event:
+		default:
+			h.logger.Warn("Disconnecting slow event stream subscriber", slog.Int("buffer", sendBuffer))
+			h.removeLocked(sub, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Subscribers returns the number of current subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close ends all subscriptions with ErrClosed and rejects new ones. Events
+// published afterwards are dropped.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.removeLocked(sub, ErrClosed)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub, err)
+}
+
+// removeLocked ends sub with err; h.mu must be held
+func (h *Hub) removeLocked(sub *Subscription, err error) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	sub.err = err
+	close(sub.events)
+}
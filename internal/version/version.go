@@ -0,0 +1,32 @@
+// Package version holds build information injected at link time, e.g.
+//
+//	go build -ldflags "-X k8watch/internal/version.Version=1.2.3"
+package version
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+var buildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "kubewatcher_build_info",
+	Help: "Always 1; labeled with the version, commit and Go version of the running binary.",
+}, []string{"version", "commit", "goversion"})
+
+func init() {
+	buildInfo.WithLabelValues(Version, GitCommit, runtime.Version()).Set(1)
+}
+
+// String describes the build for --version
+func String() string {
+	return fmt.Sprintf("k8watch version %s (commit %s, built %s)", Version, GitCommit, BuildDate)
+}
@@ -3,23 +3,31 @@ package watcher
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"maps"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"k8watch/internal/storage"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 )
 
 // watchServices watches service changes
-func (w *Watcher) watchServices() {
+func (w *Watcher) watchServices(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.CoreV1().RESTClient(),
 		"services",
@@ -30,21 +38,24 @@ func (w *Watcher) watchServices() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&corev1.Service{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleServiceEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleServiceEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleServiceEvent(watch.Deleted, obj, nil)
+				w.handleServiceEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleServiceEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -81,11 +92,7 @@ func (w *Watcher) handleServiceEvent(eventType watch.EventType, oldObj, newObj i
 			Diff:      changeDesc,
 		}
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving service event: %v", err)
-		} else {
-			log.Printf("Saved %s event for service %s/%s", eventType, svc.Namespace, svc.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -99,11 +106,7 @@ func (w *Watcher) handleServiceEvent(eventType watch.EventType, oldObj, newObj i
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving service event: %v", err)
-	} else {
-		log.Printf("Saved %s event for service %s/%s", eventType, svc.Namespace, svc.Name)
-	}
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 }
 
 // detectServiceChanges checks for meaningful service changes
@@ -149,7 +152,7 @@ func (w *Watcher) detectServiceChanges(oldSvc, newSvc *corev1.Service) (bool, st
 }
 
 // watchIngresses watches ingress changes
-func (w *Watcher) watchIngresses() {
+func (w *Watcher) watchIngresses(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.NetworkingV1().RESTClient(),
 		"ingresses",
@@ -160,21 +163,24 @@ func (w *Watcher) watchIngresses() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&networkingv1.Ingress{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleIngressEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleIngressEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleIngressEvent(watch.Deleted, obj, nil)
+				w.handleIngressEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleIngressEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -211,11 +217,7 @@ func (w *Watcher) handleIngressEvent(eventType watch.EventType, oldObj, newObj i
 			Diff:      changeDesc,
 		}
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving ingress event: %v", err)
-		} else {
-			log.Printf("Saved %s event for ingress %s/%s", eventType, ingress.Namespace, ingress.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -229,11 +231,55 @@ func (w *Watcher) handleIngressEvent(eventType watch.EventType, oldObj, newObj i
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving ingress event: %v", err)
-	} else {
-		log.Printf("Saved %s event for ingress %s/%s", eventType, ingress.Namespace, ingress.Name)
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// defaultIngressAnnotations are the Ingress annotations whose changes are
+// recorded. Entries ending in "/" match every annotation with that prefix.
+var defaultIngressAnnotations = []string{
+	"cert-manager.io/cluster-issuer",
+	"kubernetes.io/ingress.class",
+	"konghq.com/",
+	"nginx.ingress.kubernetes.io/",
+}
+
+// annotationMatches reports whether key is pattern, or starts with it if
+// pattern ends in "/"
+func annotationMatches(key, pattern string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(key, pattern)
 	}
+	return key == pattern
+}
+
+// changedAnnotations returns the sorted keys matching any of patterns that
+// were added, removed or changed between oldAnn and newAnn
+func changedAnnotations(patterns []string, oldAnn, newAnn map[string]string) []string {
+	var keys []string
+	check := func(key string) {
+		if slices.Contains(keys, key) {
+			return
+		}
+		oldVal, oldExists := oldAnn[key]
+		newVal, newExists := newAnn[key]
+		if oldExists == newExists && oldVal == newVal {
+			return
+		}
+		for _, pattern := range patterns {
+			if annotationMatches(key, pattern) {
+				keys = append(keys, key)
+				return
+			}
+		}
+	}
+	for key := range oldAnn {
+		check(key)
+	}
+	for key := range newAnn {
+		check(key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // detectIngressChanges checks for meaningful ingress changes
@@ -241,23 +287,13 @@ func (w *Watcher) detectIngressChanges(oldIng, newIng *networkingv1.Ingress) (bo
 	changes := []string{}
 
 	// Check annotation changes (important ones)
-	importantAnnotations := []string{
-		"nginx.ingress.kubernetes.io/rewrite-target",
-		"cert-manager.io/cluster-issuer",
-		"kubernetes.io/ingress.class",
-		"konghq.com/",
-	}
-
-	for _, key := range importantAnnotations {
-		oldVal, oldExists := oldIng.Annotations[key]
-		newVal, newExists := newIng.Annotations[key]
-
-		if oldExists != newExists || (oldExists && oldVal != newVal) {
-			if newExists {
-				changes = append(changes, fmt.Sprintf("Annotation %s: '%s' → '%s'", key, oldVal, newVal))
-			} else {
-				changes = append(changes, fmt.Sprintf("Annotation %s removed", key))
-			}
+	patterns := append(append([]string{}, defaultIngressAnnotations...), w.opts.IngressAnnotationPrefixes...)
+	for _, key := range changedAnnotations(patterns, oldIng.Annotations, newIng.Annotations) {
+		oldVal := oldIng.Annotations[key]
+		if newVal, newExists := newIng.Annotations[key]; newExists {
+			changes = append(changes, fmt.Sprintf("Annotation %s: '%s' → '%s'", key, oldVal, newVal))
+		} else {
+			changes = append(changes, fmt.Sprintf("Annotation %s removed", key))
 		}
 	}
 
@@ -316,7 +352,7 @@ func (w *Watcher) detectIngressChanges(oldIng, newIng *networkingv1.Ingress) (bo
 }
 
 // watchStatefulSets watches statefulset changes
-func (w *Watcher) watchStatefulSets() {
+func (w *Watcher) watchStatefulSets(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.AppsV1().RESTClient(),
 		"statefulsets",
@@ -327,21 +363,24 @@ func (w *Watcher) watchStatefulSets() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&appsv1.StatefulSet{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleStatefulSetEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleStatefulSetEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleStatefulSetEvent(watch.Deleted, obj, nil)
+				w.handleStatefulSetEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleStatefulSetEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -384,11 +423,7 @@ func (w *Watcher) handleStatefulSetEvent(eventType watch.EventType, oldObj, newO
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving statefulset event: %v", err)
-		} else {
-			log.Printf("Saved %s event for statefulset %s/%s", eventType, ss.Namespace, ss.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -401,11 +436,7 @@ func (w *Watcher) handleStatefulSetEvent(eventType watch.EventType, oldObj, newO
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving statefulset event: %v", err)
-	} else {
-		log.Printf("Saved %s event for statefulset %s/%s", eventType, ss.Namespace, ss.Name)
-	}
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 }
 
 // detectStatefulSetChanges checks for meaningful statefulset changes
@@ -429,6 +460,8 @@ func (w *Watcher) detectStatefulSetChanges(oldSS, newSS *appsv1.StatefulSet) (bo
 		}
 	}
 
+	changes = append(changes, resourceChanges(oldSS.Spec.Template.Spec, newSS.Spec.Template.Spec)...)
+
 	// Check service name changes
 	if oldSS.Spec.ServiceName != newSS.Spec.ServiceName {
 		changes = append(changes, fmt.Sprintf("Service name: %s → %s", oldSS.Spec.ServiceName, newSS.Spec.ServiceName))
@@ -439,6 +472,8 @@ func (w *Watcher) detectStatefulSetChanges(oldSS, newSS *appsv1.StatefulSet) (bo
 		changes = append(changes, fmt.Sprintf("Volume claim templates: %d → %d", len(oldSS.Spec.VolumeClaimTemplates), len(newSS.Spec.VolumeClaimTemplates)))
 	}
 
+	changes = append(changes, privilegedContainersAdded(oldSS.Spec.Template.Spec, newSS.Spec.Template.Spec)...)
+
 	// Check update strategy
 	if oldSS.Spec.UpdateStrategy.Type != newSS.Spec.UpdateStrategy.Type {
 		changes = append(changes, fmt.Sprintf("Update strategy: %s → %s", oldSS.Spec.UpdateStrategy.Type, newSS.Spec.UpdateStrategy.Type))
@@ -452,7 +487,7 @@ func (w *Watcher) detectStatefulSetChanges(oldSS, newSS *appsv1.StatefulSet) (bo
 }
 
 // watchDaemonSets watches daemonset changes
-func (w *Watcher) watchDaemonSets() {
+func (w *Watcher) watchDaemonSets(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.AppsV1().RESTClient(),
 		"daemonsets",
@@ -463,21 +498,24 @@ func (w *Watcher) watchDaemonSets() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&appsv1.DaemonSet{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleDaemonSetEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleDaemonSetEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleDaemonSetEvent(watch.Deleted, obj, nil)
+				w.handleDaemonSetEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleDaemonSetEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -514,11 +552,7 @@ func (w *Watcher) handleDaemonSetEvent(eventType watch.EventType, oldObj, newObj
 			Diff:      diff,
 		}
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving daemonset event: %v", err)
-		} else {
-			log.Printf("Saved %s event for daemonset %s/%s", eventType, ds.Namespace, ds.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -531,11 +565,7 @@ func (w *Watcher) handleDaemonSetEvent(eventType watch.EventType, oldObj, newObj
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving daemonset event: %v", err)
-	} else {
-		log.Printf("Saved %s event for daemonset %s/%s", eventType, ds.Namespace, ds.Name)
-	}
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 }
 
 // detectDaemonSetChanges checks for meaningful daemonset changes
@@ -554,6 +584,9 @@ func (w *Watcher) detectDaemonSetChanges(oldDS, newDS *appsv1.DaemonSet) (bool,
 		}
 	}
 
+	changes = append(changes, resourceChanges(oldDS.Spec.Template.Spec, newDS.Spec.Template.Spec)...)
+	changes = append(changes, privilegedContainersAdded(oldDS.Spec.Template.Spec, newDS.Spec.Template.Spec)...)
+
 	// Check update strategy
 	if oldDS.Spec.UpdateStrategy.Type != newDS.Spec.UpdateStrategy.Type {
 		changes = append(changes, fmt.Sprintf("Update strategy: %s → %s", oldDS.Spec.UpdateStrategy.Type, newDS.Spec.UpdateStrategy.Type))
@@ -572,7 +605,7 @@ func (w *Watcher) detectDaemonSetChanges(oldDS, newDS *appsv1.DaemonSet) (bool,
 }
 
 // watchCronJobs watches cronjob changes
-func (w *Watcher) watchCronJobs() {
+func (w *Watcher) watchCronJobs(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.BatchV1().RESTClient(),
 		"cronjobs",
@@ -583,21 +616,24 @@ func (w *Watcher) watchCronJobs() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&batchv1.CronJob{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleCronJobEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleCronJobEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleCronJobEvent(watch.Deleted, obj, nil)
+				w.handleCronJobEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleCronJobEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -634,11 +670,7 @@ func (w *Watcher) handleCronJobEvent(eventType watch.EventType, oldObj, newObj i
 			Diff:      diff,
 		}
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving cronjob event: %v", err)
-		} else {
-			log.Printf("Saved %s event for cronjob %s/%s", eventType, cronjob.Namespace, cronjob.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -651,11 +683,7 @@ func (w *Watcher) handleCronJobEvent(eventType watch.EventType, oldObj, newObj i
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving cronjob event: %v", err)
-	} else {
-		log.Printf("Saved %s event for cronjob %s/%s", eventType, cronjob.Namespace, cronjob.Name)
-	}
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 }
 
 // detectCronJobChanges checks for meaningful cronjob changes
@@ -699,7 +727,7 @@ func (w *Watcher) detectCronJobChanges(oldCJ, newCJ *batchv1.CronJob) (bool, str
 }
 
 // watchJobs watches job changes
-func (w *Watcher) watchJobs() {
+func (w *Watcher) watchJobs(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.BatchV1().RESTClient(),
 		"jobs",
@@ -710,21 +738,24 @@ func (w *Watcher) watchJobs() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&batchv1.Job{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleJobEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleJobEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleJobEvent(watch.Deleted, obj, nil)
+				w.handleJobEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 func (w *Watcher) handleJobEvent(eventType watch.EventType, oldObj, newObj interface{}) {
@@ -766,11 +797,7 @@ func (w *Watcher) handleJobEvent(eventType watch.EventType, oldObj, newObj inter
 			Diff:      diff,
 		}
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving job event: %v", err)
-		} else {
-			log.Printf("Saved %s event for job %s/%s", eventType, job.Namespace, job.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -783,11 +810,7 @@ func (w *Watcher) handleJobEvent(eventType watch.EventType, oldObj, newObj inter
 		Diff:      string(eventType),
 	}
 
-	if err := w.saveAndNotify(event); err != nil {
-		log.Printf("Error saving job event: %v", err)
-	} else {
-		log.Printf("Saved %s event for job %s/%s", eventType, job.Namespace, job.Name)
-	}
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 }
 
 // detectJobChanges checks for meaningful job changes
@@ -827,3 +850,857 @@ func (w *Watcher) detectJobChanges(oldJob, newJob *batchv1.Job) (bool, string) {
 
 	return true, "Job configuration changed:\n" + strings.Join(changes, "\n")
 }
+
+// watchPodDisruptionBudgets watches poddisruptionbudget changes
+func (w *Watcher) watchPodDisruptionBudgets(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.PolicyV1().RESTClient(),
+		"poddisruptionbudgets",
+		corev1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&policyv1.PodDisruptionBudget{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handlePDBEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handlePDBEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handlePDBEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handlePDBEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var pdb *policyv1.PodDisruptionBudget
+	var oldPDB *policyv1.PodDisruptionBudget
+
+	if newObj != nil {
+		pdb = newObj.(*policyv1.PodDisruptionBudget)
+	} else if oldObj != nil {
+		pdb = oldObj.(*policyv1.PodDisruptionBudget)
+	}
+
+	if oldObj != nil {
+		oldPDB = oldObj.(*policyv1.PodDisruptionBudget)
+	}
+
+	if pdb.Namespace == "kube-system" || pdb.Namespace == "kube-public" || pdb.Namespace == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: pdb.Namespace,
+		Kind:      "PodDisruptionBudget",
+		Name:      pdb.Name,
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	switch {
+	case eventType == watch.Modified && oldPDB != nil:
+		hasChanges, diff := w.detectPDBChanges(oldPDB, pdb)
+		if !hasChanges {
+			return // Ignore status-only updates
+		}
+		event.Diff = diff
+	case eventType == watch.Deleted:
+		// Deleting a PDB removes the disruption protection of its pods
+		event.Severity = storage.SeverityWarn
+		event.Diff = "PodDisruptionBudget deleted, pods matching " + metav1.FormatLabelSelector(pdb.Spec.Selector) + " are no longer protected"
+	}
+
+	metadata := map[string]interface{}{
+		"minAvailable":       intOrStringValue(pdb.Spec.MinAvailable),
+		"maxUnavailable":     intOrStringValue(pdb.Spec.MaxUnavailable),
+		"disruptionsAllowed": pdb.Status.DisruptionsAllowed,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectPDBChanges checks for meaningful poddisruptionbudget changes
+func (w *Watcher) detectPDBChanges(oldPDB, newPDB *policyv1.PodDisruptionBudget) (bool, string) {
+	changes := []string{}
+
+	if oldVal, newVal := intOrStringValue(oldPDB.Spec.MinAvailable), intOrStringValue(newPDB.Spec.MinAvailable); oldVal != newVal {
+		changes = append(changes, fmt.Sprintf("MinAvailable: %s → %s", oldVal, newVal))
+	}
+	if oldVal, newVal := intOrStringValue(oldPDB.Spec.MaxUnavailable), intOrStringValue(newPDB.Spec.MaxUnavailable); oldVal != newVal {
+		changes = append(changes, fmt.Sprintf("MaxUnavailable: %s → %s", oldVal, newVal))
+	}
+	if oldSel, newSel := metav1.FormatLabelSelector(oldPDB.Spec.Selector), metav1.FormatLabelSelector(newPDB.Spec.Selector); oldSel != newSel {
+		changes = append(changes, fmt.Sprintf("Selector: %s → %s", oldSel, newSel))
+	}
+
+	if len(changes) == 0 {
+		return false, ""
+	}
+
+	return true, "PodDisruptionBudget configuration changed:\n" + strings.Join(changes, "\n")
+}
+
+// intOrStringValue formats an optional IntOrString, using "none" when unset
+func intOrStringValue(v *intstr.IntOrString) string {
+	if v == nil {
+		return "none"
+	}
+	return v.String()
+}
+
+// clusterWideNamespace is stored as the namespace of cluster-scoped resources
+const clusterWideNamespace = "cluster-wide"
+
+// watchVolumeAttachments watches volumeattachment changes
+func (w *Watcher) watchVolumeAttachments(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.StorageV1().RESTClient(),
+		"volumeattachments",
+		metav1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&storagev1.VolumeAttachment{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleVolumeAttachmentEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleVolumeAttachmentEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleVolumeAttachmentEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleVolumeAttachmentEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var va *storagev1.VolumeAttachment
+	var oldVA *storagev1.VolumeAttachment
+
+	if newObj != nil {
+		va = newObj.(*storagev1.VolumeAttachment)
+	} else if oldObj != nil {
+		va = oldObj.(*storagev1.VolumeAttachment)
+	}
+
+	if oldObj != nil {
+		oldVA = oldObj.(*storagev1.VolumeAttachment)
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: clusterWideNamespace,
+		Kind:      "VolumeAttachment",
+		Name:      va.Name,
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldVA != nil {
+		hasChanges, diff, detached := w.detectVolumeAttachmentChanges(oldVA, va)
+		if !hasChanges {
+			return // Ignore status-only updates
+		}
+		event.Diff = diff
+		if detached {
+			// A volume that stops being attached may leave its workload without data
+			event.Severity = storage.SeverityWarn
+		}
+	}
+
+	pvName := ""
+	if va.Spec.Source.PersistentVolumeName != nil {
+		pvName = *va.Spec.Source.PersistentVolumeName
+	}
+	metadata := map[string]interface{}{
+		"attacher":         va.Spec.Attacher,
+		"nodeName":         va.Spec.NodeName,
+		"persistentVolume": pvName,
+		"attached":         va.Status.Attached,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectVolumeAttachmentChanges checks for meaningful volumeattachment changes.
+// detached reports whether the volume went from attached to not attached.
+func (w *Watcher) detectVolumeAttachmentChanges(oldVA, newVA *storagev1.VolumeAttachment) (hasChanges bool, diff string, detached bool) {
+	changes := []string{}
+
+	if oldVA.Spec.Attacher != newVA.Spec.Attacher {
+		changes = append(changes, fmt.Sprintf("Attacher: %s → %s", oldVA.Spec.Attacher, newVA.Spec.Attacher))
+	}
+	if oldVA.Status.Attached && !newVA.Status.Attached {
+		detached = true
+		reason := ""
+		if newVA.Status.DetachError != nil {
+			reason = ": " + newVA.Status.DetachError.Message
+		} else if newVA.Status.AttachError != nil {
+			reason = ": " + newVA.Status.AttachError.Message
+		}
+		changes = append(changes, fmt.Sprintf("Volume no longer attached to node %s%s", newVA.Spec.NodeName, reason))
+	}
+
+	if len(changes) == 0 {
+		return false, "", false
+	}
+
+	return true, "VolumeAttachment changed:\n" + strings.Join(changes, "\n"), detached
+}
+
+// watchStorageClasses watches storageclass changes
+func (w *Watcher) watchStorageClasses(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.StorageV1().RESTClient(),
+		"storageclasses",
+		metav1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&storagev1.StorageClass{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleStorageClassEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleStorageClassEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleStorageClassEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleStorageClassEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var sc *storagev1.StorageClass
+	var oldSC *storagev1.StorageClass
+
+	if newObj != nil {
+		sc = newObj.(*storagev1.StorageClass)
+	} else if oldObj != nil {
+		sc = oldObj.(*storagev1.StorageClass)
+	}
+
+	if oldObj != nil {
+		oldSC = oldObj.(*storagev1.StorageClass)
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: clusterWideNamespace,
+		Kind:      "StorageClass",
+		Name:      sc.Name,
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldSC != nil {
+		hasChanges, diff := w.detectStorageClassChanges(oldSC, sc)
+		if !hasChanges {
+			return // Ignore metadata-only updates
+		}
+		event.Diff = diff
+	}
+
+	metadata := map[string]interface{}{
+		"provisioner":          sc.Provisioner,
+		"reclaimPolicy":        reclaimPolicyValue(sc.ReclaimPolicy),
+		"volumeBindingMode":    bindingModeValue(sc.VolumeBindingMode),
+		"allowVolumeExpansion": boolValue(sc.AllowVolumeExpansion),
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectStorageClassChanges checks for meaningful storageclass changes
+func (w *Watcher) detectStorageClassChanges(oldSC, newSC *storagev1.StorageClass) (bool, string) {
+	changes := []string{}
+
+	if oldSC.Provisioner != newSC.Provisioner {
+		changes = append(changes, fmt.Sprintf("Provisioner: %s → %s", oldSC.Provisioner, newSC.Provisioner))
+	}
+
+	keys := make(map[string]bool)
+	for key := range oldSC.Parameters {
+		keys[key] = true
+	}
+	for key := range newSC.Parameters {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		oldVal, oldExists := oldSC.Parameters[key]
+		newVal, newExists := newSC.Parameters[key]
+		switch {
+		case !oldExists:
+			changes = append(changes, fmt.Sprintf("Parameter %s added: %s", key, newVal))
+		case !newExists:
+			changes = append(changes, fmt.Sprintf("Parameter %s removed", key))
+		case oldVal != newVal:
+			changes = append(changes, fmt.Sprintf("Parameter %s: %s → %s", key, oldVal, newVal))
+		}
+	}
+
+	if oldVal, newVal := reclaimPolicyValue(oldSC.ReclaimPolicy), reclaimPolicyValue(newSC.ReclaimPolicy); oldVal != newVal {
+		changes = append(changes, fmt.Sprintf("Reclaim policy: %s → %s", oldVal, newVal))
+	}
+	if oldVal, newVal := bindingModeValue(oldSC.VolumeBindingMode), bindingModeValue(newSC.VolumeBindingMode); oldVal != newVal {
+		changes = append(changes, fmt.Sprintf("Volume binding mode: %s → %s", oldVal, newVal))
+	}
+	if oldVal, newVal := boolValue(oldSC.AllowVolumeExpansion), boolValue(newSC.AllowVolumeExpansion); oldVal != newVal {
+		changes = append(changes, fmt.Sprintf("Allow volume expansion: %t → %t", oldVal, newVal))
+	}
+
+	if len(changes) == 0 {
+		return false, ""
+	}
+
+	return true, "StorageClass configuration changed:\n" + strings.Join(changes, "\n")
+}
+
+// reclaimPolicyValue returns the reclaim policy, which defaults to Delete
+func reclaimPolicyValue(policy *corev1.PersistentVolumeReclaimPolicy) string {
+	if policy == nil {
+		return string(corev1.PersistentVolumeReclaimDelete)
+	}
+	return string(*policy)
+}
+
+// bindingModeValue returns the volume binding mode, which defaults to Immediate
+func bindingModeValue(mode *storagev1.VolumeBindingMode) string {
+	if mode == nil {
+		return string(storagev1.VolumeBindingImmediate)
+	}
+	return string(*mode)
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// webhookSummary holds the fields of one admission webhook that are compared
+// between versions of a webhook configuration
+type webhookSummary struct {
+	failurePolicy     string
+	namespaceSelector string
+	rules             string
+}
+
+// watchMutatingWebhookConfigurations watches mutatingwebhookconfiguration changes
+func (w *Watcher) watchMutatingWebhookConfigurations(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.AdmissionregistrationV1().RESTClient(),
+		"mutatingwebhookconfigurations",
+		metav1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&admissionregistrationv1.MutatingWebhookConfiguration{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleMutatingWebhookEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleMutatingWebhookEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleMutatingWebhookEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleMutatingWebhookEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var config *admissionregistrationv1.MutatingWebhookConfiguration
+	var oldSummaries map[string]webhookSummary
+
+	if newObj != nil {
+		config = newObj.(*admissionregistrationv1.MutatingWebhookConfiguration)
+	} else if oldObj != nil {
+		config = oldObj.(*admissionregistrationv1.MutatingWebhookConfiguration)
+	}
+
+	if oldObj != nil {
+		oldSummaries = mutatingWebhookSummaries(oldObj.(*admissionregistrationv1.MutatingWebhookConfiguration))
+	}
+
+	w.handleWebhookConfigurationEvent(eventType, "MutatingWebhookConfiguration", config.Name,
+		oldSummaries, mutatingWebhookSummaries(config), oldObj, newObj)
+}
+
+func mutatingWebhookSummaries(config *admissionregistrationv1.MutatingWebhookConfiguration) map[string]webhookSummary {
+	summaries := make(map[string]webhookSummary, len(config.Webhooks))
+	for _, webhook := range config.Webhooks {
+		summaries[webhook.Name] = summarizeWebhook(webhook.FailurePolicy, webhook.NamespaceSelector, webhook.Rules)
+	}
+	return summaries
+}
+
+// watchValidatingWebhookConfigurations watches validatingwebhookconfiguration changes
+func (w *Watcher) watchValidatingWebhookConfigurations(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.AdmissionregistrationV1().RESTClient(),
+		"validatingwebhookconfigurations",
+		metav1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&admissionregistrationv1.ValidatingWebhookConfiguration{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleValidatingWebhookEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleValidatingWebhookEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleValidatingWebhookEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleValidatingWebhookEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var config *admissionregistrationv1.ValidatingWebhookConfiguration
+	var oldSummaries map[string]webhookSummary
+
+	if newObj != nil {
+		config = newObj.(*admissionregistrationv1.ValidatingWebhookConfiguration)
+	} else if oldObj != nil {
+		config = oldObj.(*admissionregistrationv1.ValidatingWebhookConfiguration)
+	}
+
+	if oldObj != nil {
+		oldSummaries = validatingWebhookSummaries(oldObj.(*admissionregistrationv1.ValidatingWebhookConfiguration))
+	}
+
+	w.handleWebhookConfigurationEvent(eventType, "ValidatingWebhookConfiguration", config.Name,
+		oldSummaries, validatingWebhookSummaries(config), oldObj, newObj)
+}
+
+func validatingWebhookSummaries(config *admissionregistrationv1.ValidatingWebhookConfiguration) map[string]webhookSummary {
+	summaries := make(map[string]webhookSummary, len(config.Webhooks))
+	for _, webhook := range config.Webhooks {
+		summaries[webhook.Name] = summarizeWebhook(webhook.FailurePolicy, webhook.NamespaceSelector, webhook.Rules)
+	}
+	return summaries
+}
+
+// handleWebhookConfigurationEvent records a change to either kind of webhook
+// configuration. A broken webhook can block pod creation cluster-wide, so
+// every event is critical.
+func (w *Watcher) handleWebhookConfigurationEvent(eventType watch.EventType, kind, name string, oldSummaries, newSummaries map[string]webhookSummary, oldObj, newObj interface{}) {
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: clusterWideNamespace,
+		Kind:      kind,
+		Name:      name,
+		Action:    string(eventType),
+		Severity:  storage.SeverityCritical,
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldSummaries != nil {
+		hasChanges, diff := detectWebhookChanges(oldSummaries, newSummaries)
+		if !hasChanges {
+			return // Ignore CA bundle rotations and metadata updates
+		}
+		event.Diff = kind + " changed:\n" + diff
+	}
+
+	webhooks := make([]string, 0, len(newSummaries))
+	for webhookName := range newSummaries {
+		webhooks = append(webhooks, webhookName)
+	}
+	sort.Strings(webhooks)
+	metadata := map[string]interface{}{
+		"webhooks": webhooks,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectWebhookChanges compares webhooks by name
+func detectWebhookChanges(oldSummaries, newSummaries map[string]webhookSummary) (bool, string) {
+	names := make(map[string]bool)
+	for name := range oldSummaries {
+		names[name] = true
+	}
+	for name := range newSummaries {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	changes := []string{}
+	for _, name := range sortedNames {
+		oldWebhook, oldExists := oldSummaries[name]
+		newWebhook, newExists := newSummaries[name]
+		switch {
+		case !oldExists:
+			changes = append(changes, fmt.Sprintf("Webhook %s added (failure policy %s)", name, newWebhook.failurePolicy))
+		case !newExists:
+			changes = append(changes, fmt.Sprintf("Webhook %s removed", name))
+		default:
+			if oldWebhook.failurePolicy != newWebhook.failurePolicy {
+				changes = append(changes, fmt.Sprintf("Webhook %s failure policy: %s → %s", name, oldWebhook.failurePolicy, newWebhook.failurePolicy))
+			}
+			if oldWebhook.namespaceSelector != newWebhook.namespaceSelector {
+				changes = append(changes, fmt.Sprintf("Webhook %s namespace selector: %s → %s", name, oldWebhook.namespaceSelector, newWebhook.namespaceSelector))
+			}
+			if oldWebhook.rules != newWebhook.rules {
+				changes = append(changes, fmt.Sprintf("Webhook %s rules: %s → %s", name, oldWebhook.rules, newWebhook.rules))
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return false, ""
+	}
+
+	return true, strings.Join(changes, "\n")
+}
+
+func summarizeWebhook(policy *admissionregistrationv1.FailurePolicyType, selector *metav1.LabelSelector, rules []admissionregistrationv1.RuleWithOperations) webhookSummary {
+	// The API server defaults an unset failure policy to Fail
+	failurePolicy := string(admissionregistrationv1.Fail)
+	if policy != nil {
+		failurePolicy = string(*policy)
+	}
+
+	namespaceSelector := "<all>"
+	if selector != nil {
+		namespaceSelector = metav1.FormatLabelSelector(selector)
+	}
+
+	formatted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		operations := make([]string, 0, len(rule.Operations))
+		for _, op := range rule.Operations {
+			operations = append(operations, string(op))
+		}
+		scope := "*"
+		if rule.Scope != nil {
+			scope = string(*rule.Scope)
+		}
+		formatted = append(formatted, fmt.Sprintf("%s %s/%s %s (%s)",
+			strings.Join(operations, ","),
+			strings.Join(rule.APIGroups, ","),
+			strings.Join(rule.APIVersions, ","),
+			strings.Join(rule.Resources, ","),
+			scope))
+	}
+
+	return webhookSummary{
+		failurePolicy:     failurePolicy,
+		namespaceSelector: namespaceSelector,
+		rules:             "[" + strings.Join(formatted, "; ") + "]",
+	}
+}
+
+// watchReplicaSets watches replicaset changes
+func (w *Watcher) watchReplicaSets(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.AppsV1().RESTClient(),
+		"replicasets",
+		corev1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&appsv1.ReplicaSet{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleReplicaSetEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleReplicaSetEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleReplicaSetEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleReplicaSetEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var rs *appsv1.ReplicaSet
+	var oldRS *appsv1.ReplicaSet
+
+	if newObj != nil {
+		rs = newObj.(*appsv1.ReplicaSet)
+	} else if oldObj != nil {
+		rs = oldObj.(*appsv1.ReplicaSet)
+	}
+
+	if oldObj != nil {
+		oldRS = oldObj.(*appsv1.ReplicaSet)
+	}
+
+	if rs.Namespace == "kube-system" || rs.Namespace == "kube-public" || rs.Namespace == "kube-node-lease" {
+		return
+	}
+
+	ownerDeployment := ""
+	if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+		ownerDeployment = owner.Name
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: rs.Namespace,
+		Kind:      "ReplicaSet",
+		Name:      rs.Name,
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	switch {
+	case eventType == watch.Modified && oldRS != nil:
+		hasChanges, diff := w.detectReplicaSetChanges(oldRS, rs)
+		if !hasChanges {
+			return // Ignore scaling steps that keep the ReplicaSet active or inactive
+		}
+		event.Diff = diff
+	case ownerDeployment != "":
+		// The Deployment creates and garbage-collects its ReplicaSets on every
+		// rollout, and the initial sync lists all old revisions; only the
+		// activation changes above add to the Deployment's own events
+		return
+	}
+
+	event.ChangesetID = w.changesetFor(rs, ownerDeployment, event.Timestamp)
+
+	metadata := map[string]interface{}{
+		"ownerDeployment": ownerDeployment,
+		"podTemplateHash": rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+		"replicas":        rs.Status.Replicas,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectReplicaSetChanges reports a ReplicaSet becoming active (its first
+// pods are created) or inactive (its last pods are removed)
+func (w *Watcher) detectReplicaSetChanges(oldRS, newRS *appsv1.ReplicaSet) (bool, string) {
+	switch {
+	case oldRS.Status.Replicas == 0 && newRS.Status.Replicas > 0:
+		return true, fmt.Sprintf("ReplicaSet became active: 0 → %d replicas", newRS.Status.Replicas)
+	case oldRS.Status.Replicas > 0 && newRS.Status.Replicas == 0:
+		return true, fmt.Sprintf("ReplicaSet became inactive: %d → 0 replicas", oldRS.Status.Replicas)
+	}
+	return false, ""
+}
+
+// watchEndpointSlices watches endpointslice changes, i.e. which pods back a service
+func (w *Watcher) watchEndpointSlices(stopCh <-chan struct{}) {
+	watchlist := cache.NewListWatchFromClient(
+		w.clientset.DiscoveryV1().RESTClient(),
+		"endpointslices",
+		corev1.NamespaceAll,
+		fields.Everything(),
+	)
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&discoveryv1.EndpointSlice{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.handleEndpointSliceEvent(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				w.handleEndpointSliceEvent(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.handleEndpointSliceEvent(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+func (w *Watcher) handleEndpointSliceEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	var slice *discoveryv1.EndpointSlice
+	var oldSlice *discoveryv1.EndpointSlice
+
+	if newObj != nil {
+		slice = newObj.(*discoveryv1.EndpointSlice)
+	} else if oldObj != nil {
+		slice = oldObj.(*discoveryv1.EndpointSlice)
+	}
+
+	if oldObj != nil {
+		oldSlice = oldObj.(*discoveryv1.EndpointSlice)
+	}
+
+	if slice.Namespace == "kube-system" || slice.Namespace == "kube-public" || slice.Namespace == "kube-node-lease" {
+		return
+	}
+
+	// Skip the API server's own endpoints (the "kubernetes" service and the like)
+	serviceName := slice.Labels[discoveryv1.LabelServiceName]
+	if strings.HasPrefix(serviceName, "kubernetes") {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: slice.Namespace,
+		Kind:      "EndpointSlice",
+		Name:      slice.Name,
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldSlice != nil {
+		hasChanges, diff := w.detectEndpointSliceChanges(oldSlice, slice)
+		if !hasChanges {
+			return // Ignore updates that don't change endpoints or their readiness
+		}
+		event.Diff = diff
+	}
+
+	endpoints := endpointReadiness(slice)
+	ready := 0
+	for _, isReady := range endpoints {
+		if isReady {
+			ready++
+		}
+	}
+	metadata := map[string]interface{}{
+		"service":     serviceName,
+		"addressType": string(slice.AddressType),
+		"endpoints":   len(endpoints),
+		"ready":       ready,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectEndpointSliceChanges checks for endpoints that were added, removed or
+// changed readiness
+func (w *Watcher) detectEndpointSliceChanges(oldSlice, newSlice *discoveryv1.EndpointSlice) (bool, string) {
+	oldEndpoints := endpointReadiness(oldSlice)
+	newEndpoints := endpointReadiness(newSlice)
+
+	changes := []string{}
+	for _, name := range slices.Sorted(maps.Keys(newEndpoints)) {
+		wasReady, existed := oldEndpoints[name]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("Endpoint added: %s (ready: %t)", name, newEndpoints[name]))
+		case wasReady != newEndpoints[name]:
+			changes = append(changes, fmt.Sprintf("Endpoint %s ready: %t → %t", name, wasReady, newEndpoints[name]))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldEndpoints)) {
+		if _, exists := newEndpoints[name]; !exists {
+			changes = append(changes, "Endpoint removed: "+name)
+		}
+	}
+
+	if len(changes) == 0 {
+		return false, ""
+	}
+
+	return true, "EndpointSlice endpoints changed:\n" + strings.Join(changes, "\n")
+}
+
+// endpointReadiness maps each endpoint of a slice to whether it is ready.
+// Endpoints are named after their target pod, or their first address if they
+// have no target. A missing ready condition means ready.
+func endpointReadiness(slice *discoveryv1.EndpointSlice) map[string]bool {
+	endpoints := make(map[string]bool, len(slice.Endpoints))
+	for _, endpoint := range slice.Endpoints {
+		name := ""
+		if endpoint.TargetRef != nil {
+			name = endpoint.TargetRef.Name
+		} else if len(endpoint.Addresses) > 0 {
+			name = endpoint.Addresses[0]
+		}
+		if name == "" {
+			continue
+		}
+		endpoints[name] = endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+	}
+	return endpoints
+}
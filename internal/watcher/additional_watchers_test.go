@@ -0,0 +1,155 @@
+package watcher
+
+import (
+	"slices"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnnotationMatches(t *testing.T) {
+	tests := []struct {
+		key     string
+		pattern string
+		want    bool
+	}{
+		{"kubernetes.io/ingress.class", "kubernetes.io/ingress.class", true},
+		{"kubernetes.io/ingress.class.extra", "kubernetes.io/ingress.class", false},
+		{"kubernetes.io/ingress", "kubernetes.io/ingress.class", false},
+		{"nginx.ingress.kubernetes.io/rewrite-target", "nginx.ingress.kubernetes.io/", true},
+		{"nginx.ingress.kubernetes.io/", "nginx.ingress.kubernetes.io/", true},
+		{"nginx.ingress.kubernetes.io", "nginx.ingress.kubernetes.io/", false},
+		{"x-nginx.ingress.kubernetes.io/rewrite-target", "nginx.ingress.kubernetes.io/", false},
+		{"konghq.com/plugins", "konghq.com/", true},
+	}
+
+	for _, tt := range tests {
+		if got := annotationMatches(tt.key, tt.pattern); got != tt.want {
+			t.Errorf("annotationMatches(%q, %q) = %v, want %v", tt.key, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestChangedAnnotations(t *testing.T) {
+	patterns := []string{"kubernetes.io/ingress.class", "nginx.ingress.kubernetes.io/"}
+
+	tests := []struct {
+		name   string
+		oldAnn map[string]string
+		newAnn map[string]string
+		want   []string
+	}{
+		{
+			name:   "exact key changed",
+			oldAnn: map[string]string{"kubernetes.io/ingress.class": "nginx"},
+			newAnn: map[string]string{"kubernetes.io/ingress.class": "traefik"},
+			want:   []string{"kubernetes.io/ingress.class"},
+		},
+		{
+			name:   "prefix key added",
+			oldAnn: map[string]string{},
+			newAnn: map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"},
+			want:   []string{"nginx.ingress.kubernetes.io/ssl-redirect"},
+		},
+		{
+			name:   "prefix key removed",
+			oldAnn: map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"},
+			newAnn: nil,
+			want:   []string{"nginx.ingress.kubernetes.io/ssl-redirect"},
+		},
+		{
+			name:   "unwatched keys ignored",
+			oldAnn: map[string]string{"team": "a", "kubernetes.io/ingress.class.extra": "x"},
+			newAnn: map[string]string{"team": "b", "kubernetes.io/ingress.class.extra": "y"},
+		},
+		{
+			name:   "unchanged keys ignored",
+			oldAnn: map[string]string{"kubernetes.io/ingress.class": "nginx", "nginx.ingress.kubernetes.io/proxy-body-size": "8m"},
+			newAnn: map[string]string{"kubernetes.io/ingress.class": "nginx", "nginx.ingress.kubernetes.io/proxy-body-size": "8m"},
+		},
+		{
+			name: "sorted",
+			oldAnn: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target": "/",
+				"nginx.ingress.kubernetes.io/auth-url":       "http://auth",
+			},
+			newAnn: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target": "/$1",
+				"kubernetes.io/ingress.class":                "nginx",
+			},
+			want: []string{
+				"kubernetes.io/ingress.class",
+				"nginx.ingress.kubernetes.io/auth-url",
+				"nginx.ingress.kubernetes.io/rewrite-target",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedAnnotations(patterns, tt.oldAnn, tt.newAnn); !slices.Equal(got, tt.want) {
+				t.Errorf("changedAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectIngressAnnotationChanges(t *testing.T) {
+	ingress := func(annotations map[string]string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	tests := []struct {
+		name     string
+		prefixes []string
+		oldAnn   map[string]string
+		newAnn   map[string]string
+		want     string
+	}{
+		{
+			name:   "default exact annotation",
+			oldAnn: map[string]string{"cert-manager.io/cluster-issuer": "staging"},
+			newAnn: map[string]string{"cert-manager.io/cluster-issuer": "prod"},
+			want:   "Ingress configuration changed:\nAnnotation cert-manager.io/cluster-issuer: 'staging' → 'prod'",
+		},
+		{
+			name:   "default prefix removed",
+			oldAnn: map[string]string{"konghq.com/plugins": "rate-limit"},
+			newAnn: map[string]string{},
+			want:   "Ingress configuration changed:\nAnnotation konghq.com/plugins removed",
+		},
+		{
+			name:   "custom annotation ignored by default",
+			oldAnn: map[string]string{"traefik.ingress.kubernetes.io/router.tls": "false"},
+			newAnn: map[string]string{"traefik.ingress.kubernetes.io/router.tls": "true"},
+		},
+		{
+			name:     "custom prefix",
+			prefixes: []string{"traefik.ingress.kubernetes.io/"},
+			oldAnn:   map[string]string{"traefik.ingress.kubernetes.io/router.tls": "false"},
+			newAnn:   map[string]string{"traefik.ingress.kubernetes.io/router.tls": "true"},
+			want:     "Ingress configuration changed:\nAnnotation traefik.ingress.kubernetes.io/router.tls: 'false' → 'true'",
+		},
+		{
+			name:     "custom exact key",
+			prefixes: []string{"example.com/owner"},
+			oldAnn:   map[string]string{"example.com/owner": "team-a", "example.com/owner-email": "a@example.com"},
+			newAnn:   map[string]string{"example.com/owner": "team-b", "example.com/owner-email": "b@example.com"},
+			want:     "Ingress configuration changed:\nAnnotation example.com/owner: 'team-a' → 'team-b'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Watcher{opts: Options{IngressAnnotationPrefixes: tt.prefixes}}
+			hasChanges, diff := w.detectIngressChanges(ingress(tt.oldAnn), ingress(tt.newAnn))
+			if hasChanges != (tt.want != "") {
+				t.Errorf("hasChanges = %v, want %v", hasChanges, tt.want != "")
+			}
+			if diff != tt.want {
+				t.Errorf("diff = %q, want %q", diff, tt.want)
+			}
+		})
+	}
+}
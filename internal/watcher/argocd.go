@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var argoCDApplicationResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+// watchArgoCDApplications watches ArgoCD Application changes
+func (w *Watcher) watchArgoCDApplications(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, argoCDApplicationResource, w.handleArgoCDApplicationEvent)
+}
+
+// handleArgoCDApplicationEvent processes ArgoCD Application events
+func (w *Watcher) handleArgoCDApplicationEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	oldApp, app := unstructuredPair(oldObj, newObj)
+	if app == nil {
+		return
+	}
+
+	if app.GetNamespace() == "kube-system" || app.GetNamespace() == "kube-public" || app.GetNamespace() == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: app.GetNamespace(),
+		Kind:      "Application",
+		Name:      app.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldApp != nil {
+		hasChanges, diff, severity := w.detectArgoCDApplicationChanges(oldApp, app)
+		if !hasChanges {
+			return // Ignore refreshes that don't change the source, sync or health status
+		}
+		event.Diff = diff
+		event.Severity = severity
+	}
+
+	repoURL, _, _ := unstructured.NestedString(app.Object, "spec", "source", "repoURL")
+	targetRevision, _, _ := unstructured.NestedString(app.Object, "spec", "source", "targetRevision")
+	syncStatus, _, _ := unstructured.NestedString(app.Object, "status", "sync", "status")
+	healthStatus, _, _ := unstructured.NestedString(app.Object, "status", "health", "status")
+	revision, _, _ := unstructured.NestedString(app.Object, "status", "sync", "revision")
+	metadata := map[string]interface{}{
+		"repoURL":        repoURL,
+		"targetRevision": targetRevision,
+		"syncStatus":     syncStatus,
+		"healthStatus":   healthStatus,
+		"revision":       revision,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectArgoCDApplicationChanges checks for a changed repo URL or target
+// revision and for sync and health status transitions. severity is critical
+// when the Application turns Degraded, warn when it drifts from Synced to
+// OutOfSync and empty otherwise, which leaves it to the severity rules.
+func (w *Watcher) detectArgoCDApplicationChanges(oldApp, newApp *unstructured.Unstructured) (hasChanges bool, diff string, severity string) {
+	changes := []string{}
+
+	oldRepo, _, _ := unstructured.NestedString(oldApp.Object, "spec", "source", "repoURL")
+	newRepo, _, _ := unstructured.NestedString(newApp.Object, "spec", "source", "repoURL")
+	if oldRepo != newRepo {
+		changes = append(changes, fmt.Sprintf("Repo URL: %s → %s", oldRepo, newRepo))
+	}
+
+	oldTarget, _, _ := unstructured.NestedString(oldApp.Object, "spec", "source", "targetRevision")
+	newTarget, _, _ := unstructured.NestedString(newApp.Object, "spec", "source", "targetRevision")
+	if oldTarget != newTarget {
+		changes = append(changes, fmt.Sprintf("Target revision: %s → %s", oldTarget, newTarget))
+	}
+
+	oldSync, _, _ := unstructured.NestedString(oldApp.Object, "status", "sync", "status")
+	newSync, _, _ := unstructured.NestedString(newApp.Object, "status", "sync", "status")
+	if oldSync != newSync && oldSync != "" && newSync != "" {
+		changes = append(changes, fmt.Sprintf("Sync status: %s → %s", oldSync, newSync))
+		if oldSync == "Synced" && newSync == "OutOfSync" {
+			severity = storage.SeverityWarn
+		}
+	}
+
+	oldHealth, _, _ := unstructured.NestedString(oldApp.Object, "status", "health", "status")
+	newHealth, _, _ := unstructured.NestedString(newApp.Object, "status", "health", "status")
+	if oldHealth != newHealth && oldHealth != "" && newHealth != "" {
+		change := fmt.Sprintf("Health status: %s → %s", oldHealth, newHealth)
+		if newHealth == "Degraded" {
+			severity = storage.SeverityCritical
+			if message, _, _ := unstructured.NestedString(newApp.Object, "status", "health", "message"); message != "" {
+				change += ": " + message
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if len(changes) > 0 {
+		return true, "Application changes:\n" + strings.Join(changes, "\n"), severity
+	}
+	return false, "", ""
+}
@@ -0,0 +1,65 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// changesetWindow is how long after a Deployment change the ReplicaSet events
+// of the rollout it started join the Deployment's changeset
+const changesetWindow = 30 * time.Second
+
+// openChangeset is a changeset that ReplicaSet events can still join
+type openChangeset struct {
+	id      string
+	started time.Time
+}
+
+// startChangeset returns a new changeset ID for a Deployment change. ReplicaSets
+// owned by the Deployment join it for changesetWindow.
+func (w *Watcher) startChangeset(namespace, deployment string, now time.Time) string {
+	id := uuid.NewString()
+
+	w.changesetMu.Lock()
+	defer w.changesetMu.Unlock()
+
+	for key, cs := range w.changesets {
+		if now.Sub(cs.started) > changesetWindow {
+			delete(w.changesets, key)
+		}
+	}
+	w.changesets["deployment/"+namespace+"/"+deployment] = openChangeset{id: id, started: now}
+	return id
+}
+
+// changesetFor returns the changeset a ReplicaSet event joins, or "" if none:
+// the open changeset of its owner Deployment, or of an earlier ReplicaSet
+// event with the same pod template hash. Joining through the owner also opens
+// the changeset to the pod template hash.
+func (w *Watcher) changesetFor(rs *appsv1.ReplicaSet, ownerDeployment string, now time.Time) string {
+	w.changesetMu.Lock()
+	defer w.changesetMu.Unlock()
+
+	hash := rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	keys := []string{}
+	if ownerDeployment != "" {
+		keys = append(keys, "deployment/"+rs.Namespace+"/"+ownerDeployment)
+	}
+	if hash != "" {
+		keys = append(keys, "hash/"+rs.Namespace+"/"+hash)
+	}
+
+	for _, key := range keys {
+		cs, ok := w.changesets[key]
+		if !ok || now.Sub(cs.started) > changesetWindow {
+			continue
+		}
+		if hash != "" {
+			w.changesets["hash/"+rs.Namespace+"/"+hash] = cs
+		}
+		return cs.id
+	}
+	return ""
+}
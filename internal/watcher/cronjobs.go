@@ -0,0 +1,141 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runCronJobChecks looks for CronJobs that stopped running every
+// CronJobCheckInterval until stopCh is closed
+func (w *Watcher) runCronJobChecks(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(w.opts.CronJobCheckInterval)
+	defer ticker.Stop()
+
+	// The last run each missed CronJob was reported for, so each stop is
+	// reported once
+	reported := make(map[string]time.Time)
+	for {
+		w.checkCronJobs(reported)
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkCronJobs reports CronJobs that missed at least two scheduled runs
+func (w *Watcher) checkCronJobs(reported map[string]time.Time) {
+	cronjobs, err := w.clientset.BatchV1().CronJobs(corev1.NamespaceAll).List(w.ctx, metav1.ListOptions{})
+	if err != nil {
+		w.logger.Warn("Failed to list cronjobs", slog.Any("error", err))
+		return
+	}
+
+	now := time.Now()
+	current := make(map[string]time.Time)
+	for i := range cronjobs.Items {
+		cronjob := &cronjobs.Items[i]
+		if cronjob.Namespace == "kube-system" || cronjob.Namespace == "kube-public" || cronjob.Namespace == "kube-node-lease" {
+			continue
+		}
+		if cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend {
+			continue
+		}
+
+		lastRun, expected, ok := w.missedSchedule(cronjob, now)
+		if !ok {
+			continue
+		}
+		key := cronjob.Namespace + "/" + cronjob.Name
+		current[key] = lastRun
+		if last, seen := reported[key]; !seen || !last.Equal(lastRun) {
+			w.reportMissedSchedule(cronjob, lastRun, expected)
+		}
+	}
+
+	// Forget CronJobs that ran again so a later stop is reported again
+	for key := range reported {
+		delete(reported, key)
+	}
+	for key, lastRun := range current {
+		reported[key] = lastRun
+	}
+}
+
+// missedSchedule returns the CronJob's last run and the first run it missed
+// if the schedule has fired twice since the last run, i.e. the last run is
+// more than two periods old. A CronJob that never ran is measured from its
+// creation.
+func (w *Watcher) missedSchedule(cronjob *batchv1.CronJob, now time.Time) (lastRun, expected time.Time, missed bool) {
+	// The CronJob controller parses schedules the same way, with
+	// spec.timeZone applied as a CRON_TZ= prefix. Without a zone the
+	// schedule runs in the controller manager's zone, which is UTC in practice.
+	spec := cronjob.Spec.Schedule
+	if cronjob.Spec.TimeZone != nil {
+		spec = "CRON_TZ=" + *cronjob.Spec.TimeZone + " " + spec
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		w.logger.Debug("Skipping CronJob with unparsable schedule",
+			slog.String("namespace", cronjob.Namespace), slog.String("name", cronjob.Name), slog.Any("error", err))
+		return time.Time{}, time.Time{}, false
+	}
+
+	lastRun = cronjob.CreationTimestamp.Time
+	if cronjob.Status.LastScheduleTime != nil {
+		lastRun = cronjob.Status.LastScheduleTime.Time
+	}
+	expected = schedule.Next(lastRun.UTC())
+	if expected.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	following := schedule.Next(expected)
+	if following.IsZero() || following.After(now) {
+		return time.Time{}, time.Time{}, false
+	}
+	return lastRun, expected, true
+}
+
+// reportMissedSchedule saves and notifies a MISSED_SCHEDULE event for a CronJob
+func (w *Watcher) reportMissedSchedule(cronjob *batchv1.CronJob, lastRun, expected time.Time) {
+	last := "never ran (created " + lastRun.UTC().Format(time.RFC3339) + ")"
+	if cronjob.Status.LastScheduleTime != nil {
+		last = "last run at " + lastRun.UTC().Format(time.RFC3339)
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: cronjob.Namespace,
+		Kind:      "CronJob",
+		Name:      cronjob.Name,
+		Action:    storage.ActionMissedSchedule,
+		Severity:  storage.SeverityWarn,
+		Diff: fmt.Sprintf("Schedule %q expected a run at %s, %s",
+			cronjob.Spec.Schedule, expected.UTC().Format(time.RFC3339), last),
+		UID:             string(cronjob.UID),
+		ResourceVersion: cronjob.ResourceVersion,
+	}
+	metadata := map[string]interface{}{
+		"schedule":     cronjob.Spec.Schedule,
+		"expected_run": expected.UTC(),
+	}
+	if cronjob.Status.LastScheduleTime != nil {
+		metadata["last_schedule_time"] = lastRun.UTC()
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	// As with quotas, the object is not passed on: its last writer is the
+	// CronJob controller, not whoever broke the schedule
+	w.logSaved(event, w.saveAndNotify(event, nil, nil))
+}
@@ -0,0 +1,140 @@
+package watcher
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMissedSchedule(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		ts, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone string
+		lastRun  string
+		now      string
+		expected string // empty when no run was missed
+	}{
+		{
+			name:     "step within one period",
+			schedule: "*/15 * * * *",
+			lastRun:  "2026-01-05T12:00:00Z",
+			now:      "2026-01-05T12:20:00Z",
+		},
+		{
+			name:     "step two periods late",
+			schedule: "*/15 * * * *",
+			lastRun:  "2026-01-05T12:00:00Z",
+			now:      "2026-01-05T12:31:00Z",
+			expected: "2026-01-05T12:15:00Z",
+		},
+		{
+			name:     "range skips the weekend",
+			schedule: "0 9-17 * * 1-5",
+			lastRun:  "2026-01-09T17:00:00Z", // Friday
+			now:      "2026-01-12T09:30:00Z", // Monday
+		},
+		{
+			name:     "range missed on Monday",
+			schedule: "0 9-17 * * MON-FRI",
+			lastRun:  "2026-01-09T17:00:00Z",
+			now:      "2026-01-12T10:05:00Z",
+			expected: "2026-01-12T09:00:00Z",
+		},
+		{
+			name:     "list",
+			schedule: "0 0,12 * * *",
+			lastRun:  "2026-01-05T00:00:00Z",
+			now:      "2026-01-06T00:01:00Z",
+			expected: "2026-01-05T12:00:00Z",
+		},
+		{
+			name:     "day of month or day of week",
+			schedule: "0 0 1 * 1",            // the 1st and every Monday
+			lastRun:  "2026-01-01T00:00:00Z", // Thursday
+			now:      "2026-01-12T00:01:00Z",
+			expected: "2026-01-05T00:00:00Z",
+		},
+		{
+			name:     "day of week only",
+			schedule: "0 0 * * 0", // Sundays
+			lastRun:  "2026-01-04T00:00:00Z",
+			now:      "2026-01-17T00:00:00Z",
+		},
+		{
+			name:     "descriptor",
+			schedule: "@daily",
+			lastRun:  "2026-01-05T00:00:00Z",
+			now:      "2026-01-07T00:00:00Z",
+			expected: "2026-01-06T00:00:00Z",
+		},
+		{
+			name:     "time zone",
+			schedule: "0 9 * * *",
+			timeZone: "America/New_York",
+			lastRun:  "2026-01-05T14:00:00Z",
+			now:      "2026-01-07T14:30:00Z",
+			expected: "2026-01-06T14:00:00Z",
+		},
+		{
+			name:     "CRON_TZ prefix",
+			schedule: "CRON_TZ=America/New_York 0 9 * * *",
+			lastRun:  "2026-01-05T14:00:00Z",
+			now:      "2026-01-07T13:30:00Z",
+		},
+		{
+			name:     "minute out of range",
+			schedule: "61 * * * *",
+			lastRun:  "2026-01-05T00:00:00Z",
+			now:      "2027-01-05T00:00:00Z",
+		},
+		{
+			name:     "too few fields",
+			schedule: "* * *",
+			lastRun:  "2026-01-05T00:00:00Z",
+			now:      "2027-01-05T00:00:00Z",
+		},
+		{
+			name:     "unknown time zone",
+			schedule: "0 * * * *",
+			timeZone: "Mars/Olympus_Mons",
+			lastRun:  "2026-01-05T00:00:00Z",
+			now:      "2027-01-05T00:00:00Z",
+		},
+	}
+
+	w := &Watcher{logger: slog.New(slog.DiscardHandler)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronjob := &batchv1.CronJob{
+				Spec: batchv1.CronJobSpec{Schedule: tt.schedule},
+				Status: batchv1.CronJobStatus{
+					LastScheduleTime: &metav1.Time{Time: at(tt.lastRun)},
+				},
+			}
+			if tt.timeZone != "" {
+				cronjob.Spec.TimeZone = &tt.timeZone
+			}
+
+			_, expected, missed := w.missedSchedule(cronjob, at(tt.now))
+			if missed != (tt.expected != "") {
+				t.Fatalf("missed = %v (expected run %v), want %v", missed, expected, tt.expected != "")
+			}
+			if missed && !expected.Equal(at(tt.expected)) {
+				t.Errorf("expected run = %v, want %s", expected.UTC(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"k8watch/internal/diff"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Custom resources are watched as unstructured objects through the dynamic
+// client. Their kinds are only started when the CRD is installed.
+
+// customResourceHandler processes an event of a custom resource. oldObj and
+// newObj are *unstructured.Unstructured or nil.
+type customResourceHandler func(eventType watch.EventType, oldObj, newObj interface{})
+
+// watchCustomResource runs an informer for a custom resource in all namespaces
+func (w *Watcher) watchCustomResource(stopCh <-chan struct{}, gvr schema.GroupVersionResource, handle customResourceHandler) {
+	resource := w.dynamic.Resource(gvr).Namespace(corev1.NamespaceAll)
+	watchlist := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return resource.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(ctx, options)
+		},
+	}
+
+	_, controller := cache.NewInformer(
+		watchlist,
+		&unstructured.Unstructured{},
+		w.opts.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				handle(watch.Added, nil, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
+				handle(watch.Modified, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				handle(watch.Deleted, deletedObject(obj), nil)
+			},
+		},
+	)
+
+	controller.Run(stopCh)
+}
+
+// customResourceInstalled reports whether the API server serves a custom
+// resource. Discovery errors other than not found count as installed, so the
+// watcher starts and retries instead of being skipped for good.
+func (w *Watcher) customResourceInstalled(gvr schema.GroupVersionResource) bool {
+	resources, err := w.clientset.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		w.logger.Warn("Failed to discover custom resource", slog.String("resource", gvr.String()), slog.Any("error", err))
+		return true
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == gvr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// unstructuredPair returns the previous object and the current one (the new
+// object, or the old one for deletions) of a custom resource event
+func unstructuredPair(oldObj, newObj interface{}) (*unstructured.Unstructured, *unstructured.Unstructured) {
+	var old, current *unstructured.Unstructured
+	if oldObj != nil {
+		old, _ = oldObj.(*unstructured.Unstructured)
+	}
+	if newObj != nil {
+		current, _ = newObj.(*unstructured.Unstructured)
+	}
+	if current == nil {
+		current = old
+	}
+	return old, current
+}
+
+// describeFieldChanges lists the leaf fields that differ between two values
+// of a custom resource field, as "path: old → new"
+func describeFieldChanges(path string, oldValue, newValue interface{}) []string {
+	changes, err := diff.ComputeStructuredDiff(oldValue, newValue)
+	if err != nil {
+		return []string{fmt.Sprintf("%s changed", path)}
+	}
+
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		field := path
+		if c.Path != "" {
+			if c.Path[0] == '[' {
+				field += c.Path
+			} else {
+				field += "." + c.Path
+			}
+		}
+		switch c.Type {
+		case "added":
+			lines = append(lines, fmt.Sprintf("%s: added %v", field, c.New))
+		case "removed":
+			lines = append(lines, fmt.Sprintf("%s: removed %v", field, c.Old))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %v → %v", field, c.Old, c.New))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,209 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	kustomizationResource = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	helmReleaseResource   = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2beta1", Resource: "helmreleases"}
+)
+
+// watchKustomizations watches Flux Kustomization changes
+func (w *Watcher) watchKustomizations(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, kustomizationResource, w.handleKustomizationEvent)
+}
+
+// handleKustomizationEvent processes Kustomization events
+func (w *Watcher) handleKustomizationEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	oldKs, ks := unstructuredPair(oldObj, newObj)
+	if ks == nil {
+		return
+	}
+
+	if ks.GetNamespace() == "kube-system" || ks.GetNamespace() == "kube-public" || ks.GetNamespace() == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: ks.GetNamespace(),
+		Kind:      "Kustomization",
+		Name:      ks.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldKs != nil {
+		hasChanges, diff := w.detectKustomizationChanges(oldKs, ks)
+		if !hasChanges {
+			return // Ignore reconciles that don't change the path or source
+		}
+		event.Diff = diff
+	}
+
+	path, _, _ := unstructured.NestedString(ks.Object, "spec", "path")
+	revision, _, _ := unstructured.NestedString(ks.Object, "status", "lastAppliedRevision")
+	metadata := map[string]interface{}{
+		"path":                path,
+		"sourceRef":           sourceRef(ks),
+		"lastAppliedRevision": revision,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectKustomizationChanges checks for a changed path or source, which
+// decide what the Kustomization applies
+func (w *Watcher) detectKustomizationChanges(oldKs, newKs *unstructured.Unstructured) (bool, string) {
+	changes := []string{}
+
+	oldPath, _, _ := unstructured.NestedString(oldKs.Object, "spec", "path")
+	newPath, _, _ := unstructured.NestedString(newKs.Object, "spec", "path")
+	if oldPath != newPath {
+		changes = append(changes, fmt.Sprintf("Path: %s → %s", oldPath, newPath))
+	}
+
+	if oldRef, newRef := sourceRef(oldKs), sourceRef(newKs); oldRef != newRef {
+		changes = append(changes, fmt.Sprintf("Source: %s → %s", oldRef, newRef))
+	}
+
+	if len(changes) > 0 {
+		return true, "Kustomization changes:\n" + strings.Join(changes, "\n")
+	}
+	return false, ""
+}
+
+// sourceRef names the source of a Kustomization as Kind/[namespace/]name;
+// the namespace is only shown when it differs from the Kustomization's
+func sourceRef(ks *unstructured.Unstructured) string {
+	kind, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "kind")
+	name, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "name")
+	if namespace, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "namespace"); namespace != "" && namespace != ks.GetNamespace() {
+		name = namespace + "/" + name
+	}
+	return kind + "/" + name
+}
+
+// watchHelmReleases watches Flux HelmRelease changes
+func (w *Watcher) watchHelmReleases(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, helmReleaseResource, w.handleHelmReleaseEvent)
+}
+
+// handleHelmReleaseEvent processes HelmRelease events
+func (w *Watcher) handleHelmReleaseEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	oldHR, hr := unstructuredPair(oldObj, newObj)
+	if hr == nil {
+		return
+	}
+
+	if hr.GetNamespace() == "kube-system" || hr.GetNamespace() == "kube-public" || hr.GetNamespace() == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: hr.GetNamespace(),
+		Kind:      "HelmRelease",
+		Name:      hr.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldHR != nil {
+		hasChanges, diff, failed := w.detectHelmReleaseChanges(oldHR, hr)
+		if !hasChanges {
+			return // Ignore reconciles that don't change the chart, values or readiness
+		}
+		event.Diff = diff
+		if failed {
+			event.Severity = storage.SeverityWarn
+		}
+	}
+
+	chart, _, _ := unstructured.NestedString(hr.Object, "spec", "chart", "spec", "chart")
+	version, _, _ := unstructured.NestedString(hr.Object, "spec", "chart", "spec", "version")
+	ready, _, _ := readyCondition(hr)
+	metadata := map[string]interface{}{
+		"chart":   chart,
+		"version": version,
+		"ready":   ready,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectHelmReleaseChanges checks for a new chart version, changed values and
+// the Ready condition turning False. failed reports the latter, a failed
+// install or upgrade.
+func (w *Watcher) detectHelmReleaseChanges(oldHR, newHR *unstructured.Unstructured) (hasChanges bool, diff string, failed bool) {
+	changes := []string{}
+
+	oldVersion, _, _ := unstructured.NestedString(oldHR.Object, "spec", "chart", "spec", "version")
+	newVersion, _, _ := unstructured.NestedString(newHR.Object, "spec", "chart", "spec", "version")
+	if oldVersion != newVersion {
+		changes = append(changes, fmt.Sprintf("Chart version: %s → %s", oldVersion, newVersion))
+	}
+
+	oldValues, _, _ := unstructured.NestedFieldNoCopy(oldHR.Object, "spec", "values")
+	newValues, _, _ := unstructured.NestedFieldNoCopy(newHR.Object, "spec", "values")
+	if !reflect.DeepEqual(oldValues, newValues) {
+		changes = append(changes, describeFieldChanges("values", oldValues, newValues)...)
+	}
+
+	oldReady, _, _ := readyCondition(oldHR)
+	newReady, reason, message := readyCondition(newHR)
+	if newReady == "False" && oldReady != "False" {
+		failed = true
+		if oldReady == "" {
+			oldReady = "Unknown"
+		}
+		change := fmt.Sprintf("Ready: %s → False", oldReady)
+		if reason != "" {
+			change += " (" + reason + ")"
+		}
+		if message != "" {
+			change += ": " + message
+		}
+		changes = append(changes, change)
+	}
+
+	if len(changes) > 0 {
+		return true, "HelmRelease changes:\n" + strings.Join(changes, "\n"), failed
+	}
+	return false, "", false
+}
+
+// readyCondition returns the status, reason and message of the Ready
+// condition of a Flux object; the status is empty without one
+func readyCondition(obj *unstructured.Unstructured) (status, reason, message string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionType, _, _ := unstructured.NestedString(condition, "type"); conditionType != "Ready" {
+			continue
+		}
+		status, _, _ = unstructured.NestedString(condition, "status")
+		reason, _, _ = unstructured.NestedString(condition, "reason")
+		message, _, _ = unstructured.NestedString(condition, "message")
+		return status, reason, message
+	}
+	return "", "", ""
+}
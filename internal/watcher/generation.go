@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ignoreUpdate reports whether an informer update is skipped before its
+// handler runs, see Options.IgnoreResourceVersionOnly
+func (w *Watcher) ignoreUpdate(oldObj, newObj interface{}) bool {
+	return w.opts.IgnoreResourceVersionOnly && resourceVersionOnly(oldObj, newObj)
+}
+
+// resourceVersionOnly reports whether an update changed nothing but metadata,
+// as when a controller records its reconcile state in an annotation: the
+// generation, spec and status are unchanged. Status is compared because some
+// kinds, such as ReplicaSets, are reported on status changes. Objects without a
+// generation or a spec, such as ConfigMaps, Secrets and webhook configurations,
+// are left to their handlers.
+func resourceVersionOnly(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	if newMeta.GetGeneration() == 0 || oldMeta.GetGeneration() != newMeta.GetGeneration() {
+		return false
+	}
+
+	oldSpec, ok := objectField(oldObj, "Spec")
+	if !ok {
+		return false
+	}
+	newSpec, _ := objectField(newObj, "Spec")
+	oldStatus, _ := objectField(oldObj, "Status")
+	newStatus, _ := objectField(newObj, "Status")
+	return equality.Semantic.DeepEqual(oldSpec, newSpec) && equality.Semantic.DeepEqual(oldStatus, newStatus)
+}
+
+// objectField returns the named top-level field (Spec or Status) of a typed
+// object, or the lowercased key of an unstructured one
+func objectField(obj interface{}, name string) (interface{}, bool) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		switch name {
+		case "Spec":
+			value, ok := u.Object["spec"]
+			return value, ok
+		case "Status":
+			value, ok := u.Object["status"]
+			return value, ok
+		}
+		return nil, false
+	}
+
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return nil, false
+	}
+	return field.Interface(), true
+}
@@ -0,0 +1,182 @@
+package watcher
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	initialRestartBackoff = time.Second
+	maxRestartBackoff     = 60 * time.Second
+	// restartHistoryWindow is how long restart times are kept for RecentRestarts
+	restartHistoryWindow = time.Hour
+)
+
+var watcherRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kubewatcher_watcher_restarts_total",
+	Help: "Number of times a resource watcher was restarted after it stopped unexpectedly.",
+}, []string{"kind"})
+
+// kindTracker follows the state of one kind's watcher
+type kindTracker struct {
+	running     atomic.Bool
+	events      atomic.Int64
+	count       atomic.Int32
+	recent      []time.Time // guarded by Watcher.restartMu
+	lastRestart time.Time   // guarded by Watcher.restartMu
+}
+
+// WatcherStatus is the state of one kind's watcher, see Statuses
+type WatcherStatus struct {
+	Kind    string `json:"kind"`
+	Running bool   `json:"running"`
+	// RestartCount and LastRestart count restarts after unexpected stops
+	RestartCount int       `json:"restart_count"`
+	LastRestart  time.Time `json:"last_restart,omitzero"`
+	// EventsProcessed counts the change events recorded for the kind,
+	// including synthetic ones such as missed CronJob schedules
+	EventsProcessed int64 `json:"events_processed"`
+}
+
+// runWatch runs the informer for kind until stopCh is closed. If the informer
+// returns or panics early it is restarted with exponential backoff.
+func (w *Watcher) runWatch(kind string, watchFunc func(stopCh <-chan struct{}), stopCh <-chan struct{}) {
+	backoff := initialRestartBackoff
+	for {
+		started := time.Now()
+		w.runWatchOnce(kind, watchFunc, stopCh)
+
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		// A watcher that ran for a while before failing starts over with a short backoff
+		if time.Since(started) > maxRestartBackoff {
+			backoff = initialRestartBackoff
+		}
+		w.recordRestart(kind)
+		w.logger.Warn("Watcher stopped unexpectedly, restarting",
+			slog.String("kind", kind), slog.Duration("backoff", backoff))
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+// runWatchOnce runs the informer for kind, logging a panic in it instead of
+// crashing the process
+func (w *Watcher) runWatchOnce(kind string, watchFunc func(stopCh <-chan struct{}), stopCh <-chan struct{}) {
+	tracker := w.tracker(kind)
+	tracker.running.Store(true)
+	defer tracker.running.Store(false)
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Warn("Watcher panicked", slog.String("kind", kind), slog.Any("panic", r))
+		}
+	}()
+	watchFunc(stopCh)
+}
+
+// tracker returns the tracker of kind, creating it on first use
+func (w *Watcher) tracker(kind string) *kindTracker {
+	w.restartMu.Lock()
+	defer w.restartMu.Unlock()
+	return w.trackerLocked(kind)
+}
+
+func (w *Watcher) trackerLocked(kind string) *kindTracker {
+	tracker, ok := w.restarts[kind]
+	if !ok {
+		tracker = &kindTracker{}
+		w.restarts[kind] = tracker
+	}
+	return tracker
+}
+
+func (w *Watcher) recordRestart(kind string) {
+	w.restartMu.Lock()
+	defer w.restartMu.Unlock()
+
+	tracker := w.trackerLocked(kind)
+	tracker.count.Add(1)
+	now := time.Now()
+	tracker.recent = append(pruneBefore(tracker.recent, now.Add(-restartHistoryWindow)), now)
+	tracker.lastRestart = now
+	watcherRestarts.WithLabelValues(kind).Inc()
+}
+
+// recordEvent counts an event recorded for its kind
+func (w *Watcher) recordEvent(event *storage.ChangeEvent) {
+	w.tracker(event.Kind).events.Add(1)
+}
+
+// Statuses returns the state of the watcher of every enabled kind. Kinds
+// whose CRD is not installed, and all kinds on a replica that is not the
+// leader, are not running.
+func (w *Watcher) Statuses() map[string]WatcherStatus {
+	w.restartMu.Lock()
+	defer w.restartMu.Unlock()
+
+	statuses := make(map[string]WatcherStatus)
+	for _, kind := range w.WatchedKinds() {
+		status := WatcherStatus{Kind: kind}
+		if tracker, ok := w.restarts[kind]; ok {
+			status.Running = tracker.running.Load()
+			status.RestartCount = int(tracker.count.Load())
+			status.LastRestart = tracker.lastRestart
+			status.EventsProcessed = tracker.events.Load()
+		}
+		statuses[kind] = status
+	}
+	return statuses
+}
+
+// RestartCounts returns how often each kind's watcher has been restarted since startup
+func (w *Watcher) RestartCounts() map[string]int32 {
+	w.restartMu.Lock()
+	defer w.restartMu.Unlock()
+
+	counts := make(map[string]int32, len(w.restarts))
+	for kind, tracker := range w.restarts {
+		if count := tracker.count.Load(); count > 0 {
+			counts[kind] = count
+		}
+	}
+	return counts
+}
+
+// RecentRestarts returns the number of restarts per kind within window (at most one hour)
+func (w *Watcher) RecentRestarts(window time.Duration) map[string]int {
+	w.restartMu.Lock()
+	defer w.restartMu.Unlock()
+
+	cutoff := time.Now().Add(-window)
+	counts := make(map[string]int, len(w.restarts))
+	for kind, tracker := range w.restarts {
+		if recent := len(pruneBefore(tracker.recent, cutoff)); recent > 0 {
+			counts[kind] = recent
+		}
+	}
+	return counts
+}
+
+// pruneBefore drops the leading times before cutoff from a sorted slice
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,265 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	virtualServiceResource  = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}
+	destinationRuleResource = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}
+)
+
+// watchVirtualServices watches Istio VirtualService changes
+func (w *Watcher) watchVirtualServices(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, virtualServiceResource, w.handleVirtualServiceEvent)
+}
+
+// handleVirtualServiceEvent processes VirtualService events
+func (w *Watcher) handleVirtualServiceEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	oldVS, vs := unstructuredPair(oldObj, newObj)
+	if vs == nil {
+		return
+	}
+
+	if vs.GetNamespace() == "kube-system" || vs.GetNamespace() == "kube-public" || vs.GetNamespace() == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: vs.GetNamespace(),
+		Kind:      "VirtualService",
+		Name:      vs.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldVS != nil {
+		hasChanges, diff := w.detectVirtualServiceChanges(oldVS, vs)
+		if !hasChanges {
+			return // Ignore updates that don't touch hosts or route weights
+		}
+		event.Diff = diff
+	}
+
+	hosts, _, _ := unstructured.NestedStringSlice(vs.Object, "spec", "hosts")
+	gateways, _, _ := unstructured.NestedStringSlice(vs.Object, "spec", "gateways")
+	metadata := map[string]interface{}{
+		"hosts":      hosts,
+		"gateways":   gateways,
+		"httpRoutes": len(httpRouteWeights(vs)),
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectVirtualServiceChanges checks for changed hosts and HTTP route
+// weights, the fields canary rollouts shift
+func (w *Watcher) detectVirtualServiceChanges(oldVS, newVS *unstructured.Unstructured) (bool, string) {
+	changes := []string{}
+
+	oldHosts, _, _ := unstructured.NestedStringSlice(oldVS.Object, "spec", "hosts")
+	newHosts, _, _ := unstructured.NestedStringSlice(newVS.Object, "spec", "hosts")
+	if !slices.Equal(oldHosts, newHosts) {
+		changes = append(changes, fmt.Sprintf("Hosts: %v → %v", oldHosts, newHosts))
+	}
+
+	oldRoutes := httpRouteWeights(oldVS)
+	newRoutes := httpRouteWeights(newVS)
+	for _, route := range newRoutes {
+		i := slices.IndexFunc(oldRoutes, func(r httpRoute) bool { return r.name == route.name })
+		if i < 0 {
+			changes = append(changes, fmt.Sprintf("HTTP route %s added: %s", route.name, route.weights))
+			continue
+		}
+		if old := oldRoutes[i]; !reflect.DeepEqual(old.weights, route.weights) {
+			changes = append(changes, fmt.Sprintf("HTTP route %s weights: %s → %s", route.name, old.weights, route.weights))
+		}
+	}
+	for _, route := range oldRoutes {
+		if !slices.ContainsFunc(newRoutes, func(r httpRoute) bool { return r.name == route.name }) {
+			changes = append(changes, fmt.Sprintf("HTTP route %s removed", route.name))
+		}
+	}
+
+	if len(changes) > 0 {
+		return true, "VirtualService changes:\n" + strings.Join(changes, "\n")
+	}
+	return false, ""
+}
+
+// httpRoute is an HTTP route of a VirtualService with the weight of each
+// destination
+type httpRoute struct {
+	name    string
+	weights routeWeights
+}
+
+// routeWeights lists "destination=weight" pairs in route order
+type routeWeights []string
+
+func (r routeWeights) String() string {
+	return strings.Join(r, ", ")
+}
+
+// httpRouteWeights returns the HTTP routes of a VirtualService. Routes are
+// identified by name, or by position when unnamed. A destination without a
+// weight gets 100 when it is the only one, as in Istio.
+func httpRouteWeights(vs *unstructured.Unstructured) []httpRoute {
+	http, _, _ := unstructured.NestedSlice(vs.Object, "spec", "http")
+	routes := make([]httpRoute, 0, len(http))
+	for i, item := range http {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(rule, "name")
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		destinations, _, _ := unstructured.NestedSlice(rule, "route")
+		weights := routeWeights{}
+		for _, d := range destinations {
+			dest, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			weight, found, _ := unstructured.NestedInt64(dest, "weight")
+			if !found && len(destinations) == 1 {
+				weight = 100
+			}
+			weights = append(weights, fmt.Sprintf("%s=%d", routeDestination(dest), weight))
+		}
+		routes = append(routes, httpRoute{name: name, weights: weights})
+	}
+	return routes
+}
+
+// routeDestination names a route destination as host[/subset][:port]
+func routeDestination(dest map[string]interface{}) string {
+	host, _, _ := unstructured.NestedString(dest, "destination", "host")
+	if subset, _, _ := unstructured.NestedString(dest, "destination", "subset"); subset != "" {
+		host += "/" + subset
+	}
+	if port, found, _ := unstructured.NestedInt64(dest, "destination", "port", "number"); found {
+		host += fmt.Sprintf(":%d", port)
+	}
+	return host
+}
+
+// watchDestinationRules watches Istio DestinationRule changes
+func (w *Watcher) watchDestinationRules(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, destinationRuleResource, w.handleDestinationRuleEvent)
+}
+
+// handleDestinationRuleEvent processes DestinationRule events
+func (w *Watcher) handleDestinationRuleEvent(eventType watch.EventType, oldObj, newObj interface{}) {
+	oldRule, rule := unstructuredPair(oldObj, newObj)
+	if rule == nil {
+		return
+	}
+
+	if rule.GetNamespace() == "kube-system" || rule.GetNamespace() == "kube-public" || rule.GetNamespace() == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: rule.GetNamespace(),
+		Kind:      "DestinationRule",
+		Name:      rule.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldRule != nil {
+		hasChanges, diff := w.detectDestinationRuleChanges(oldRule, rule)
+		if !hasChanges {
+			return // Ignore updates that don't touch the traffic policy or subsets
+		}
+		event.Diff = diff
+	}
+
+	host, _, _ := unstructured.NestedString(rule.Object, "spec", "host")
+	subsets := []string{}
+	for name := range destinationSubsets(rule) {
+		subsets = append(subsets, name)
+	}
+	slices.Sort(subsets)
+	metadata := map[string]interface{}{
+		"host":    host,
+		"subsets": subsets,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectDestinationRuleChanges checks for changes to the traffic policy and
+// the subsets
+func (w *Watcher) detectDestinationRuleChanges(oldRule, newRule *unstructured.Unstructured) (bool, string) {
+	changes := []string{}
+
+	oldPolicy, _, _ := unstructured.NestedFieldNoCopy(oldRule.Object, "spec", "trafficPolicy")
+	newPolicy, _, _ := unstructured.NestedFieldNoCopy(newRule.Object, "spec", "trafficPolicy")
+	if !reflect.DeepEqual(oldPolicy, newPolicy) {
+		changes = append(changes, describeFieldChanges("trafficPolicy", oldPolicy, newPolicy)...)
+	}
+
+	oldSubsets := destinationSubsets(oldRule)
+	newSubsets := destinationSubsets(newRule)
+	for _, name := range slices.Sorted(maps.Keys(newSubsets)) {
+		oldSubset, ok := oldSubsets[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("Subset added: %s", name))
+			continue
+		}
+		if !reflect.DeepEqual(oldSubset, newSubsets[name]) {
+			changes = append(changes, describeFieldChanges("subsets["+name+"]", oldSubset, newSubsets[name])...)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldSubsets)) {
+		if _, ok := newSubsets[name]; !ok {
+			changes = append(changes, fmt.Sprintf("Subset removed: %s", name))
+		}
+	}
+
+	if len(changes) > 0 {
+		return true, "DestinationRule changes:\n" + strings.Join(changes, "\n")
+	}
+	return false, ""
+}
+
+// destinationSubsets returns the subsets of a DestinationRule by name,
+// without the name field
+func destinationSubsets(rule *unstructured.Unstructured) map[string]map[string]interface{} {
+	items, _, _ := unstructured.NestedSlice(rule.Object, "spec", "subsets")
+	subsets := make(map[string]map[string]interface{}, len(items))
+	for _, item := range items {
+		subset, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(subset, "name")
+		subset = maps.Clone(subset) // informer objects are shared, don't modify them
+		delete(subset, "name")
+		subsets[name] = subset
+	}
+	return subsets
+}
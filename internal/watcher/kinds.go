@@ -0,0 +1,134 @@
+package watcher
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SupportedKinds lists every resource kind the watcher can watch, in start order
+var SupportedKinds = []string{
+	"Deployment",
+	"ConfigMap",
+	"Secret",
+	"Service",
+	"Ingress",
+	"StatefulSet",
+	"DaemonSet",
+	"CronJob",
+	"Job",
+	"PodDisruptionBudget",
+	"VolumeAttachment",
+	"StorageClass",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+	"ReplicaSet",
+	"EndpointSlice",
+	"VirtualService",
+	"DestinationRule",
+	"Kustomization",
+	"HelmRelease",
+	"Application",
+	"Policy",
+	"ClusterPolicy",
+}
+
+// customResources maps the kinds served by CRDs to their resource. They are
+// skipped at start when the CRD is not installed.
+var customResources = map[string]schema.GroupVersionResource{
+	"VirtualService":  virtualServiceResource,
+	"DestinationRule": destinationRuleResource,
+	"Kustomization":   kustomizationResource,
+	"HelmRelease":     helmReleaseResource,
+	"Application":     argoCDApplicationResource,
+	"Policy":          kyvernoPolicyResource,
+	"ClusterPolicy":   kyvernoClusterPolicyResource,
+}
+
+// watchFuncs maps each supported kind to its watch loop
+func (w *Watcher) watchFuncs() map[string]func(stopCh <-chan struct{}) {
+	return map[string]func(stopCh <-chan struct{}){
+		"Deployment":                     w.watchDeployments,
+		"ConfigMap":                      w.watchConfigMaps,
+		"Secret":                         w.watchSecrets,
+		"Service":                        w.watchServices,
+		"Ingress":                        w.watchIngresses,
+		"StatefulSet":                    w.watchStatefulSets,
+		"DaemonSet":                      w.watchDaemonSets,
+		"CronJob":                        w.watchCronJobs,
+		"Job":                            w.watchJobs,
+		"PodDisruptionBudget":            w.watchPodDisruptionBudgets,
+		"VolumeAttachment":               w.watchVolumeAttachments,
+		"StorageClass":                   w.watchStorageClasses,
+		"MutatingWebhookConfiguration":   w.watchMutatingWebhookConfigurations,
+		"ValidatingWebhookConfiguration": w.watchValidatingWebhookConfigurations,
+		"ReplicaSet":                     w.watchReplicaSets,
+		"EndpointSlice":                  w.watchEndpointSlices,
+		"VirtualService":                 w.watchVirtualServices,
+		"DestinationRule":                w.watchDestinationRules,
+		"Kustomization":                  w.watchKustomizations,
+		"HelmRelease":                    w.watchHelmReleases,
+		"Application":                    w.watchArgoCDApplications,
+		"Policy":                         w.watchKyvernoPolicies,
+		"ClusterPolicy":                  w.watchKyvernoClusterPolicies,
+	}
+}
+
+// resolveKinds turns the requested kinds into a set of enabled kinds.
+// Names are matched case-insensitively; an empty list enables every supported kind.
+func resolveKinds(requested []string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	if len(requested) == 0 {
+		for _, kind := range SupportedKinds {
+			enabled[kind] = true
+		}
+		return enabled, nil
+	}
+
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, kind := range SupportedKinds {
+			if strings.EqualFold(name, kind) {
+				enabled[kind] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported kind %q (supported: %s)", name, strings.Join(SupportedKinds, ", "))
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("no kinds to watch")
+	}
+	return enabled, nil
+}
+
+// WatchedKinds returns the enabled kinds in start order
+func (w *Watcher) WatchedKinds() []string {
+	kinds := make([]string, 0, len(w.enabledKinds))
+	for _, kind := range SupportedKinds {
+		if w.enabledKinds[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// resourceForKind returns the lowercase plural API resource name of a kind,
+// as it appears in audit log objectRefs (e.g. Ingress -> ingresses)
+func resourceForKind(kind string) string {
+	if gvr, ok := customResources[kind]; ok {
+		return gvr.Resource // e.g. Policy -> policies
+	}
+	resource := strings.ToLower(kind)
+	if strings.HasSuffix(resource, "s") {
+		return resource + "es"
+	}
+	return resource + "s"
+}
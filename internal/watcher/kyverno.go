@@ -0,0 +1,167 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	kyvernoPolicyResource        = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "policies"}
+	kyvernoClusterPolicyResource = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies"}
+)
+
+// watchKyvernoPolicies watches namespaced Kyverno Policy changes
+func (w *Watcher) watchKyvernoPolicies(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, kyvernoPolicyResource, func(eventType watch.EventType, oldObj, newObj interface{}) {
+		w.handleKyvernoPolicyEvent("Policy", eventType, oldObj, newObj)
+	})
+}
+
+// watchKyvernoClusterPolicies watches Kyverno ClusterPolicy changes
+func (w *Watcher) watchKyvernoClusterPolicies(stopCh <-chan struct{}) {
+	w.watchCustomResource(stopCh, kyvernoClusterPolicyResource, func(eventType watch.EventType, oldObj, newObj interface{}) {
+		w.handleKyvernoPolicyEvent("ClusterPolicy", eventType, oldObj, newObj)
+	})
+}
+
+// handleKyvernoPolicyEvent processes Policy and ClusterPolicy events. Both
+// kinds share the same spec.
+func (w *Watcher) handleKyvernoPolicyEvent(kind string, eventType watch.EventType, oldObj, newObj interface{}) {
+	oldPolicy, policy := unstructuredPair(oldObj, newObj)
+	if policy == nil {
+		return
+	}
+
+	namespace := policy.GetNamespace()
+	if kind == "ClusterPolicy" {
+		namespace = clusterWideNamespace
+	} else if namespace == "kube-system" || namespace == "kube-public" || namespace == "kube-node-lease" {
+		return
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp: time.Now(),
+		Namespace: namespace,
+		Kind:      kind,
+		Name:      policy.GetName(),
+		Action:    string(eventType),
+		Diff:      string(eventType),
+	}
+
+	if eventType == watch.Modified && oldPolicy != nil {
+		hasChanges, diff, enforced := w.detectKyvernoPolicyChanges(oldPolicy, policy)
+		if !hasChanges {
+			return // Ignore status updates and changes outside the rules and actions
+		}
+		event.Diff = diff
+		if enforced {
+			event.Severity = storage.SeverityCritical
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"validationFailureAction": kyvernoAction(policy),
+		"background":              kyvernoBackground(policy),
+		"rules":                   kyvernoRuleNames(policy),
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+}
+
+// detectKyvernoPolicyChanges checks for rules added, removed or changed (by
+// name), a changed validationFailureAction and a changed background flag.
+// enforced reports a switch from Audit to Enforce, after which the policy
+// blocks admission instead of only reporting violations.
+func (w *Watcher) detectKyvernoPolicyChanges(oldPolicy, newPolicy *unstructured.Unstructured) (hasChanges bool, diff string, enforced bool) {
+	changes := []string{}
+
+	oldRules, newRules := kyvernoRules(oldPolicy), kyvernoRules(newPolicy)
+	for _, name := range slices.Sorted(maps.Keys(newRules)) {
+		oldRule, ok := oldRules[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("Rule added: %s", name))
+		} else if !reflect.DeepEqual(oldRule, newRules[name]) {
+			changes = append(changes, fmt.Sprintf("Rule modified: %s", name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldRules)) {
+		if _, ok := newRules[name]; !ok {
+			changes = append(changes, fmt.Sprintf("Rule removed: %s", name))
+		}
+	}
+
+	oldAction, newAction := kyvernoAction(oldPolicy), kyvernoAction(newPolicy)
+	if !strings.EqualFold(oldAction, newAction) {
+		changes = append(changes, fmt.Sprintf("Validation failure action: %s → %s", oldAction, newAction))
+		enforced = strings.EqualFold(oldAction, "Audit") && strings.EqualFold(newAction, "Enforce")
+	}
+
+	if oldBackground, newBackground := kyvernoBackground(oldPolicy), kyvernoBackground(newPolicy); oldBackground != newBackground {
+		changes = append(changes, fmt.Sprintf("Background: %t → %t", oldBackground, newBackground))
+	}
+
+	if len(changes) > 0 {
+		return true, "Policy changes:\n" + strings.Join(changes, "\n"), enforced
+	}
+	return false, "", false
+}
+
+// kyvernoRules maps the rules of a policy by name
+func kyvernoRules(policy *unstructured.Unstructured) map[string]interface{} {
+	rules := make(map[string]interface{})
+	list, _, _ := unstructured.NestedSlice(policy.Object, "spec", "rules")
+	for i, r := range list {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(rule, "name")
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		rules[name] = rule
+	}
+	return rules
+}
+
+// kyvernoRuleNames returns the rule names of a policy in spec order
+func kyvernoRuleNames(policy *unstructured.Unstructured) []string {
+	names := []string{}
+	list, _, _ := unstructured.NestedSlice(policy.Object, "spec", "rules")
+	for _, r := range list {
+		if rule, ok := r.(map[string]interface{}); ok {
+			name, _, _ := unstructured.NestedString(rule, "name")
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// kyvernoAction returns spec.validationFailureAction, which defaults to Audit.
+// Older policies spell it in lower case.
+func kyvernoAction(policy *unstructured.Unstructured) string {
+	action, _, _ := unstructured.NestedString(policy.Object, "spec", "validationFailureAction")
+	if action == "" {
+		return "Audit"
+	}
+	return action
+}
+
+// kyvernoBackground returns spec.background, which defaults to true
+func kyvernoBackground(policy *unstructured.Unstructured) bool {
+	background, found, _ := unstructured.NestedBool(policy.Object, "spec", "background")
+	return background || !found
+}
@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionConfig configures the Lease used to elect the replica that runs the watchers
+type LeaderElectionConfig struct {
+	LeaseName      string
+	LeaseNamespace string
+	Identity       string
+	LeaseDuration  time.Duration
+}
+
+// RunWithLeaderElection campaigns for the lease and runs the watchers only while
+// this replica is the leader. When leadership is lost the watchers are stopped
+// and the replica campaigns again. It blocks until ctx is cancelled.
+func (w *Watcher) RunWithLeaderElection(ctx context.Context, cfg LeaderElectionConfig) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      cfg.LeaseName,
+			Namespace: cfg.LeaseNamespace,
+		},
+		Client: w.clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: cfg.Identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.LeaseDuration * 2 / 3,
+		RetryPeriod:     cfg.LeaseDuration / 5,
+		ReleaseOnCancel: true,
+		Name:            cfg.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				w.logger.Info("Acquired leadership, starting watchers", slog.String("identity", cfg.Identity))
+				if err := w.Start(); err != nil {
+					w.logger.Error("Failed to start watchers", slog.Any("error", err))
+				}
+			},
+			OnStoppedLeading: func() {
+				w.logger.Info("Lost leadership, stopping watchers", slog.String("identity", cfg.Identity))
+				w.Stop()
+			},
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					w.logger.Info("Leader changed", slog.String("leader", identity))
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	for {
+		// Run returns once leadership is lost or ctx is cancelled
+		elector.Run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package watcher
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// extractAuthor returns the field manager that most likely made a change
+// (e.g. "kubectl-client-side-apply", "argocd-controller", "helm").
+// For updates, managedFields entries whose timestamp moved between the old
+// and new object are preferred; otherwise the most recently updated entry wins.
+func extractAuthor(oldObj, newObj interface{}) string {
+	obj := newObj
+	if obj == nil {
+		obj = oldObj
+	}
+	if obj == nil {
+		return ""
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	entries := accessor.GetManagedFields()
+
+	// Remember when each manager last touched the old object so only the
+	// entries bumped by this change are considered
+	if oldObj != nil && newObj != nil {
+		if oldAccessor, err := meta.Accessor(oldObj); err == nil {
+			previous := make(map[string]time.Time)
+			for _, entry := range oldAccessor.GetManagedFields() {
+				if entry.Time != nil {
+					previous[managedFieldsKey(entry)] = entry.Time.Time
+				}
+			}
+			if author := latestManager(entries, previous); author != "" {
+				return author
+			}
+		}
+	}
+
+	return latestManager(entries, nil)
+}
+
+// latestManager returns the manager of the most recently updated entry,
+// skipping status subresource updates and entries not newer than previous
+func latestManager(entries []metav1.ManagedFieldsEntry, previous map[string]time.Time) string {
+	var author string
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.Subresource != "" || entry.Time == nil {
+			continue
+		}
+		if prev, ok := previous[managedFieldsKey(entry)]; ok && !entry.Time.After(prev) {
+			continue
+		}
+		if author == "" || entry.Time.After(latest) {
+			author = entry.Manager
+			latest = entry.Time.Time
+		}
+	}
+	return author
+}
+
+// managedFieldsKey identifies a managedFields entry across object versions
+func managedFieldsKey(entry metav1.ManagedFieldsEntry) string {
+	return entry.Manager + "/" + string(entry.Operation) + "/" + entry.Subresource
+}
+
+// Change sources reported in ChangeEvent.Source
+const (
+	SourceHelm    = "helm"
+	SourceArgoCD  = "argocd"
+	SourceFlux    = "flux"
+	SourceKubectl = "kubectl"
+	SourceUnknown = "unknown"
+)
+
+// sourcePriority is the order in which sources are looked for in managedFields
+var sourcePriority = []string{SourceHelm, SourceArgoCD, SourceFlux, SourceKubectl}
+
+// sourceForManager maps a field manager name to the tool behind it
+func sourceForManager(manager string) string {
+	switch {
+	case manager == "helm":
+		return SourceHelm
+	case strings.HasPrefix(manager, "argocd"):
+		return SourceArgoCD
+	case manager == "flux-kustomize-controller", manager == "kustomize-controller", manager == "helm-controller":
+		return SourceFlux
+	case strings.HasPrefix(manager, "kubectl"):
+		return SourceKubectl
+	}
+	return SourceUnknown
+}
+
+// detectSource returns which tool made a change. The author of the change
+// decides when it is recognised; otherwise any manager of the object counts,
+// in sourcePriority order.
+func detectSource(author string, oldObj, newObj interface{}) string {
+	if source := sourceForManager(author); source != SourceUnknown {
+		return source
+	}
+
+	obj := newObj
+	if obj == nil {
+		obj = oldObj
+	}
+	if obj == nil {
+		return SourceUnknown
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return SourceUnknown
+	}
+
+	found := make(map[string]bool)
+	for _, entry := range accessor.GetManagedFields() {
+		found[sourceForManager(entry.Manager)] = true
+	}
+	for _, source := range sourcePriority {
+		if found[source] {
+			return source
+		}
+	}
+	return SourceUnknown
+}
@@ -0,0 +1,44 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"k8watch/internal/plugin"
+	"k8watch/internal/storage"
+)
+
+// pluginTimeout bounds a single plugin call
+const pluginTimeout = 5 * time.Second
+
+// RegisterPlugin makes p run on every event stored from now on
+func (w *Watcher) RegisterPlugin(p plugin.Plugin) {
+	w.pluginsMu.Lock()
+	defer w.pluginsMu.Unlock()
+
+	w.plugins = append(w.plugins, p)
+	w.logger.Info("Plugin registered", slog.String("plugin", p.Name()))
+}
+
+// runPlugins hands a stored event to every registered plugin, each in its own
+// goroutine with its own copy of the event
+func (w *Watcher) runPlugins(event *storage.ChangeEvent) {
+	w.pluginsMu.RLock()
+	plugins := w.plugins
+	w.pluginsMu.RUnlock()
+
+	for _, p := range plugins {
+		event := *event
+		go func() {
+			ctx, cancel := context.WithTimeout(w.ctx, pluginTimeout)
+			defer cancel()
+			if err := p.OnEvent(ctx, &event); err != nil {
+				w.logger.Warn("Plugin failed", append(eventAttrs(&event),
+					slog.String("plugin", p.Name()),
+					slog.Any("error", err),
+				)...)
+			}
+		}()
+	}
+}
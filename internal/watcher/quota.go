@@ -0,0 +1,130 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"k8watch/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// quotaUsage is the usage of one resource in a ResourceQuota
+type quotaUsage struct {
+	Resource string  `json:"resource"`
+	Used     string  `json:"used"`
+	Hard     string  `json:"hard"`
+	Percent  float64 `json:"percent"`
+}
+
+// runQuotaChecks checks ResourceQuota usage every QuotaCheckInterval until stopCh is closed
+func (w *Watcher) runQuotaChecks(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(w.opts.QuotaCheckInterval)
+	defer ticker.Stop()
+
+	// Quotas already over the threshold, so each crossing is reported once
+	exceeded := make(map[string]bool)
+	for {
+		w.checkQuotas(exceeded)
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkQuotas reports quota resources whose usage reached QuotaAlertThreshold
+// since the last check. exceeded holds the resources reported before.
+func (w *Watcher) checkQuotas(exceeded map[string]bool) {
+	quotas, err := w.clientset.CoreV1().ResourceQuotas(corev1.NamespaceAll).List(w.ctx, metav1.ListOptions{})
+	if err != nil {
+		w.logger.Warn("Failed to list resource quotas", slog.Any("error", err))
+		return
+	}
+
+	current := make(map[string]bool)
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		if quota.Namespace == "kube-system" || quota.Namespace == "kube-public" || quota.Namespace == "kube-node-lease" {
+			continue
+		}
+
+		var newlyExceeded []quotaUsage
+		for _, usage := range quotaUsages(quota) {
+			if usage.Percent < w.opts.QuotaAlertThreshold*100 {
+				continue
+			}
+			key := quota.Namespace + "/" + quota.Name + "/" + usage.Resource
+			current[key] = true
+			if !exceeded[key] {
+				newlyExceeded = append(newlyExceeded, usage)
+			}
+		}
+		if len(newlyExceeded) > 0 {
+			w.reportQuota(quota, newlyExceeded)
+		}
+	}
+
+	// Forget resources that dropped below the threshold so they are reported again
+	for key := range exceeded {
+		delete(exceeded, key)
+	}
+	for key := range current {
+		exceeded[key] = true
+	}
+}
+
+// quotaUsages returns the usage of each hard limit of a quota, sorted by resource name
+func quotaUsages(quota *corev1.ResourceQuota) []quotaUsage {
+	var usages []quotaUsage
+	for name, hard := range quota.Spec.Hard {
+		used, ok := quota.Status.Used[name]
+		if !ok || hard.IsZero() {
+			continue
+		}
+		usages = append(usages, quotaUsage{
+			Resource: string(name),
+			Used:     used.String(),
+			Hard:     hard.String(),
+			Percent:  used.AsApproximateFloat64() / hard.AsApproximateFloat64() * 100,
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Resource < usages[j].Resource })
+	return usages
+}
+
+// reportQuota saves and notifies a THRESHOLD_EXCEEDED event for a quota
+func (w *Watcher) reportQuota(quota *corev1.ResourceQuota, usages []quotaUsage) {
+	lines := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		lines = append(lines, fmt.Sprintf("%s at %.0f%% of quota (%s/%s)", usage.Resource, usage.Percent, usage.Used, usage.Hard))
+	}
+
+	event := &storage.ChangeEvent{
+		Timestamp:       time.Now(),
+		Namespace:       quota.Namespace,
+		Kind:            "ResourceQuota",
+		Name:            quota.Name,
+		Action:          storage.ActionThresholdExceeded,
+		Severity:        storage.SeverityWarn,
+		Diff:            strings.Join(lines, "\n"),
+		UID:             string(quota.UID),
+		ResourceVersion: quota.ResourceVersion,
+	}
+	metadata := map[string]interface{}{
+		"threshold": w.opts.QuotaAlertThreshold,
+		"resources": usages,
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	event.Metadata = string(metadataJSON)
+
+	// The quota object is not passed on: its managed fields and audit entries
+	// describe the quota controller, not whoever used up the quota
+	w.logSaved(event, w.saveAndNotify(event, nil, nil))
+}
@@ -0,0 +1,127 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8watch/internal/storage"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SeverityRule assigns a severity to the events it matches. Empty fields match
+// every event; Kind and Action are compared case-insensitively and Diff is a
+// regular expression searched in the event diff.
+type SeverityRule struct {
+	Kind     string `json:"kind,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Diff     string `json:"diff,omitempty"`
+	Severity string `json:"severity"`
+}
+
+// DefaultSeverityRules classify the changes with the largest blast radius.
+// They apply to events that neither a configured rule nor the watcher itself
+// assigned a severity to.
+var DefaultSeverityRules = []SeverityRule{
+	{Diff: `(?i)privileged container added`, Severity: storage.SeverityCritical},
+	{Kind: "Secret", Action: "DELETED", Severity: storage.SeverityCritical},
+	{Kind: "Deployment", Action: "DELETED", Severity: storage.SeverityWarn},
+	{Kind: "StatefulSet", Action: "DELETED", Severity: storage.SeverityWarn},
+	{Kind: "DaemonSet", Action: "DELETED", Severity: storage.SeverityWarn},
+	{Kind: "Service", Action: "DELETED", Severity: storage.SeverityWarn},
+	{Kind: "Ingress", Action: "DELETED", Severity: storage.SeverityWarn},
+	{Kind: "ClusterPolicy", Action: "DELETED", Severity: storage.SeverityCritical},
+	{Kind: "Secret", Action: "MODIFIED", Severity: storage.SeverityNotice},
+	{Kind: "ConfigMap", Action: "DELETED", Severity: storage.SeverityNotice},
+	{Diff: `(?m)^(Image updated|Container \S+ image): `, Severity: storage.SeverityNotice},
+}
+
+// LoadSeverityRules reads a YAML or JSON array of SeverityRule from path
+func LoadSeverityRules(path string) ([]SeverityRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read severity rules: %w", err)
+	}
+	var rules []SeverityRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse severity rules: %w", err)
+	}
+	if _, err := compileSeverityRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type severityRule struct {
+	SeverityRule
+	diff *regexp.Regexp
+}
+
+func compileSeverityRules(rules []SeverityRule) ([]severityRule, error) {
+	compiled := make([]severityRule, 0, len(rules))
+	for i, rule := range rules {
+		if err := storage.ValidateSeverity(rule.Severity); err != nil {
+			return nil, fmt.Errorf("severity rule %d: %w", i+1, err)
+		}
+		c := severityRule{SeverityRule: rule}
+		if rule.Diff != "" {
+			re, err := regexp.Compile(rule.Diff)
+			if err != nil {
+				return nil, fmt.Errorf("severity rule %d: invalid diff pattern: %w", i+1, err)
+			}
+			c.diff = re
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func (r *severityRule) matches(event *storage.ChangeEvent) bool {
+	if r.Kind != "" && !strings.EqualFold(r.Kind, event.Kind) {
+		return false
+	}
+	if r.Action != "" && !strings.EqualFold(r.Action, event.Action) {
+		return false
+	}
+	return r.diff == nil || r.diff.MatchString(event.Diff)
+}
+
+// classifySeverity sets the event severity. The first matching configured rule
+// wins, then a severity the watcher already set, then the first matching
+// default rule; anything else is info.
+func (w *Watcher) classifySeverity(event *storage.ChangeEvent) {
+	if severity, ok := firstMatch(w.severityRules, event); ok {
+		event.Severity = severity
+		return
+	}
+	if event.Severity != "" {
+		return
+	}
+	if severity, ok := firstMatch(w.defaultSeverityRules, event); ok {
+		event.Severity = severity
+		return
+	}
+	event.Severity = storage.SeverityInfo
+}
+
+// validateNotifyMinSeverity checks Options.NotifyMinSeverity
+func validateNotifyMinSeverity(severity string) error {
+	if severity == "" {
+		return nil
+	}
+	if err := storage.ValidateSeverity(severity); err != nil {
+		return fmt.Errorf("invalid notification severity: %w", err)
+	}
+	return nil
+}
+
+func firstMatch(rules []severityRule, event *storage.ChangeEvent) (string, bool) {
+	for i := range rules {
+		if rules[i].matches(event) {
+			return rules[i].Severity, true
+		}
+	}
+	return "", false
+}
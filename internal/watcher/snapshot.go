@@ -0,0 +1,68 @@
+package watcher
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"k8watch/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// saveSnapshot stores the sanitized before/after objects for a MODIFIED event
+func (w *Watcher) saveSnapshot(event *storage.ChangeEvent, oldObj, newObj interface{}) {
+	if event.ID == 0 || oldObj == nil || newObj == nil {
+		return
+	}
+
+	snapshot := &storage.EventSnapshot{
+		EventID:   event.ID,
+		CreatedAt: time.Now(),
+		Before:    w.limitSpec(event, "before", sanitizedJSON(oldObj)),
+		After:     w.limitSpec(event, "after", sanitizedJSON(newObj)),
+	}
+	if snapshot.Before == "" && snapshot.After == "" {
+		return
+	}
+	if err := w.storage.SaveSnapshot(w.ctx, snapshot); err != nil {
+		w.logger.Warn("Failed to save snapshot", slog.Int64("event_id", event.ID), slog.Any("error", err))
+	}
+}
+
+// sanitizedJSON returns the object as JSON without managedFields, the
+// last-applied annotation, or Secret data
+func sanitizedJSON(obj interface{}) string {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return ""
+	}
+
+	if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+
+	// Never persist secret values
+	if _, ok := obj.(*corev1.Secret); ok {
+		delete(m, "data")
+		delete(m, "stringData")
+	}
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"encoding/json"
+	"log/slog"
+	"unicode/utf8"
+
+	"k8watch/internal/storage"
+)
+
+// truncatedDiffSuffix is appended to diffs cut to Options.MaxDiffSize
+const truncatedDiffSuffix = "\n... (truncated, full diff available in spec_before/spec_after)"
+
+// truncateDiff cuts the event's diff to MaxDiffSize bytes and records the
+// original size in its metadata as original_diff_size
+func (w *Watcher) truncateDiff(event *storage.ChangeEvent) {
+	limit := w.opts.MaxDiffSize
+	if limit <= 0 || len(event.Diff) <= limit {
+		return
+	}
+
+	size := len(event.Diff)
+	event.Diff = truncateUTF8(event.Diff, limit) + truncatedDiffSuffix
+	event.Metadata = withMetadata(event.Metadata, "original_diff_size", size)
+	w.logger.Debug("Truncated diff", append(eventAttrs(event),
+		slog.Int("size", size),
+		slog.Int("max_diff_size", limit),
+	)...)
+}
+
+// limitSpec returns the snapshot JSON of one side of a change, or "" if it is
+// larger than MaxSpecSize. Oversized objects are dropped rather than cut,
+// since a cut document can no longer be parsed.
+func (w *Watcher) limitSpec(event *storage.ChangeEvent, side, spec string) string {
+	limit := w.opts.MaxSpecSize
+	if limit <= 0 || len(spec) <= limit {
+		return spec
+	}
+	w.logger.Debug("Dropped oversized snapshot", append(eventAttrs(event),
+		slog.String("side", side),
+		slog.Int("size", len(spec)),
+		slog.Int("max_spec_size", limit),
+	)...)
+	return ""
+}
+
+// truncateUTF8 returns at most n bytes of s without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// withMetadata sets key in a JSON metadata object. Metadata that is not a
+// JSON object is returned unchanged.
+func withMetadata(metadata, key string, value interface{}) string {
+	m := map[string]interface{}{}
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &m); err != nil {
+			return metadata
+		}
+	}
+	m[key] = value
+	out, err := json.Marshal(m)
+	if err != nil {
+		return metadata
+	}
+	return string(out)
+}
@@ -1,21 +1,36 @@
 package watcher
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"k8watch/internal/audit"
 	"k8watch/internal/diff"
 	"k8watch/internal/notifier"
+	"k8watch/internal/plugin"
 	"k8watch/internal/storage"
 
+	"github.com/google/uuid"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -23,14 +38,97 @@ import (
 )
 
 type Watcher struct {
+	ctx       context.Context
+	logger    *slog.Logger
 	clientset *kubernetes.Clientset
-	storage   *storage.Storage
-	notifier  *notifier.SlackNotifier
-	stopCh    chan struct{}
+	dynamic   dynamic.Interface // custom resources, see dynamic.go
+	storage   storage.EventStore
+	notifiers *notifier.RetryQueue
+	opts      Options
+
+	// enabledKinds holds the resource kinds started by Start
+	enabledKinds map[string]bool
+
+	mu       sync.Mutex
+	stopCh   chan struct{} // nil while the watchers are stopped
+	stopOnce sync.Once     // closes stopCh exactly once; reset by Start
+
+	restartMu sync.Mutex
+	restarts  map[string]*kindTracker // per-kind state, see health.go
+
+	// Open Deployment changesets by key, see changesets.go
+	changesetMu sync.Mutex
+	changesets  map[string]openChangeset
+
+	// Severity classification, see severity.go
+	severityRules        []severityRule
+	defaultSeverityRules []severityRule
+
+	// Event processing plugins, see plugins.go
+	pluginsMu sync.RWMutex
+	plugins   []plugin.Plugin
+
+	// secretHashKey keys the HMACs of secret values, see hashSecretData
+	secretHashKey []byte
+}
+
+// Options holds optional watcher behaviour
+type Options struct {
+	// StoreSnapshots stores the full sanitized before/after objects of MODIFIED events
+	StoreSnapshots bool
+	// WatchKinds limits watching to these kinds; empty means all SupportedKinds
+	WatchKinds []string
+	// AuditLog, when set, is used to attribute changes to the user that made them
+	AuditLog *audit.Tailer
+	// ManagedFields enables author and source detection from metadata.managedFields
+	ManagedFields bool
+	// IngressAnnotationPrefixes adds Ingress annotation keys, or prefixes ending
+	// in "/", whose changes are recorded on top of the built-in ones
+	IngressAnnotationPrefixes []string
+	// QuotaCheckInterval is how often ResourceQuota usage is checked; 0 disables the checks
+	QuotaCheckInterval time.Duration
+	// QuotaAlertThreshold is the used/hard ratio at which a quota resource is reported
+	QuotaAlertThreshold float64
+	// CronJobCheckInterval is how often CronJobs are checked for missed
+	// schedules; 0 disables the checks
+	CronJobCheckInterval time.Duration
+	// IgnoreResourceVersionOnly skips updates that leave the generation, spec
+	// and status unchanged, e.g. controllers touching annotations on every
+	// reconcile, see resourceVersionOnly
+	IgnoreResourceVersionOnly bool
+	// ResyncPeriod is how often informers replay their cached objects as
+	// updates; 0 disables resyncs
+	ResyncPeriod time.Duration
+	// DryRun logs detected events instead of saving them or sending notifications
+	DryRun bool
+	// SeverityRules classify events ahead of DefaultSeverityRules
+	SeverityRules []SeverityRule
+	// NotifyMinSeverity is the least severity that is sent to notifiers; empty means all
+	NotifyMinSeverity string
+	// MaxDiffSize truncates stored diffs to this many bytes; 0 means no limit
+	MaxDiffSize int
+	// MaxSpecSize drops snapshot objects larger than this many bytes; 0 means no limit
+	MaxSpecSize int
+	// CAFile replaces the kubeconfig's certificate authority for the API server
+	CAFile string
+	// SkipTLSVerify disables API server certificate verification. It is only
+	// allowed with a kubeconfig, not with the in-cluster config.
+	SkipTLSVerify bool
+	// AppLabel is the label whose value is stored as the event's app, e.g.
+	// app.kubernetes.io/name; empty leaves App unset
+	AppLabel string
+	// SecretHashKey keys the HMAC-SHA256 digests of secret values stored as
+	// key_hashes; empty uses a random key, so digests only match within one run
+	SecretHashKey string
 }
 
-// NewWatcher creates a new Kubernetes watcher
-func NewWatcher(kubeconfig string, storage *storage.Storage, slackWebhook string) (*Watcher, error) {
+// NewWatcher creates a new Kubernetes watcher. Disabled notifiers are dropped.
+// Storage writes and notifications are bound to ctx. A nil logger uses slog.Default().
+func NewWatcher(ctx context.Context, kubeconfig string, storage storage.EventStore, notifiers []notifier.Notifier, opts Options, logger *slog.Logger) (*Watcher, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	var config *rest.Config
 	var err error
 
@@ -42,72 +140,151 @@ func NewWatcher(kubeconfig string, storage *storage.Storage, slackWebhook string
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config: %w", err)
 	}
+	if opts.CAFile != "" {
+		config.TLSClientConfig.CAFile = opts.CAFile
+		config.TLSClientConfig.CAData = nil
+	}
+	if opts.SkipTLSVerify {
+		if kubeconfig == "" {
+			return nil, fmt.Errorf("TLS verification can only be skipped with a kubeconfig, not in-cluster")
+		}
+		// client-go refuses a CA together with the insecure flag
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAFile = ""
+		config.TLSClientConfig.CAData = nil
+	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
 
-	slackNotifier := notifier.NewSlackNotifier(slackWebhook)
-	if slackNotifier.IsEnabled() {
-		log.Println("Slack notifications enabled")
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	enabledKinds, err := resolveKinds(opts.WatchKinds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve watch kinds: %w", err)
+	}
+
+	severityRules, err := compileSeverityRules(opts.SeverityRules)
+	if err != nil {
+		return nil, err
+	}
+	defaultSeverityRules, err := compileSeverityRules(DefaultSeverityRules)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNotifyMinSeverity(opts.NotifyMinSeverity); err != nil {
+		return nil, err
+	}
+
+	secretHashKey := []byte(opts.SecretHashKey)
+	if len(secretHashKey) == 0 {
+		secretHashKey = make([]byte, 32)
+		if _, err := rand.Read(secretHashKey); err != nil {
+			return nil, fmt.Errorf("failed to generate secret hash key: %w", err)
+		}
+		logger.Info("No secret hash key set; secret key_hashes can only be compared within this run")
+	}
+
+	enabled := make([]notifier.Notifier, 0, len(notifiers))
+	for _, n := range notifiers {
+		if !n.IsEnabled() {
+			continue
+		}
+		logger.Info("Notifications enabled", slog.String("notifier", n.Name()))
 		// Test connection
-		if err := slackNotifier.TestConnection(); err != nil {
-			log.Printf("Warning: Failed to send test Slack message: %v", err)
+		if err := n.TestConnection(); err != nil {
+			logger.Warn("Notifier connection test failed", slog.String("notifier", n.Name()), slog.Any("error", err))
 		}
+		enabled = append(enabled, n)
 	}
 
 	return &Watcher{
+		ctx:       ctx,
+		logger:    logger,
 		clientset: clientset,
+		dynamic:   dynamicClient,
 		storage:   storage,
-		notifier:  slackNotifier,
-		stopCh:    make(chan struct{}),
-	}, nil
-}
-
-// Start starts watching all resources
-func (w *Watcher) Start() error {
-	log.Println("Starting watchers...")
-
-	// Start deployment watcher
-	go w.watchDeployments()
-
-	// Start configmap watcher
-	go w.watchConfigMaps()
+		notifiers: notifier.NewRetryQueue(storage, enabled, logger),
+		opts:      opts,
 
-	// Start secret watcher
-	go w.watchSecrets()
+		enabledKinds: enabledKinds,
+		restarts:     make(map[string]*kindTracker),
+		changesets:   make(map[string]openChangeset),
 
-	// Start service watcher
-	go w.watchServices()
+		severityRules:        severityRules,
+		defaultSeverityRules: defaultSeverityRules,
 
-	// Start ingress watcher
-	go w.watchIngresses()
-
-	// Start statefulset watcher
-	go w.watchStatefulSets()
-
-	// Start daemonset watcher
-	go w.watchDaemonSets()
+		secretHashKey: secretHashKey,
+	}, nil
+}
 
-	// Start cronjob watcher
-	go w.watchCronJobs()
+// Start starts watching all enabled resource kinds. It can be called again after Stop,
+// e.g. when leadership is re-acquired.
+func (w *Watcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	// Start job watcher
-	go w.watchJobs()
+	if w.stopCh != nil {
+		return fmt.Errorf("watchers are already running")
+	}
+	w.stopCh = make(chan struct{})
+	w.stopOnce = sync.Once{}
+	stopCh := w.stopCh
+
+	w.logger.Info("Starting watchers")
+
+	watchFuncs := w.watchFuncs()
+	started := []string{}
+	for _, kind := range w.WatchedKinds() {
+		if gvr, ok := customResources[kind]; ok && !w.customResourceInstalled(gvr) {
+			w.logger.Info("Skipping kind, its CRD is not installed", slog.String("kind", kind), slog.String("resource", gvr.String()))
+			continue
+		}
+		go w.runWatch(kind, watchFuncs[kind], stopCh)
+		started = append(started, kind)
+	}
+	if w.opts.QuotaCheckInterval > 0 {
+		go w.runQuotaChecks(stopCh)
+	}
+	if w.opts.CronJobCheckInterval > 0 && w.enabledKinds["CronJob"] {
+		go w.runCronJobChecks(stopCh)
+	}
 
-	log.Println("All watchers started successfully")
+	w.logger.Info("Watchers started", slog.String("kinds", strings.Join(started, ",")))
 	return nil
 }
 
-// Stop stops all watchers
+// Stop stops all watchers. It is a no-op if the watchers are not running.
 func (w *Watcher) Stop() {
-	close(w.stopCh)
-	log.Println("Stopped all watchers")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopCh == nil {
+		return
+	}
+	stopCh := w.stopCh
+	w.stopOnce.Do(func() { close(stopCh) })
+	w.stopCh = nil
+	w.logger.Info("Stopped all watchers")
+}
+
+// deletedObject returns the object of an informer delete notification. When
+// the watch missed the deletion the informer passes a DeletedFinalStateUnknown
+// tombstone instead, which holds the last known state of the object.
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
 }
 
 // watchDeployments watches deployment changes
-func (w *Watcher) watchDeployments() {
+func (w *Watcher) watchDeployments(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.AppsV1().RESTClient(),
 		"deployments",
@@ -118,21 +295,24 @@ func (w *Watcher) watchDeployments() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&appsv1.Deployment{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleDeploymentEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleDeploymentEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleDeploymentEvent(watch.Deleted, obj, nil)
+				w.handleDeploymentEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 // handleDeploymentEvent processes deployment events
@@ -183,12 +363,9 @@ func (w *Watcher) handleDeploymentEvent(eventType watch.EventType, oldObj, newOb
 		}
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
+		event.ChangesetID = w.startChangeset(deployment.Namespace, deployment.Name, event.Timestamp)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving deployment event: %v", err)
-		} else {
-			log.Printf("Saved %s event for deployment %s/%s: %s", eventType, deployment.Namespace, deployment.Name, changeDescription)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -206,7 +383,10 @@ func (w *Watcher) handleDeploymentEvent(eventType watch.EventType, oldObj, newOb
 			newMap := convertToMap(deployment)
 			event.ImageAfter = diff.ExtractImage(newMap)
 			event.Diff = "Deployment created"
+			// Creating a Deployment rolls out its first ReplicaSet
+			event.ChangesetID = w.startChangeset(deployment.Namespace, deployment.Name, event.Timestamp)
 		} else {
+			event.ChangesetID = uuid.NewString()
 			event.Diff = "Deployment deleted"
 		}
 
@@ -216,17 +396,99 @@ func (w *Watcher) handleDeploymentEvent(eventType watch.EventType, oldObj, newOb
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving deployment event: %v", err)
-		} else {
-			log.Printf("Saved %s event for deployment %s/%s", eventType, deployment.Namespace, deployment.Name)
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+	}
+}
+
+// trackedResources are the resources reported by resourceChanges
+var trackedResources = []struct {
+	name  corev1.ResourceName
+	label string
+}{
+	{corev1.ResourceCPU, "CPU"},
+	{corev1.ResourceMemory, "Memory"},
+}
+
+// resourceChanges describes the CPU and memory limits and requests that
+// differ between containers of the same name, e.g. "CPU limit: 500m → 1".
+// Pods with several containers name the container. Containers that were
+// added or removed are not compared.
+func resourceChanges(oldSpec, newSpec corev1.PodSpec) []string {
+	oldContainers := make(map[string]corev1.Container, len(oldSpec.Containers))
+	for _, c := range oldSpec.Containers {
+		oldContainers[c.Name] = c
+	}
+
+	var changes []string
+	for _, c := range newSpec.Containers {
+		old, ok := oldContainers[c.Name]
+		if !ok {
+			continue
+		}
+		prefix := ""
+		if len(newSpec.Containers) > 1 {
+			prefix = "Container " + c.Name + " "
+		}
+		for _, list := range []struct {
+			label    string
+			old, new corev1.ResourceList
+		}{
+			{"limit", old.Resources.Limits, c.Resources.Limits},
+			{"request", old.Resources.Requests, c.Resources.Requests},
+		} {
+			for _, r := range trackedResources {
+				oldQty, hadOld := list.old[r.name]
+				newQty, hasNew := list.new[r.name]
+				if hadOld == hasNew && (!hasNew || oldQty.Cmp(newQty) == 0) {
+					continue // 1000m and 1 are the same CPU
+				}
+				changes = append(changes, fmt.Sprintf("%s%s %s: %s → %s",
+					prefix, r.label, list.label, formatQuantity(list.old, r.name), formatQuantity(list.new, r.name)))
+			}
+		}
+	}
+	return changes
+}
+
+// formatQuantity returns a resource's quantity from a list, or "(none)"
+func formatQuantity(list corev1.ResourceList, name corev1.ResourceName) string {
+	qty, ok := list[name]
+	if !ok {
+		return "(none)"
+	}
+	return qty.String()
+}
+
+// privilegedContainersAdded describes the containers that run privileged in
+// newSpec but did not in oldSpec
+func privilegedContainersAdded(oldSpec, newSpec corev1.PodSpec) []string {
+	wasPrivileged := make(map[string]bool)
+	for _, containers := range [][]corev1.Container{oldSpec.InitContainers, oldSpec.Containers} {
+		for _, c := range containers {
+			wasPrivileged[c.Name] = isPrivileged(c)
+		}
+	}
+
+	var added []string
+	for _, containers := range [][]corev1.Container{newSpec.InitContainers, newSpec.Containers} {
+		for _, c := range containers {
+			if isPrivileged(c) && !wasPrivileged[c.Name] {
+				added = append(added, "Privileged container added: "+c.Name)
+			}
 		}
 	}
+	return added
 }
 
-// detectMeaningfulChanges checks for scale, image, or spec changes
+func isPrivileged(c corev1.Container) bool {
+	return c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged
+}
+
+// detectMeaningfulChanges checks for scale, image, or spec changes and
+// describes each one on its own line
 func (w *Watcher) detectMeaningfulChanges(oldDep, newDep *appsv1.Deployment) (bool, string) {
-	changes := []string{}
+	// Privilege escalations come first
+	changes := privilegedContainersAdded(oldDep.Spec.Template.Spec, newDep.Spec.Template.Spec)
 
 	// Check for replica changes (scale up/down)
 	oldReplicas := int32(0)
@@ -259,18 +521,7 @@ func (w *Watcher) detectMeaningfulChanges(oldDep, newDep *appsv1.Deployment) (bo
 		}
 
 		// Check for resource changes
-		oldResources := oldContainers[0].Resources
-		newResources := newContainers[0].Resources
-
-		if !oldResources.Limits.Cpu().Equal(*newResources.Limits.Cpu()) ||
-			!oldResources.Limits.Memory().Equal(*newResources.Limits.Memory()) {
-			changes = append(changes, "Resource limits updated")
-		}
-
-		if !oldResources.Requests.Cpu().Equal(*newResources.Requests.Cpu()) ||
-			!oldResources.Requests.Memory().Equal(*newResources.Requests.Memory()) {
-			changes = append(changes, "Resource requests updated")
-		}
+		changes = append(changes, resourceChanges(oldDep.Spec.Template.Spec, newDep.Spec.Template.Spec)...)
 
 		// Check for env var changes
 		if len(oldContainers[0].Env) != len(newContainers[0].Env) {
@@ -287,11 +538,11 @@ func (w *Watcher) detectMeaningfulChanges(oldDep, newDep *appsv1.Deployment) (bo
 		return false, ""
 	}
 
-	return true, fmt.Sprintf("%s", changes[0])
+	return true, strings.Join(changes, "\n")
 }
 
 // watchConfigMaps watches configmap changes
-func (w *Watcher) watchConfigMaps() {
+func (w *Watcher) watchConfigMaps(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.CoreV1().RESTClient(),
 		"configmaps",
@@ -302,21 +553,24 @@ func (w *Watcher) watchConfigMaps() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&corev1.ConfigMap{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleConfigMapEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleConfigMapEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleConfigMapEvent(watch.Deleted, obj, nil)
+				w.handleConfigMapEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 // handleConfigMapEvent processes configmap events
@@ -356,21 +610,13 @@ func (w *Watcher) handleConfigMapEvent(eventType watch.EventType, oldObj, newObj
 		}
 
 		// Extract metadata
-		keys := make([]string, 0, len(cm.Data))
-		for k := range cm.Data {
-			keys = append(keys, k)
-		}
 		metadata := map[string]interface{}{
-			"keys": keys,
+			"keys": configMapKeys(cm),
 		}
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving configmap event: %v", err)
-		} else {
-			log.Printf("Saved %s event for configmap %s/%s: %s", eventType, cm.Namespace, cm.Name, changeDescription)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -384,58 +630,40 @@ func (w *Watcher) handleConfigMapEvent(eventType watch.EventType, oldObj, newObj
 			Action:    string(eventType),
 		}
 
+		keys := configMapKeys(cm)
 		if eventType == watch.Added {
-			keys := make([]string, 0, len(cm.Data))
-			for k := range cm.Data {
-				keys = append(keys, k)
-			}
 			event.Diff = fmt.Sprintf("ConfigMap created with %d key(s)", len(keys))
 		} else {
 			event.Diff = "ConfigMap deleted"
 		}
 
-		keys := make([]string, 0, len(cm.Data))
-		for k := range cm.Data {
-			keys = append(keys, k)
-		}
 		metadata := map[string]interface{}{
 			"keys": keys,
 		}
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving configmap event: %v", err)
-		} else {
-			log.Printf("Saved %s event for configmap %s/%s", eventType, cm.Namespace, cm.Name)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 	}
 }
 
 // detectConfigMapChanges checks for key additions, removals, or value changes
+// in data and binaryData. Binary values are described by their size.
 func (w *Watcher) detectConfigMapChanges(oldCM, newCM *corev1.ConfigMap) (bool, string) {
-	oldKeys := make(map[string]bool)
-	for k := range oldCM.Data {
-		oldKeys[k] = true
-	}
-
-	newKeys := make(map[string]bool)
-	for k := range newCM.Data {
-		newKeys[k] = true
-	}
+	oldKeys, newKeys := configMapKeys(oldCM), configMapKeys(newCM)
 
 	// Check for added keys
 	addedKeys := []string{}
-	for k := range newCM.Data {
-		if !oldKeys[k] {
+	for _, k := range newKeys {
+		if !slices.Contains(oldKeys, k) {
 			addedKeys = append(addedKeys, k)
 		}
 	}
 
 	// Check for removed keys
 	removedKeys := []string{}
-	for k := range oldCM.Data {
-		if !newKeys[k] {
+	for _, k := range oldKeys {
+		if !slices.Contains(newKeys, k) {
 			removedKeys = append(removedKeys, k)
 		}
 	}
@@ -443,11 +671,19 @@ func (w *Watcher) detectConfigMapChanges(oldCM, newCM *corev1.ConfigMap) (bool,
 	// Check for modified values and collect full details
 	modifiedKeys := []string{}
 	detailedChanges := []string{}
-	for k, newVal := range newCM.Data {
-		if oldVal, exists := oldCM.Data[k]; exists && oldVal != newVal {
-			modifiedKeys = append(modifiedKeys, k)
-			// Store full change details for timeline
-			detailedChanges = append(detailedChanges, fmt.Sprintf("[%s]\n- %s\n+ %s", k, oldVal, newVal))
+	for _, k := range newKeys {
+		if oldVal, exists := oldCM.Data[k]; exists {
+			if newVal, ok := newCM.Data[k]; ok && oldVal != newVal {
+				modifiedKeys = append(modifiedKeys, k)
+				// Store full change details for timeline
+				detailedChanges = append(detailedChanges, fmt.Sprintf("[%s]\n- %s\n+ %s", k, oldVal, newVal))
+			}
+		}
+		if oldVal, exists := oldCM.BinaryData[k]; exists {
+			if newVal, ok := newCM.BinaryData[k]; ok && !bytes.Equal(oldVal, newVal) {
+				modifiedKeys = append(modifiedKeys, k)
+				detailedChanges = append(detailedChanges, fmt.Sprintf("[%s]\n- (binary, %d bytes)\n+ (binary, %d bytes)", k, len(oldVal), len(newVal)))
+			}
 		}
 	}
 
@@ -469,8 +705,18 @@ func (w *Watcher) detectConfigMapChanges(oldCM, newCM *corev1.ConfigMap) (bool,
 	return true, changeDesc
 }
 
+// configMapKeys returns the sorted keys of a ConfigMap's data and binaryData
+func configMapKeys(cm *corev1.ConfigMap) []string {
+	keys := slices.Collect(maps.Keys(cm.Data))
+	for k := range cm.BinaryData {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
 // watchSecrets watches secret changes
-func (w *Watcher) watchSecrets() {
+func (w *Watcher) watchSecrets(stopCh <-chan struct{}) {
 	watchlist := cache.NewListWatchFromClient(
 		w.clientset.CoreV1().RESTClient(),
 		"secrets",
@@ -481,21 +727,24 @@ func (w *Watcher) watchSecrets() {
 	_, controller := cache.NewInformer(
 		watchlist,
 		&corev1.Secret{},
-		time.Second*30,
+		w.opts.ResyncPeriod,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				w.handleSecretEvent(watch.Added, nil, obj)
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
+				if w.ignoreUpdate(oldObj, newObj) {
+					return
+				}
 				w.handleSecretEvent(watch.Modified, oldObj, newObj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				w.handleSecretEvent(watch.Deleted, obj, nil)
+				w.handleSecretEvent(watch.Deleted, deletedObject(obj), nil)
 			},
 		},
 	)
 
-	controller.Run(w.stopCh)
+	controller.Run(stopCh)
 }
 
 // handleSecretEvent processes secret events
@@ -546,18 +795,17 @@ func (w *Watcher) handleSecretEvent(eventType watch.EventType, oldObj, newObj in
 		for k := range secret.Data {
 			keys = append(keys, k)
 		}
+		newHashes := w.hashSecretData(secret.Data)
 		metadata := map[string]interface{}{
-			"type": secret.Type,
-			"keys": keys,
+			"type":         secret.Type,
+			"keys":         keys,
+			"key_hashes":   newHashes,
+			"changed_keys": changedSecretKeys(w.hashSecretData(oldSecret.Data), newHashes),
 		}
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving secret event: %v", err)
-		} else {
-			log.Printf("Saved %s event for secret %s/%s: %s", eventType, secret.Namespace, secret.Name, changeDescription)
-		}
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
 		return
 	}
 
@@ -586,18 +834,41 @@ func (w *Watcher) handleSecretEvent(eventType watch.EventType, oldObj, newObj in
 			keys = append(keys, k)
 		}
 		metadata := map[string]interface{}{
-			"type": secret.Type,
-			"keys": keys,
+			"type":       secret.Type,
+			"keys":       keys,
+			"key_hashes": w.hashSecretData(secret.Data),
 		}
 		metadataJSON, _ := json.Marshal(metadata)
 		event.Metadata = string(metadataJSON)
 
-		if err := w.saveAndNotify(event); err != nil {
-			log.Printf("Error saving secret event: %v", err)
-		} else {
-			log.Printf("Saved %s event for secret %s/%s", eventType, secret.Namespace, secret.Name)
+		w.logSaved(event, w.saveAndNotify(event, oldObj, newObj))
+	}
+}
+
+// hashSecretData returns "hmac-sha256:<hex>" digests of each secret value, so
+// values can be compared across events without storing plaintext. The digests
+// are keyed with secretHashKey: a plain SHA-256 of a short value could be
+// brute-forced from the database.
+func (w *Watcher) hashSecretData(data map[string][]byte) map[string]string {
+	hashes := make(map[string]string, len(data))
+	for k, v := range data {
+		mac := hmac.New(sha256.New, w.secretHashKey)
+		mac.Write(v)
+		hashes[k] = "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return hashes
+}
+
+// changedSecretKeys returns the sorted keys present in both sets of digests
+// whose digests differ
+func changedSecretKeys(oldHashes, newHashes map[string]string) []string {
+	changed := []string{}
+	for _, k := range slices.Sorted(maps.Keys(newHashes)) {
+		if oldHash, ok := oldHashes[k]; ok && oldHash != newHashes[k] {
+			changed = append(changed, k)
 		}
 	}
+	return changed
 }
 
 // detectSecretChanges checks for key additions, removals, or type changes
@@ -633,16 +904,8 @@ func (w *Watcher) detectSecretChanges(oldSecret, newSecret *corev1.Secret) (bool
 		}
 	}
 
-	// Check for modified values (show which keys changed, not values for security)
-	modifiedKeys := []string{}
-	for k, newVal := range newSecret.Data {
-		if oldVal, exists := oldSecret.Data[k]; exists {
-			// Compare byte slices
-			if string(oldVal) != string(newVal) {
-				modifiedKeys = append(modifiedKeys, k)
-			}
-		}
-	}
+	// Check for modified values by hash (show which keys changed, never values)
+	modifiedKeys := changedSecretKeys(w.hashSecretData(oldSecret.Data), w.hashSecretData(newSecret.Data))
 
 	if len(addedKeys) == 0 && len(removedKeys) == 0 && len(modifiedKeys) == 0 {
 		return false, ""
@@ -662,25 +925,115 @@ func (w *Watcher) detectSecretChanges(oldSecret, newSecret *corev1.Secret) (bool
 	return false, ""
 }
 
-// saveAndNotify saves an event and sends notification
-func (w *Watcher) saveAndNotify(event *storage.ChangeEvent) error {
-	// Save to database
-	if err := w.storage.SaveEvent(event); err != nil {
-		return err
+// saveAndNotify saves an event and sends notification.
+// oldObj and newObj are the raw informer objects the event was built from.
+func (w *Watcher) saveAndNotify(event *storage.ChangeEvent, oldObj, newObj interface{}) error {
+	w.enrichEvent(event, oldObj, newObj)
+	w.recordEvent(event)
+
+	if w.opts.DryRun {
+		w.logger.Info("Dry run event", append(eventAttrs(event),
+			slog.String("severity", event.Severity),
+			slog.String("author", event.Author),
+			slog.String("diff", event.Diff),
+		)...)
+		return nil
 	}
 
-	// Send Slack notification (non-blocking)
-	if w.notifier.IsEnabled() {
-		go func() {
-			if err := w.notifier.NotifyChange(event); err != nil {
-				log.Printf("Warning: Failed to send Slack notification: %v", err)
-			}
-		}()
+	w.truncateDiff(event)
+
+	notify := storage.SeverityRank(event.Severity) >= storage.SeverityRank(w.opts.NotifyMinSeverity)
+	snapshot := w.opts.StoreSnapshots && event.Action == string(watch.Modified)
+
+	// Save to database. Snapshots and the notification log are keyed by
+	// event ID, so those events bypass the write queue to get their ID back.
+	if snapshot || (notify && w.notifiers.Tracks(event)) {
+		err := w.storage.SaveEventSync(w.ctx, event)
+		if errors.Is(err, storage.ErrDuplicateEvent) {
+			return nil // stored and notified before
+		}
+		if err != nil {
+			return err
+		}
+		if snapshot {
+			w.saveSnapshot(event, oldObj, newObj)
+		}
+	} else if err := w.storage.SaveEvent(w.ctx, event); err != nil {
+		return err
 	}
 
+	w.runPlugins(event)
+	if notify {
+		w.notifiers.Notify(w.ctx, event) // non-blocking
+	}
 	return nil
 }
 
+// Notifications returns the queue delivering events to the notifiers
+func (w *Watcher) Notifications() *notifier.RetryQueue {
+	return w.notifiers
+}
+
+// logSaved logs the outcome of saving an event
+func (w *Watcher) logSaved(event *storage.ChangeEvent, err error) {
+	if err != nil {
+		w.logger.Error("Failed to save event", append(eventAttrs(event), slog.Any("error", err))...)
+		return
+	}
+	if w.opts.DryRun {
+		return // already logged by saveAndNotify
+	}
+	w.logger.Info("Saved event", eventAttrs(event)...)
+}
+
+// eventAttrs returns the identifying fields of an event for structured logs
+func eventAttrs(event *storage.ChangeEvent) []any {
+	return []any{
+		slog.String("kind", event.Kind),
+		slog.String("namespace", event.Namespace),
+		slog.String("name", event.Name),
+		slog.String("action", event.Action),
+	}
+}
+
+// enrichEvent fills in the object identity and authorship fields shared by all kinds
+func (w *Watcher) enrichEvent(event *storage.ChangeEvent, oldObj, newObj interface{}) {
+	obj := newObj
+	if obj == nil {
+		obj = oldObj
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		if event.UID == "" {
+			event.UID = string(accessor.GetUID())
+		}
+		if event.ResourceVersion == "" {
+			event.ResourceVersion = accessor.GetResourceVersion()
+		}
+		if event.App == "" && w.opts.AppLabel != "" {
+			event.App = accessor.GetLabels()[w.opts.AppLabel]
+		}
+	}
+
+	if w.opts.ManagedFields {
+		if event.Author == "" {
+			event.Author = extractAuthor(oldObj, newObj)
+		}
+		if event.Source == "" {
+			event.Source = detectSource(event.Author, oldObj, newObj)
+		}
+	}
+
+	w.classifySeverity(event)
+
+	if event.Actor == "" && w.opts.AuditLog != nil {
+		namespace := event.Namespace
+		if namespace == clusterWideNamespace {
+			namespace = "" // cluster-scoped objects have no namespace in the audit log
+		}
+		event.Actor = w.opts.AuditLog.Lookup(resourceForKind(event.Kind), namespace, event.Name, event.Timestamp)
+	}
+}
+
 // convertToMap converts a runtime object to a map for diffing
 func convertToMap(obj runtime.Object) map[string]interface{} {
 	data, err := json.Marshal(obj)
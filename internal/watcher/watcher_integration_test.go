@@ -0,0 +1,168 @@
+//go:build integration
+
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"k8watch/internal/storage"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// The tests in this file run the watcher against a real kube-apiserver and
+// etcd started by envtest. Install the binaries with setup-envtest and run
+//
+//	KUBEBUILDER_ASSETS=$(setup-envtest use -p path) go test -tags integration ./internal/watcher/
+
+// startAPIServer starts an API server for the test and returns the path of a
+// kubeconfig for it and a client
+func startAPIServer(t *testing.T) (string, kubernetes.Interface) {
+	t.Helper()
+	env := &envtest.Environment{}
+	config, err := env.Start()
+	if err != nil {
+		t.Fatalf("starting envtest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Stop(); err != nil {
+			t.Errorf("stopping envtest: %v", err)
+		}
+	})
+
+	user, err := env.AddUser(envtest.User{Name: "k8watch", Groups: []string{"system:masters"}}, nil)
+	if err != nil {
+		t.Fatalf("adding user: %v", err)
+	}
+	kubeconfig, err := user.KubeConfig()
+	if err != nil {
+		t.Fatalf("writing kubeconfig: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, kubeconfig, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	return path, client
+}
+
+// waitForEvents flushes store until it holds count events of kind/name and
+// returns them, oldest first
+func waitForEvents(t *testing.T, store *storage.Storage, kind, name string, count int) []storage.ChangeEvent {
+	t.Helper()
+	ctx := context.Background()
+	var events []storage.ChangeEvent
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if err := store.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		events, err = store.GetEvents(ctx, storage.Filter{Kind: kind, Name: name, OrderBy: "id", Order: storage.SortAsc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) >= count {
+			return events
+		}
+	}
+	t.Fatalf("stored %d %s events, want %d: %+v", len(events), kind, count, events)
+	return nil
+}
+
+func TestDeploymentLifecycle(t *testing.T) {
+	kubeconfig, client := startAPIServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	logger := slog.New(slog.DiscardHandler)
+
+	// A SQLite database in a file: the connection pool would give each
+	// connection its own :memory: database
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "events.db"), "", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	w, err := NewWatcher(ctx, kubeconfig, store, nil, Options{WatchKinds: []string{"Deployment"}}, logger)
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	if err := w.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(w.Stop)
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}}
+	if _, err := client.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deployments := client.AppsV1().Deployments("shop")
+
+	// (1) Created
+	replicas := int32(1)
+	labels := map[string]string{"app": "web"}
+	deployment, err := deployments.Create(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "nginx:1.26"}}},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := waitForEvents(t, store, "Deployment", "web", 1)
+	if events[0].Action != "ADDED" || events[0].Namespace != "shop" {
+		t.Errorf("first event = %s in %s, want ADDED in shop", events[0].Action, events[0].Namespace)
+	}
+
+	// (2) Image updated
+	deployment.Spec.Template.Spec.Containers[0].Image = "nginx:1.27"
+	if deployment, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	events = waitForEvents(t, store, "Deployment", "web", 2)
+	if modified := events[1]; modified.Action != "MODIFIED" || modified.ImageBefore != "nginx:1.26" || modified.ImageAfter != "nginx:1.27" {
+		t.Errorf("second event = %s %q → %q, want MODIFIED nginx:1.26 → nginx:1.27", modified.Action, modified.ImageBefore, modified.ImageAfter)
+	}
+
+	// (4) Only the status changes: no event. There is no controller in
+	// envtest, so the status only changes when the test sets it.
+	deployment.Status.Replicas = 1
+	deployment.Status.ReadyReplicas = 1
+	if _, err := deployments.UpdateStatus(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// (3) Deleted. The watcher handles events in order, so by the time the
+	// deletion is stored the status update has been handled too.
+	if err := deployments.Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	events = waitForEvents(t, store, "Deployment", "web", 3)
+	actions := make([]string, len(events))
+	for i, event := range events {
+		actions[i] = event.Action
+	}
+	if want := []string{"ADDED", "MODIFIED", "DELETED"}; !slices.Equal(actions, want) {
+		t.Errorf("actions = %v, want %v; the status update must not be stored", actions, want)
+	}
+}
@@ -0,0 +1,549 @@
+package watcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8watch/internal/notifier"
+	"k8watch/internal/storage"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDetectConfigMapChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldCM       *corev1.ConfigMap
+		newCM       *corev1.ConfigMap
+		wantChanges bool
+		wantDiff    string
+	}{
+		{
+			name:  "no change",
+			oldCM: &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			newCM: &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+		},
+		{
+			name:        "key added",
+			oldCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info", "timeout": "30s"}},
+			wantChanges: true,
+			wantDiff:    "Keys added: [timeout]",
+		},
+		{
+			name:        "key removed",
+			oldCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info", "timeout": "30s"}},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			wantChanges: true,
+			wantDiff:    "Keys removed: [timeout]",
+		},
+		{
+			name:        "value changed",
+			oldCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "debug"}},
+			wantChanges: true,
+			wantDiff:    "Keys modified: [log_level]\n\n[log_level]\n- info\n+ debug",
+		},
+		{
+			name:        "multiple keys",
+			oldCM:       &corev1.ConfigMap{Data: map[string]string{"a": "1", "b": "1", "c": "1"}},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"a": "2", "b": "1", "c": "3"}},
+			wantChanges: true,
+			wantDiff:    "Keys modified: [a c]\n\n[a]\n- 1\n+ 2\n\n[c]\n- 1\n+ 3",
+		},
+		{
+			name:        "binary data changed",
+			oldCM:       &corev1.ConfigMap{BinaryData: map[string][]byte{"logo.png": {0x89, 0x50, 0x4e}}},
+			newCM:       &corev1.ConfigMap{BinaryData: map[string][]byte{"logo.png": {0x89, 0x50, 0x4e, 0x47}}},
+			wantChanges: true,
+			wantDiff:    "Keys modified: [logo.png]\n\n[logo.png]\n- (binary, 3 bytes)\n+ (binary, 4 bytes)",
+		},
+		{
+			name:        "binary key added",
+			oldCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}, BinaryData: map[string][]byte{"logo.png": {0x89}}},
+			wantChanges: true,
+			wantDiff:    "Keys added: [logo.png]",
+		},
+		{
+			name:        "key added to an empty ConfigMap",
+			oldCM:       &corev1.ConfigMap{},
+			newCM:       &corev1.ConfigMap{Data: map[string]string{"log_level": "info"}},
+			wantChanges: true,
+			wantDiff:    "Keys added: [log_level]",
+		},
+	}
+
+	w := &Watcher{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasChanges, diff := w.detectConfigMapChanges(tt.oldCM, tt.newCM)
+			if hasChanges != tt.wantChanges {
+				t.Errorf("hasChanges = %v, want %v", hasChanges, tt.wantChanges)
+			}
+			if diff != tt.wantDiff {
+				t.Errorf("diff = %q, want %q", diff, tt.wantDiff)
+			}
+		})
+	}
+}
+
+func TestDetectSecretChanges(t *testing.T) {
+	const (
+		oldPassword = "hunter2-old-password"
+		newPassword = "correct-horse-battery-staple"
+		apiKey      = "sk-live-1234567890abcdef"
+	)
+
+	tests := []struct {
+		name        string
+		oldSecret   *corev1.Secret
+		newSecret   *corev1.Secret
+		wantChanges bool
+		wantKeys    []string // keys the diff must name
+	}{
+		{
+			name:      "no change",
+			oldSecret: &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword)}},
+			newSecret: &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword)}},
+		},
+		{
+			name:        "value rotated",
+			oldSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword), "api_key": []byte(apiKey)}},
+			newSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(newPassword), "api_key": []byte(apiKey)}},
+			wantChanges: true,
+			wantKeys:    []string{"password"},
+		},
+		{
+			name:        "key added",
+			oldSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword)}},
+			newSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword), "api_key": []byte(apiKey)}},
+			wantChanges: true,
+			wantKeys:    []string{"api_key"},
+		},
+		{
+			name:        "key removed",
+			oldSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword), "api_key": []byte(apiKey)}},
+			newSecret:   &corev1.Secret{Data: map[string][]byte{"password": []byte(oldPassword)}},
+			wantChanges: true,
+			wantKeys:    []string{"api_key"},
+		},
+		{
+			name:        "type changed",
+			oldSecret:   &corev1.Secret{Type: corev1.SecretTypeOpaque, Data: map[string][]byte{"password": []byte(oldPassword)}},
+			newSecret:   &corev1.Secret{Type: corev1.SecretTypeBasicAuth, Data: map[string][]byte{"password": []byte(newPassword)}},
+			wantChanges: true,
+		},
+	}
+
+	w := &Watcher{secretHashKey: []byte("test-key")}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasChanges, diff := w.detectSecretChanges(tt.oldSecret, tt.newSecret)
+			if hasChanges != tt.wantChanges {
+				t.Errorf("hasChanges = %v, want %v", hasChanges, tt.wantChanges)
+			}
+			for _, key := range tt.wantKeys {
+				if !strings.Contains(diff, key) {
+					t.Errorf("diff %q does not name key %q", diff, key)
+				}
+			}
+			for _, value := range []string{oldPassword, newPassword, apiKey} {
+				if strings.Contains(diff, value) || strings.Contains(diff, base64.StdEncoding.EncodeToString([]byte(value))) {
+					t.Errorf("diff %q contains a secret value", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestChangedSecretKeys(t *testing.T) {
+	oldData := map[string][]byte{"a": []byte("1"), "b": []byte("1"), "c": []byte("1"), "removed": []byte("1")}
+	newData := map[string][]byte{"c": []byte("2"), "a": []byte("2"), "b": []byte("1"), "added": []byte("1")}
+
+	w := &Watcher{secretHashKey: []byte("test-key")}
+	got := changedSecretKeys(w.hashSecretData(oldData), w.hashSecretData(newData))
+	if strings.Join(got, ",") != "a,c" {
+		t.Errorf("changedSecretKeys() = %v, want [a c]", got)
+	}
+}
+
+func TestHashSecretData(t *testing.T) {
+	data := map[string][]byte{"password": []byte("hunter2")}
+	plain := sha256.Sum256(data["password"])
+
+	w := &Watcher{secretHashKey: []byte("test-key")}
+	hashes := w.hashSecretData(data)
+	if !strings.HasPrefix(hashes["password"], "hmac-sha256:") {
+		t.Fatalf("hash = %q, want an hmac-sha256 digest", hashes["password"])
+	}
+	if strings.Contains(hashes["password"], hex.EncodeToString(plain[:])) {
+		t.Error("hash is the unkeyed SHA-256 of the value")
+	}
+	if again := w.hashSecretData(data); again["password"] != hashes["password"] {
+		t.Error("the same value and key give different digests")
+	}
+	other := &Watcher{secretHashKey: []byte("other-key")}
+	if other.hashSecretData(data)["password"] == hashes["password"] {
+		t.Error("digests do not depend on the key")
+	}
+}
+
+func TestStopTwice(t *testing.T) {
+	// No kinds are enabled, so Start runs no informers and needs no cluster
+	w := &Watcher{logger: slog.New(slog.DiscardHandler), restarts: make(map[string]*kindTracker)}
+
+	if err := w.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	stopCh := w.stopCh
+	w.Stop()
+	w.Stop()
+	select {
+	case <-stopCh:
+	default:
+		t.Fatal("Stop did not close the stop channel")
+	}
+
+	// Stopping concurrently after a restart closes the new channel exactly once
+	if err := w.Start(); err != nil {
+		t.Fatalf("Start after Stop: %v", err)
+	}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Stop()
+		}()
+	}
+	wg.Wait()
+	if w.stopCh != nil {
+		t.Error("stopCh is still set after Stop")
+	}
+}
+
+func TestRunWatchOnceRecoversPanic(t *testing.T) {
+	w := &Watcher{logger: slog.New(slog.DiscardHandler), restarts: make(map[string]*kindTracker)}
+
+	w.runWatchOnce("Deployment", func(<-chan struct{}) { panic("informer exploded") }, make(chan struct{}))
+	if w.tracker("Deployment").running.Load() {
+		t.Error("tracker still reports the watcher as running after the panic")
+	}
+}
+
+// deployment returns a Deployment running one container per resources entry
+func deployment(resources ...corev1.ResourceRequirements) *appsv1.Deployment {
+	dep := &appsv1.Deployment{}
+	for i, r := range resources {
+		dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, corev1.Container{
+			Name:      []string{"app", "sidecar"}[i],
+			Image:     "nginx:1.27",
+			Resources: r,
+		})
+	}
+	return dep
+}
+
+func TestDetectMeaningfulChangesWithoutResources(t *testing.T) {
+	limits := corev1.ResourceRequirements{Limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}}
+
+	tests := []struct {
+		name     string
+		oldDep   *appsv1.Deployment
+		newDep   *appsv1.Deployment
+		wantDiff string
+	}{
+		{
+			name:   "no limits on either side",
+			oldDep: deployment(corev1.ResourceRequirements{}),
+			newDep: deployment(corev1.ResourceRequirements{}),
+		},
+		{
+			name:   "empty lists on either side",
+			oldDep: deployment(corev1.ResourceRequirements{Limits: corev1.ResourceList{}}),
+			newDep: deployment(corev1.ResourceRequirements{}),
+		},
+		{
+			name:     "limits added",
+			oldDep:   deployment(corev1.ResourceRequirements{}),
+			newDep:   deployment(limits),
+			wantDiff: "CPU limit: (none) → 500m\nMemory limit: (none) → 256Mi",
+		},
+		{
+			name:     "limits removed",
+			oldDep:   deployment(limits),
+			newDep:   deployment(corev1.ResourceRequirements{}),
+			wantDiff: "CPU limit: 500m → (none)\nMemory limit: 256Mi → (none)",
+		},
+		{
+			name:   "no containers",
+			oldDep: &appsv1.Deployment{},
+			newDep: &appsv1.Deployment{},
+		},
+	}
+
+	w := &Watcher{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasChanges, diff := w.detectMeaningfulChanges(tt.oldDep, tt.newDep)
+			if hasChanges != (tt.wantDiff != "") {
+				t.Errorf("hasChanges = %v, want %v", hasChanges, tt.wantDiff != "")
+			}
+			if diff != tt.wantDiff {
+				t.Errorf("diff = %q, want %q", diff, tt.wantDiff)
+			}
+		})
+	}
+}
+
+func TestResourceChanges(t *testing.T) {
+	requirements := func(limitCPU, limitMem, requestCPU string) corev1.ResourceRequirements {
+		r := corev1.ResourceRequirements{Limits: corev1.ResourceList{}, Requests: corev1.ResourceList{}}
+		if limitCPU != "" {
+			r.Limits[corev1.ResourceCPU] = resource.MustParse(limitCPU)
+		}
+		if limitMem != "" {
+			r.Limits[corev1.ResourceMemory] = resource.MustParse(limitMem)
+		}
+		if requestCPU != "" {
+			r.Requests[corev1.ResourceCPU] = resource.MustParse(requestCPU)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		oldDep *appsv1.Deployment
+		newDep *appsv1.Deployment
+		want   []string
+	}{
+		{
+			name:   "millicores to cores",
+			oldDep: deployment(requirements("500m", "", "")),
+			newDep: deployment(requirements("1", "", "")),
+			want:   []string{"CPU limit: 500m → 1"},
+		},
+		{
+			name:   "binary memory units",
+			oldDep: deployment(requirements("", "256Mi", "")),
+			newDep: deployment(requirements("", "1Gi", "")),
+			want:   []string{"Memory limit: 256Mi → 1Gi"},
+		},
+		{
+			name:   "decimal memory units",
+			oldDep: deployment(requirements("", "500M", "")),
+			newDep: deployment(requirements("", "2G", "")),
+			want:   []string{"Memory limit: 500M → 2G"},
+		},
+		{
+			name:   "equal quantities in different units",
+			oldDep: deployment(requirements("1000m", "1024Mi", "")),
+			newDep: deployment(requirements("1", "1Gi", "")),
+		},
+		{
+			name:   "request added",
+			oldDep: deployment(requirements("1", "", "")),
+			newDep: deployment(requirements("1", "", "250m")),
+			want:   []string{"CPU request: (none) → 250m"},
+		},
+		{
+			name:   "several containers",
+			oldDep: deployment(requirements("500m", "", ""), requirements("", "64Mi", "")),
+			newDep: deployment(requirements("750m", "", ""), requirements("", "128Mi", "")),
+			want: []string{
+				"Container app CPU limit: 500m → 750m",
+				"Container sidecar Memory limit: 64Mi → 128Mi",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resourceChanges(tt.oldDep.Spec.Template.Spec, tt.newDep.Spec.Template.Spec)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("resourceChanges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectMeaningfulChangesJoinsChanges(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	oldDep := deployment(corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}})
+	oldDep.Spec.Replicas = replicas(2)
+	newDep := deployment(corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}})
+	newDep.Spec.Replicas = replicas(4)
+	newDep.Spec.Template.Spec.Containers[0].Image = "nginx:1.28"
+
+	hasChanges, diff := (&Watcher{}).detectMeaningfulChanges(oldDep, newDep)
+	want := "Scaled up: 2 → 4 replicas\nImage updated: nginx:1.27 → nginx:1.28\nCPU limit: 500m → 1"
+	if !hasChanges || diff != want {
+		t.Errorf("detectMeaningfulChanges() = %v, %q; want true, %q", hasChanges, diff, want)
+	}
+}
+
+// newTestWatcher returns a watcher that saves into store and has no cluster
+// connection or notifiers
+func newTestWatcher(t *testing.T, store storage.EventStore) *Watcher {
+	t.Helper()
+	defaultSeverityRules, err := compileSeverityRules(DefaultSeverityRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.DiscardHandler)
+	return &Watcher{
+		ctx:                  context.Background(),
+		logger:               logger,
+		storage:              store,
+		notifiers:            notifier.NewRetryQueue(store, nil, logger),
+		restarts:             make(map[string]*kindTracker),
+		changesets:           make(map[string]openChangeset),
+		defaultSeverityRules: defaultSeverityRules,
+		secretHashKey:        []byte("test-key"),
+	}
+}
+
+// storedEvents returns the events in store, oldest first
+func storedEvents(t *testing.T, store storage.EventStore) []storage.ChangeEvent {
+	t.Helper()
+	events, err := store.GetEvents(context.Background(), storage.Filter{OrderBy: "id", Order: storage.SortAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestHandleConfigMapEvent(t *testing.T) {
+	store := storage.NewMemoryStore()
+	w := newTestWatcher(t, store)
+
+	configMap := func(resourceVersion string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "settings", UID: "cm-1", ResourceVersion: resourceVersion},
+			Data:       data,
+		}
+	}
+	v1 := configMap("1", map[string]string{"log_level": "info"})
+	v2 := configMap("2", map[string]string{"log_level": "info"}) // resync, nothing changed
+	v3 := configMap("3", map[string]string{"log_level": "debug"})
+
+	w.handleConfigMapEvent(watch.Added, nil, v1)
+	w.handleConfigMapEvent(watch.Modified, v1, v2)
+	w.handleConfigMapEvent(watch.Modified, v2, v3)
+	w.handleConfigMapEvent(watch.Deleted, nil, deletedObject(cache.DeletedFinalStateUnknown{Key: "shop/settings", Obj: v3}))
+	w.handleConfigMapEvent(watch.Added, nil, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "coredns"}})
+
+	events := storedEvents(t, store)
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		if event.Namespace != "shop" || event.Kind != "ConfigMap" || event.Name != "settings" || event.UID != "cm-1" {
+			t.Errorf("stored event %+v, want shop/ConfigMap/settings", event)
+		}
+	}
+	if !slices.Equal(actions, []string{"ADDED", "MODIFIED", "DELETED"}) {
+		t.Fatalf("stored actions %v, want ADDED, MODIFIED, DELETED", actions)
+	}
+	if modified := events[1]; modified.Diff != "Keys modified: [log_level]\n\n[log_level]\n- info\n+ debug" || modified.ResourceVersion != "3" {
+		t.Errorf("MODIFIED event diff %q, resourceVersion %q", modified.Diff, modified.ResourceVersion)
+	}
+	if got := w.tracker("ConfigMap").events.Load(); got != 3 {
+		t.Errorf("events processed = %d, want 3", got)
+	}
+}
+
+func TestHandleSecretEventStoresNoValues(t *testing.T) {
+	store := storage.NewMemoryStore()
+	w := newTestWatcher(t, store)
+
+	secret := func(password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "db"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"username": []byte("shop"), "password": []byte(password)},
+		}
+	}
+	oldSecret, newSecret := secret("hunter2-old-password"), secret("correct-horse-battery-staple")
+
+	w.handleSecretEvent(watch.Added, nil, oldSecret)
+	w.handleSecretEvent(watch.Modified, oldSecret, newSecret)
+	w.handleSecretEvent(watch.Modified, newSecret, oldSecret) // rotation reverted
+	w.handleSecretEvent(watch.Added, nil, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "sa-token"},
+		Type:       corev1.SecretTypeServiceAccountToken,
+	})
+
+	events := storedEvents(t, store)
+	if len(events) != 3 {
+		t.Fatalf("stored %d events, want 3", len(events))
+	}
+	if !strings.Contains(events[1].Metadata, `"changed_keys":["password"]`) {
+		t.Errorf("MODIFIED metadata %s does not list the changed key", events[1].Metadata)
+	}
+
+	keyHashes := make([]map[string]string, len(events))
+	for i, event := range events {
+		var metadata struct {
+			KeyHashes map[string]string `json:"key_hashes"`
+		}
+		if err := json.Unmarshal([]byte(event.Metadata), &metadata); err != nil {
+			t.Fatal(err)
+		}
+		keyHashes[i] = metadata.KeyHashes
+	}
+	if keyHashes[0]["password"] == "" || keyHashes[0]["password"] == keyHashes[1]["password"] {
+		t.Errorf("key_hashes %v and %v do not show the rotation", keyHashes[0], keyHashes[1])
+	}
+	if keyHashes[2]["password"] != keyHashes[0]["password"] || keyHashes[2]["username"] != keyHashes[0]["username"] {
+		t.Errorf("key_hashes %v of the reverted secret differ from the original %v", keyHashes[2], keyHashes[0])
+	}
+
+	for _, event := range events {
+		for _, value := range []string{"hunter2-old-password", "correct-horse-battery-staple"} {
+			plain := sha256.Sum256([]byte(value))
+			if strings.Contains(event.Diff, value) || strings.Contains(event.Metadata, value) ||
+				strings.Contains(event.Metadata, base64.StdEncoding.EncodeToString([]byte(value))) ||
+				strings.Contains(event.Metadata, hex.EncodeToString(plain[:])) {
+				t.Errorf("%s event stores a secret value: diff %q, metadata %s", event.Action, event.Diff, event.Metadata)
+			}
+		}
+	}
+}
+
+func TestHandleDeploymentEvent(t *testing.T) {
+	store := storage.NewMemoryStore()
+	w := newTestWatcher(t, store)
+
+	oldDep := deployment(corev1.ResourceRequirements{})
+	oldDep.Namespace, oldDep.Name = "shop", "web"
+	newDep := oldDep.DeepCopy()
+	newDep.Spec.Template.Spec.Containers[0].Image = "nginx:1.28"
+
+	w.handleDeploymentEvent(watch.Modified, oldDep, oldDep.DeepCopy()) // no change
+	w.handleDeploymentEvent(watch.Modified, oldDep, newDep)
+
+	events := storedEvents(t, store)
+	if len(events) != 1 {
+		t.Fatalf("stored %d events, want 1", len(events))
+	}
+	if event := events[0]; event.ImageBefore != "nginx:1.27" || event.ImageAfter != "nginx:1.28" || event.Diff != "Image updated: nginx:1.27 → nginx:1.28" {
+		t.Errorf("stored event %+v", event)
+	}
+}
@@ -5,6 +5,8 @@ let countdown = 10;
 let currentPage = 1;
 let pageSize = 50;
 let totalCount = 0;
+// pageCursors[n] is the cursor that loads page n (page 1 needs none)
+let pageCursors = [null, null];
 
 // Helper function to escape HTML
 function escapeHtml(text) {
@@ -17,6 +19,7 @@ function escapeHtml(text) {
 document.addEventListener('DOMContentLoaded', () => {
     loadData();
     startAutoRefresh();
+    connectStream();
 });
 
 // Switch tabs
@@ -53,16 +56,23 @@ async function loadStats() {
         const stats = await response.json();
         
         document.getElementById('totalChanges').textContent = stats.total_changes || 0;
-        document.getElementById('changes24h').textContent = stats.changes_last_24h || 0;
+        document.getElementById('changes24h').textContent = stats.changes_last_window || 0;
         document.getElementById('changesPerHour').textContent = (stats.changes_per_hour || 0).toFixed(1);
         document.getElementById('recentImagesCount').textContent = (stats.recent_images || []).length;
         
+        // Critical changes turn the card red
+        const critical = (stats.changes_by_severity || {}).critical || 0;
+        const criticalEl = document.getElementById('criticalChanges');
+        criticalEl.textContent = critical;
+        criticalEl.classList.toggle('text-red-600', critical > 0);
+        criticalEl.classList.toggle('text-gray-400', critical === 0);
+        
         // Top modified apps
         const topApps = stats.top_modified_apps || [];
         const topAppsHTML = topApps.length > 0 
             ? topApps.map(app => `
                 <div class="flex items-center justify-between p-3 bg-gray-50 dark:bg-gray-900 rounded">
-                    <span class="text-sm font-medium text-gray-900 dark:text-white">${app.name}</span>
+                    <span class="text-sm font-medium text-gray-900 dark:text-white">${app.name} <span class="text-xs text-gray-500 dark:text-gray-400">${app.kind} in ${app.namespace}</span></span>
                     <span class="px-2 py-1 bg-blue-100 dark:bg-blue-900 text-blue-800 dark:text-blue-200 rounded text-xs">${app.count} changes</span>
                 </div>
             `).join('')
@@ -105,8 +115,8 @@ async function loadEvents() {
     };
     
     const kind = kindMap[currentTab] || 'Deployment';
-    const offset = (currentPage - 1) * pageSize;
-    let url = `/api/events?kind=${kind}&limit=${pageSize}&offset=${offset}`;
+    let url = `/api/events?kind=${kind}&limit=${pageSize}`;
+    if (pageCursors[currentPage]) url += `&cursor=${encodeURIComponent(pageCursors[currentPage])}`;
     if (namespace) url += `&namespace=${encodeURIComponent(namespace)}`;
     if (name) url += `&name=${encodeURIComponent(name)}`;
     if (action) url += `&action=${encodeURIComponent(action)}`;
@@ -116,6 +126,7 @@ async function loadEvents() {
         const data = await response.json();
         const events = data.events || [];
         totalCount = data.total_count || 0;
+        pageCursors[currentPage + 1] = data.next_cursor || null;
         
         const tbody = document.getElementById('eventsTable');
         if (events.length === 0) {
@@ -163,6 +174,7 @@ async function loadEvents() {
                                 ${event.action}
                             </span>
                             <span class="text-sm">${summaryText}</span>
+                            ${repeatBadge(event)}
                         </div>
                     </td>
                     <td class="px-6 py-4 text-sm text-gray-600 dark:text-gray-400">
@@ -191,6 +203,27 @@ async function loadEvents() {
     }
 }
 
+// Badge for events that coalesced repeats, e.g. "×37 in the last hour"
+function repeatBadge(event) {
+    if (!(event.repeat_count > 1)) return '';
+    return `<span class="px-2 py-1 bg-yellow-100 dark:bg-yellow-900 text-yellow-800 dark:text-yellow-200 rounded text-xs whitespace-nowrap" title="Last seen ${escapeHtml(new Date(event.last_seen || event.timestamp).toLocaleString())}">×${event.repeat_count} in the last ${formatSpan(Date.now() - new Date(event.timestamp))}</span>`;
+}
+
+// Formats a duration in milliseconds as "minute", "5 minutes", "hour", "3 hours", ...
+function formatSpan(ms) {
+    const minutes = Math.max(1, Math.round(ms / 60000));
+    const units = [['day', 1440], ['hour', 60], ['minute', 1]];
+    for (const [unit, size] of units) {
+        if (minutes >= size) {
+            const n = Math.round(minutes / size);
+            return n === 1 ? unit : `${n} ${unit}s`;
+        }
+    }
+}
+
+// Timeline currently shown in the modal; more pages are appended by loadMoreTimeline
+let timelineState = null;
+
 // Show timeline for a resource
 async function showTimeline(namespace, kind, name) {
     document.getElementById('timelineModal').classList.remove('hidden');
@@ -198,68 +231,46 @@ async function showTimeline(namespace, kind, name) {
     document.getElementById('timelineTitle').textContent = `Timeline: ${namespace}/${name}`;
     document.getElementById('timelineContent').innerHTML = '<div class="text-center text-gray-500 dark:text-gray-400">Loading timeline...</div>';
     
+    timelineState = { namespace, kind, name, events: [], nextCursor: null, totalCount: 0 };
+    await loadTimelinePage();
+}
+
+// Load the next page of the open timeline
+async function loadMoreTimeline() {
+    if (timelineState && timelineState.nextCursor) {
+        await loadTimelinePage();
+    }
+}
+
+async function loadTimelinePage() {
+    const state = timelineState;
     try {
-        const response = await fetch(`/api/timeline/${encodeURIComponent(namespace)}/${encodeURIComponent(kind)}/${encodeURIComponent(name)}`);
+        let url = `/api/timeline/${encodeURIComponent(state.namespace)}/${encodeURIComponent(state.kind)}/${encodeURIComponent(state.name)}`;
+        if (state.nextCursor) url += `?cursor=${encodeURIComponent(state.nextCursor)}`;
+        const response = await fetch(url);
         const data = await response.json();
-        const timeline = data.timeline || [];
+        if (state !== timelineState) return; // another timeline was opened meanwhile
+        
+        state.events = state.events.concat(data.timeline || []);
+        state.nextCursor = data.next_cursor || null;
+        state.totalCount = data.total_count || state.events.length;
+        const timeline = state.events;
         
         if (timeline.length === 0) {
             document.getElementById('timelineContent').innerHTML = '<div class="text-center text-gray-500 dark:text-gray-400">No timeline data available</div>';
             return;
         }
         
-        const timelineHTML = timeline.map((event, index) => {
-            const timestamp = new Date(event.timestamp).toLocaleString();
-            const actionColor = event.action === 'ADDED' ? 'green' : event.action === 'DELETED' ? 'red' : 'blue';
-            const hasImageChange = event.image_before && event.image_after && event.image_before !== event.image_after;
-            
-            // Split diff into lines for detailed display
-            const diffLines = (event.diff || event.action).split('\n');
-            const summary = diffLines[0];
-            const details = diffLines.slice(1).join('\n').trim();
-            
-            return `
-                <div class="relative pl-8 pb-8 ${index === timeline.length - 1 ? '' : 'border-l-2 border-gray-300 dark:border-gray-600'}">
-                    <div class="absolute left-0 top-0 w-4 h-4 rounded-full bg-${actionColor}-500 -ml-2"></div>
-                    <div class="bg-gray-50 dark:bg-gray-900 rounded-lg p-4">
-                        <div class="flex items-center justify-between mb-2">
-                            <span class="px-2 py-1 bg-${actionColor}-100 dark:bg-${actionColor}-900 text-${actionColor}-800 dark:text-${actionColor}-200 rounded text-sm font-medium">
-                                ${event.action}
-                            </span>
-                            <span class="text-sm text-gray-600 dark:text-gray-400">${timestamp}</span>
-                        </div>
-                        <div class="mt-2 text-sm font-medium text-gray-900 dark:text-white">
-                            ${summary}
-                        </div>
-                        ${details ? `
-                            <div class="mt-3 p-3 bg-gray-100 dark:bg-gray-800 rounded font-mono text-xs overflow-x-auto">
-                                ${details.split('\n').map(line => {
-                                    if (line.startsWith('- ')) {
-                                        return `<div class="text-red-600 dark:text-red-400">${escapeHtml(line)}</div>`;
-                                    } else if (line.startsWith('+ ')) {
-                                        return `<div class="text-green-600 dark:text-green-400">${escapeHtml(line)}</div>`;
-                                    } else if (line.startsWith('[') && line.endsWith(']')) {
-                                        return `<div class="text-blue-600 dark:text-blue-400 font-bold mt-2">${escapeHtml(line)}</div>`;
-                                    } else {
-                                        return `<div class="text-gray-600 dark:text-gray-400">${escapeHtml(line)}</div>`;
-                                    }
-                                }).join('')}
-                            </div>
-                        ` : ''}
-                        ${hasImageChange ? `
-                            <div class="mt-3 p-3 bg-gray-100 dark:bg-gray-800 rounded font-mono text-xs overflow-x-auto">
-                                <div class="text-red-600 dark:text-red-400">- ${escapeHtml(event.image_before)}</div>
-                                <div class="text-green-600 dark:text-green-400">+ ${escapeHtml(event.image_after)}</div>
-                            </div>
-                        ` : event.image_after && event.action === 'ADDED' ? `
-                            <div class="mt-2 text-sm text-gray-600 dark:text-gray-400">
-                                <code class="text-xs">${escapeHtml(event.image_after)}</code>
-                            </div>
-                        ` : ''}
-                    </div>
+        let timelineHTML = renderTimeline(timeline);
+        if (state.nextCursor) {
+            timelineHTML += `
+                <div class="text-center">
+                    <button onclick="loadMoreTimeline()" class="px-3 py-1 rounded bg-blue-600 text-white hover:bg-blue-700 text-sm">
+                        Load more (${timeline.length} of ${state.totalCount})
+                    </button>
                 </div>
             `;
-        }).join('');
+        }
         
         document.getElementById('timelineContent').innerHTML = timelineHTML;
         
@@ -269,6 +280,74 @@ async function showTimeline(namespace, kind, name) {
     }
 }
 
+// Render timeline events as HTML
+function renderTimeline(timeline) {
+    return timeline.map((event, index) => {
+        const timestamp = new Date(event.timestamp).toLocaleString();
+        const actionColor = event.action === 'ADDED' ? 'green' : event.action === 'DELETED' ? 'red' : 'blue';
+        const hasImageChange = event.image_before && event.image_after && event.image_before !== event.image_after;
+        
+        // Split diff into lines for detailed display
+        const diffLines = (event.diff || event.action).split('\n');
+        const summary = diffLines[0];
+        const details = diffLines.slice(1).join('\n').trim();
+        
+        // A different UID means the resource was deleted and recreated
+        const previous = timeline[index - 1];
+        const previousIncarnation = previous && previous.uid && event.uid && previous.uid !== event.uid;
+        
+        return `
+            ${previousIncarnation ? `
+                <div class="flex items-center gap-2 pb-6 text-xs text-gray-500 dark:text-gray-400">
+                    <span class="flex-1 border-t border-dashed border-gray-400"></span>
+                    Previous incarnation (uid ${escapeHtml(event.uid)})
+                    <span class="flex-1 border-t border-dashed border-gray-400"></span>
+                </div>
+            ` : ''}
+            <div class="relative pl-8 pb-8 ${index === timeline.length - 1 ? '' : 'border-l-2 border-gray-300 dark:border-gray-600'}">
+                <div class="absolute left-0 top-0 w-4 h-4 rounded-full bg-${actionColor}-500 -ml-2"></div>
+                <div class="bg-gray-50 dark:bg-gray-900 rounded-lg p-4">
+                    <div class="flex items-center justify-between mb-2">
+                        <span class="px-2 py-1 bg-${actionColor}-100 dark:bg-${actionColor}-900 text-${actionColor}-800 dark:text-${actionColor}-200 rounded text-sm font-medium">
+                            ${event.action}
+                        </span>
+                        <span class="text-sm text-gray-600 dark:text-gray-400">${timestamp}${event.last_seen ? ` – ${new Date(event.last_seen).toLocaleString()}` : ''}</span>
+                    </div>
+                    <div class="mt-2 text-sm font-medium text-gray-900 dark:text-white flex items-center gap-2">
+                        ${summary}
+                        ${repeatBadge(event)}
+                    </div>
+                    ${details ? `
+                        <div class="mt-3 p-3 bg-gray-100 dark:bg-gray-800 rounded font-mono text-xs overflow-x-auto">
+                            ${details.split('\n').map(line => {
+                                if (line.startsWith('- ')) {
+                                    return `<div class="text-red-600 dark:text-red-400">${escapeHtml(line)}</div>`;
+                                } else if (line.startsWith('+ ')) {
+                                    return `<div class="text-green-600 dark:text-green-400">${escapeHtml(line)}</div>`;
+                                } else if (line.startsWith('[') && line.endsWith(']')) {
+                                    return `<div class="text-blue-600 dark:text-blue-400 font-bold mt-2">${escapeHtml(line)}</div>`;
+                                } else {
+                                    return `<div class="text-gray-600 dark:text-gray-400">${escapeHtml(line)}</div>`;
+                                }
+                            }).join('')}
+                        </div>
+                    ` : ''}
+                    ${hasImageChange ? `
+                        <div class="mt-3 p-3 bg-gray-100 dark:bg-gray-800 rounded font-mono text-xs overflow-x-auto">
+                            <div class="text-red-600 dark:text-red-400">- ${escapeHtml(event.image_before)}</div>
+                            <div class="text-green-600 dark:text-green-400">+ ${escapeHtml(event.image_after)}</div>
+                        </div>
+                    ` : event.image_after && event.action === 'ADDED' ? `
+                        <div class="mt-2 text-sm text-gray-600 dark:text-gray-400">
+                            <code class="text-xs">${escapeHtml(event.image_after)}</code>
+                        </div>
+                    ` : ''}
+                </div>
+            </div>
+        `;
+    }).join('');
+}
+
 // Close timeline modal
 function closeTimeline() {
     document.getElementById('timelineModal').classList.add('hidden');
@@ -278,6 +357,7 @@ function closeTimeline() {
 // Apply filters
 function applyFilters() {
     currentPage = 1; // Reset to first page when filters change
+    pageCursors = [null, null];
     loadEvents();
 }
 
@@ -300,6 +380,7 @@ function updatePagination() {
         return;
     }
     
+    const hasNext = currentPage < totalPages && !!pageCursors[currentPage + 1];
     const startItem = (currentPage - 1) * pageSize + 1;
     const endItem = Math.min(currentPage * pageSize, totalCount);
     
@@ -316,8 +397,8 @@ function updatePagination() {
                 <span class="px-3 py-1 text-gray-700 dark:text-gray-300">
                     Page ${currentPage} of ${totalPages}
                 </span>
-                <button onclick="changePage(${currentPage + 1})" ${currentPage === totalPages ? 'disabled' : ''} 
-                    class="px-3 py-1 rounded ${currentPage === totalPages ? 'bg-gray-100 text-gray-400 cursor-not-allowed' : 'bg-blue-600 text-white hover:bg-blue-700'}">
+                <button onclick="changePage(${currentPage + 1})" ${!hasNext ? 'disabled' : ''} 
+                    class="px-3 py-1 rounded ${!hasNext ? 'bg-gray-100 text-gray-400 cursor-not-allowed' : 'bg-blue-600 text-white hover:bg-blue-700'}">
                     Next
                 </button>
             </div>
@@ -326,8 +407,8 @@ function updatePagination() {
 }
 
 function changePage(page) {
-    const totalPages = Math.ceil(totalCount / pageSize);
-    if (page < 1 || page > totalPages) return;
+    // Pages are reached by cursor, so only neighbouring pages can be loaded
+    if (page < 1 || page > currentPage + 1 || (page > 1 && !pageCursors[page])) return;
     currentPage = page;
     loadEvents();
 }
@@ -347,11 +428,27 @@ function startAutoRefresh() {
         
         if (countdown <= 0) {
             countdown = 10;
-            loadData();
+            // The live stream reloads on new events; poll only without it
+            if (!eventStream || eventStream.readyState !== WebSocket.OPEN) loadData();
         }
     }, 1000);
 }
 
+// Live updates: reload shortly after new events are stored
+let eventStream;
+let streamReloadTimer;
+
+function connectStream() {
+    const protocol = location.protocol === 'https:' ? 'wss:' : 'ws:';
+    eventStream = new WebSocket(`${protocol}//${location.host}/api/stream`);
+    eventStream.onmessage = () => {
+        if (currentPage !== 1) return;
+        clearTimeout(streamReloadTimer);
+        streamReloadTimer = setTimeout(loadData, 500);
+    };
+    eventStream.onclose = () => setTimeout(connectStream, 5000);
+}
+
 // Utility function to escape HTML
 function escapeHtml(text) {
     const div = document.createElement('div');
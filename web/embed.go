@@ -0,0 +1,9 @@
+// Package web holds the dashboard assets, embedded into the binary
+package web
+
+import "embed"
+
+// Assets are the dashboard files, served at the root of the API listener
+//
+//go:embed index.html app.js styles.css
+var Assets embed.FS